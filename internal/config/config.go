@@ -1,9 +1,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"github.com/zeromicro/go-zero/core/stores/cache"
 	"github.com/zeromicro/go-zero/rest"
+	"net"
+	"strings"
 	"time"
 )
 
@@ -13,17 +16,282 @@ type Config struct {
 	App            AppConf
 	ShortUrlMap    ShortUrlConf
 	Sequence       SequenceConf
-	CacheRedis     cache.CacheConf
+	CacheRedis     cache.CacheConf `json:",optional"` // 单机模式不使用
 	ShortUrlFilter BloomFilterConf
 	Auth           AuthConf
+	AdminAuth      AuthConf `json:",optional"` // 管理接口使用独立的密钥签发令牌，未配置AccessSecret时关闭管理接口
 	Connect        ConnectConf
 	Limit          LimitConf
+	ShortCode      ShortCodeConf  `json:",optional"`
+	WarmUp         WarmUpConf     `json:",optional"`
+	Monitor        MonitorConf    `json:",optional"`
+	Expiry         ExpiryConf     `json:",optional"`
+	Canonical      CanonicalConf  `json:",optional"`
+	Tracking       TrackingConf   `json:",optional"`
+	DomainRule     DomainRuleConf `json:",optional"`
+	Threat         ThreatConf     `json:",optional"`
+	Storage        StorageConf    `json:",optional"`
+	Standalone     bool           `json:",optional"` // 单机模式，不依赖MySQL、Redis等外部服务
+}
+
+// Validate 校验字段之间的约束，conf.MustLoad 加载配置后自动调用，校验失败时启动失败
+func (c Config) Validate() error {
+	if err := c.App.Validate(); err != nil {
+		return err
+	}
+	if err := c.ShortUrlFilter.Validate(); err != nil {
+		return err
+	}
+	if err := c.ShortCode.Validate(c.App); err != nil {
+		return err
+	}
+	return c.Sequence.Validate(c.ShortCode)
+}
+
+const (
+	StorageDriverMysql    = "mysql"
+	StorageDriverPostgres = "postgres"
+	StorageDriverSqlite   = "sqlite"
+	StorageDriverMemory   = "memory"
+)
+
+// StorageConf 短链映射和序号的存储后端，未配置时使用MySQL
+type StorageConf struct {
+	Driver string `json:",default=mysql,options=mysql|postgres|sqlite|memory"` // memory重启后数据丢失，仅用于开发和测试
+	DSN    string `json:",optional"`                                           // postgres的连接串或sqlite数据库文件
+	// 启动时执行未执行的结构迁移，单机模式总是自动迁移
+	AutoMigrate bool `json:",default=false"`
+}
+
+// DriverOf 获取存储后端，未配置时为mysql
+func (s StorageConf) DriverOf() string {
+	if s.Driver == "" {
+		return StorageDriverMysql
+	}
+	return s.Driver
 }
 
 type AppConf struct {
 	Operator       string
 	ShortUrlDomain string
 	ShortUrlPath   string
+	Namespaces     []NamespaceConf `json:",optional"` // 品牌短域名，每个域名拥有独立的短码空间
+	// TrustForwardedHost 解析时使用X-Forwarded-Host确定命名空间，只在反向代理总会覆盖该请求头时开启，否则使用请求Host
+	TrustForwardedHost bool `json:",default=false"`
+}
+
+type NamespaceConf struct {
+	Name        string
+	Domain      string
+	LegacyMaxID *uint64 `json:",optional"` // 开启ShortCode.CheckCode前该命名空间已发放的最大序号，各命名空间的序号独立计数
+}
+
+// Validate 命名空间名称用于拼接缓存键和过滤器键（命名空间:短码），不能为空、包含冒号或重复
+func (a AppConf) Validate() error {
+	names := make(map[string]struct{}, len(a.Namespaces))
+	for _, ns := range a.Namespaces {
+		if len(ns.Name) == 0 || strings.Contains(ns.Name, ":") {
+			return fmt.Errorf("App.Namespaces: invalid namespace name %q", ns.Name)
+		}
+		if _, ok := names[ns.Name]; ok {
+			return fmt.Errorf("App.Namespaces: duplicate namespace name %q", ns.Name)
+		}
+		names[ns.Name] = struct{}{}
+	}
+	return nil
+}
+
+// NamespaceOf 根据短域名查找命名空间，ShortUrlDomain 对应默认命名空间 ""
+func (a AppConf) NamespaceOf(domain string) (string, bool) {
+	domain = hostOnly(domain)
+
+	if domain == hostOnly(a.ShortUrlDomain) {
+		return "", true
+	}
+	for _, ns := range a.Namespaces {
+		if domain == hostOnly(ns.Domain) {
+			return ns.Name, true
+		}
+	}
+	return "", false
+}
+
+// DomainOf 获取命名空间对应的短域名
+func (a AppConf) DomainOf(namespace string) string {
+	for _, ns := range a.Namespaces {
+		if ns.Name == namespace {
+			return ns.Domain
+		}
+	}
+	return a.ShortUrlDomain
+}
+
+// Domains 获取全部短域名
+func (a AppConf) Domains() []string {
+	domains := make([]string, 0, len(a.Namespaces)+1)
+	domains = append(domains, a.ShortUrlDomain)
+	for _, ns := range a.Namespaces {
+		domains = append(domains, ns.Domain)
+	}
+	return domains
+}
+
+// WarmUpConf 启动预热配置，上一批实例退出时持久化的热点短链会在服务就绪前加载到本地缓存
+type WarmUpConf struct {
+	Enabled  bool          `json:",default=false"`
+	TopN     int           `json:",default=1000"`                 // 预热的短链个数
+	Budget   time.Duration `json:",default=5s"`                   // 预热的最长时间，超时后直接启动
+	Key      string        `json:",default=shortener:warmup:hot"` // 持久化热点短链的有序集合
+	TTL      time.Duration `json:",default=24h"`                  // 持久化列表的过期时间
+	HalfLife time.Duration `json:",default=1h"`                   // 已持久化次数的半衰期，不再访问的短链逐渐被新的热点替换
+}
+
+// MonitorConf 失效链接巡检配置，定期检查全部有效短链的目标地址，多实例部署时只需在一个实例开启
+type MonitorConf struct {
+	Enabled          bool          `json:",default=false"`
+	Interval         time.Duration `json:",default=24h"` // 两轮巡检的间隔，启动后立即执行第一轮
+	Batch            int           `json:",default=500"` // 每批从数据库读取的短链个数
+	Workers          int           `json:",default=8"`   // 并发检查的协程数
+	HostRate         float64       `json:",default=1"`   // 每个目标主机每秒最多检查的次数
+	HostBurst        int           `json:",default=1"`   // 每个目标主机允许的突发检查次数
+	FailureThreshold uint64        `json:",default=3"`   // 连续失败达到该次数后判定为失效，解析时改用备用地址
+}
+
+// ExpiryConf 过期短链清理配置，定期删除已过期的映射并从过滤器中移除，解析请求不会删除数据
+type ExpiryConf struct {
+	Enabled  bool          `json:",default=false"`
+	Interval time.Duration `json:",default=1h"`  // 两轮清理的间隔，启动后立即执行第一轮
+	Batch    int           `json:",default=500"` // 每批从数据库读取的短链个数
+}
+
+// CanonicalConf 计算长链接MD5前的规范化配置，修改后需执行 -rehash-md5 重新计算已有短链的MD5
+type CanonicalConf struct {
+	SortQuery          bool `json:",default=false"` // 按参数名排序查询参数，参数顺序有意义的网站会被视为同一链接
+	StripTrailingSlash bool `json:",default=false"` // 去掉路径末尾的"/"，/a/ 与 /a 视为同一链接
+}
+
+// TrackingConf 跟踪参数配置
+type TrackingConf struct {
+	// StripParams 存储长链接前去掉的查询参数，不区分大小写，以*结尾时按前缀匹配，如 utm_*。
+	// 默认不去掉任何参数，避免升级后已有部署的长链接被改写
+	StripParams []string `json:",optional"`
+	// Tags 按短链标签在跳转时追加的UTM参数，短链自身的UTM参数优先
+	Tags []UtmTagConf `json:",optional"`
+}
+
+// UtmTagConf 一个标签的UTM参数，修改后对该标签的全部短链立即生效
+type UtmTagConf struct {
+	Tag      string
+	Source   string `json:",optional"`
+	Medium   string `json:",optional"`
+	Campaign string `json:",optional"`
+}
+
+// UtmOf 查找标签的UTM参数
+func (t TrackingConf) UtmOf(tag string) (UtmTagConf, bool) {
+	for _, conf := range t.Tags {
+		if conf.Tag == tag {
+			return conf, true
+		}
+	}
+	return UtmTagConf{}, false
+}
+
+const (
+	// DomainRuleModeOff 不检查目标域名
+	DomainRuleModeOff = "off"
+	// DomainRuleModeAllow 只允许匹配规则的目标域名
+	DomainRuleModeAllow = "allow"
+	// DomainRuleModeBlock 拒绝匹配规则的目标域名
+	DomainRuleModeBlock = "block"
+)
+
+// DomainRuleConf 目标域名规则，生成短链和解析时检查
+type DomainRuleConf struct {
+	Mode string `json:",default=off,options=off|allow|block"`
+	// Path 规则文件，每行一条规则。单机模式下作为规则的存储，管理接口修改规则后写回该文件；
+	// 其他模式下只在Key第一次使用时导入
+	Path string `json:",default=assets/domainRules.txt"`
+	// Key 保存规则的Redis集合，多实例共享，是规则的唯一来源
+	Key string `json:",default=shortener:domainRules"`
+	// Refresh 定期重新加载规则的间隔，兜底错过的修改通知
+	Refresh time.Duration `json:",default=30s"`
+	// Channel 管理接口修改规则时通知其他实例立即重新加载的频道
+	Channel string `json:",default=shortener:domainRules:events"`
+}
+
+const (
+	// ThreatListHash 每行一个十六进制的SHA-256前缀（4-32字节），与Safe Browsing的哈希前缀列表相同
+	ThreatListHash = "hash"
+	// ThreatListDomain 每行一个域名，匹配该域名及其子域名
+	ThreatListDomain = "domain"
+	// ThreatListUrl 每行一个URL，匹配该地址及其下级路径
+	ThreatListUrl = "url"
+
+	// ThreatActionBlock 解析命中列表的短链时拒绝跳转
+	ThreatActionBlock = "block"
+	// ThreatActionInterstitial 解析命中列表的短链时跳转到提示页
+	ThreatActionInterstitial = "interstitial"
+)
+
+// ThreatConf 本地同步的恶意链接列表，匹配在进程内完成，不访问外部服务
+type ThreatConf struct {
+	Enabled bool             `json:",default=false"`
+	Lists   []ThreatListConf `json:",optional"`
+	// Refresh 检查列表文件是否更新的间隔，文件修改后重新加载
+	Refresh time.Duration `json:",default=1m"`
+	Action  string        `json:",default=block,options=block|interstitial"`
+	// InterstitialUrl 提示页地址，跳转时追加 url（目标地址）和 list（命中的列表）参数，为空时拒绝跳转
+	InterstitialUrl string `json:",optional"`
+	// RejectPrefixMatches 只命中哈希前缀、未经完整哈希确认的地址同样拒绝生成短链，解析时按Action处理；
+	// 关闭时只记录日志并放行，配置了提示页时解析仍跳转到提示页
+	RejectPrefixMatches bool `json:",default=true"`
+}
+
+// ThreatListConf 一个列表文件，由外部任务定期同步到本地
+type ThreatListConf struct {
+	Name   string
+	Path   string
+	Format string `json:",default=hash,options=hash|domain|url"`
+}
+
+type ShortCodeConf struct {
+	CheckCode bool `json:",default=false"` // 是否在短码末尾追加校验字符
+	// 启用校验字符前默认命名空间已发放的最大序号，不超过该序号的短码视为旧短码，没有发放过短码的新部署配置为0
+	LegacyMaxID *uint64 `json:",optional"`
+}
+
+// Validate 启用校验字符时默认命名空间和每个品牌命名空间都必须显式配置LegacyMaxID，
+// 否则之前发放的不带校验字符的短码全部无法解析
+func (s ShortCodeConf) Validate(app AppConf) error {
+	if !s.CheckCode {
+		return nil
+	}
+
+	if s.LegacyMaxID == nil {
+		return errors.New("ShortCode: LegacyMaxID must be set to the largest id issued before enabling CheckCode, 0 if none")
+	}
+	for _, ns := range app.Namespaces {
+		if ns.LegacyMaxID == nil {
+			return fmt.Errorf("App.Namespaces: LegacyMaxID of namespace %q must be set when ShortCode.CheckCode is enabled", ns.Name)
+		}
+	}
+	return nil
+}
+
+// LegacyMaxIDOf 获取命名空间启用校验字符前已发放的最大序号
+func (c Config) LegacyMaxIDOf(namespace string) uint64 {
+	legacy := c.ShortCode.LegacyMaxID
+	for _, ns := range c.App.Namespaces {
+		if ns.Name == namespace {
+			legacy = ns.LegacyMaxID
+		}
+	}
+
+	if legacy == nil {
+		return 0
+	}
+	return *legacy
 }
 
 type MysqlConf struct {
@@ -41,12 +309,30 @@ type RedisConf struct {
 }
 
 type ShortUrlConf struct {
-	Mysql MysqlConf
+	Mysql         MysqlConf         `json:",optional"`
+	NegativeCache NegativeCacheConf `json:",optional"`
+	HotCache      HotCacheConf      `json:",optional"`
+}
+
+// HotCacheConf 热点短链的进程内缓存
+type HotCacheConf struct {
+	Enabled bool          `json:",default=false"`
+	TTL     time.Duration `json:",default=10s"`                              // 缓存时间，失效消息丢失时最多读到该时长的旧数据
+	Limit   int           `json:",default=10000"`                            // 最多缓存的短链个数，超出后淘汰最久未使用的
+	Channel string        `json:",default=shortener:shortUrlMap:invalidate"` // 短链更新、删除时通知其他实例失效的频道
+}
+
+// NegativeCacheConf 不存在短码的进程内缓存
+type NegativeCacheConf struct {
+	Enabled bool          `json:",default=false"`
+	TTL     time.Duration `json:",default=1m"`                            // 不存在的短码缓存时间
+	Limit   int           `json:",default=100000"`                        // 最多缓存的短码个数
+	Channel string        `json:",default=shortener:shortUrlMap:created"` // 新建短码时通知其他实例失效的频道
 }
 
 type SequenceConf struct {
-	Mysql            MysqlConf
-	Redis            RedisConf
+	Mysql            MysqlConf `json:",optional"`
+	Redis            RedisConf `json:",optional"`
 	RetryBackoff     time.Duration
 	MaxRetries       int
 	CachePatch       uint64
@@ -56,12 +342,54 @@ type SequenceConf struct {
 	LocalCapacity    int
 	KeySequenceID    string
 	KeySequenceState string
+	Backend          string   `json:",default=mysql,options=mysql|multistub|redis|snowflake"` // ID分配后端
+	Stubs            []string `json:",optional"`                                              // multistub后端使用的stub行
+	KeyCounter       string   `json:",default=sequence:counter"`                              // redis后端使用的计数器键
+	WorkerID         int64    `json:",optional"`                                              // snowflake后端的机器号(0-1023)
 }
 
+// Validate 短码最长11位（short_url列的长度）。雪花ID在2031年5月后编码为11位，追加校验字符后超出长度，
+// 因此snowflake后端不能与校验字符同时使用
+func (s SequenceConf) Validate(code ShortCodeConf) error {
+	if s.Backend == SequenceBackendSnowflake && code.CheckCode {
+		return errors.New("Sequence: the snowflake backend can not be used with ShortCode.CheckCode")
+	}
+	return nil
+}
+
+const (
+	SequenceBackendMysql     = "mysql"
+	SequenceBackendMultiStub = "multistub"
+	SequenceBackendRedis     = "redis"
+	SequenceBackendSnowflake = "snowflake"
+)
+
+const (
+	FilterTypeBloom    = "bloom"
+	FilterTypeCounting = "counting"
+)
+
 type BloomFilterConf struct {
-	Redis RedisConf
-	Bits  uint
-	Key   string
+	Redis             RedisConf `json:",optional"`
+	Type              string    `json:",default=bloom,options=bloom|counting"` // bloom不支持删除，counting为支持删除的计数布隆过滤器
+	Bits              uint      `json:",optional"`                             // 位数，配置ExpectedItems时忽略
+	ExpectedItems     uint64    `json:",optional"`                             // 预期元素个数，用于计算位数
+	FalsePositiveRate float64   `json:",default=0.001"`                        // 目标误判率
+	Key               string
+	RebuildBatch      int           `json:",default=1000"`                    // 从数据库重建时每批读取的行数
+	CheckOnStart      bool          `json:",default=true"`                    // 启动时检查位图是否丢失，丢失则从数据库重建
+	FailOpen          bool          `json:",default=false"`                   // Redis异常时跳过过滤而不是返回错误
+	Replica           bool          `json:",default=false"`                   // 在进程内维护位图副本，解析时不访问Redis
+	ReplicaRefresh    time.Duration `json:",default=1s"`                      // 副本检查位图版本号的间隔，有事件丢失时重新加载快照
+	ReplicaChannel    string        `json:",default=shortener:filter:events"` // 副本同步增删事件的频道
+}
+
+// Validate 位数由Bits或ExpectedItems确定，两者都未配置时位数为0，无法计算位置
+func (b BloomFilterConf) Validate() error {
+	if b.Bits == 0 && b.ExpectedItems == 0 {
+		return errors.New("ShortUrlFilter: either Bits or ExpectedItems must be set")
+	}
+	return nil
 }
 
 type AuthConf struct {
@@ -69,19 +397,53 @@ type AuthConf struct {
 	AccessExpire int64
 }
 
+const (
+	// ConnectPolicyStrict 生成短链前同步检查连通性，不可访问时拒绝
+	ConnectPolicyStrict = "strict"
+	// ConnectPolicyAsync 先生成短链，在后台检查连通性并记录结果
+	ConnectPolicyAsync = "async"
+	// ConnectPolicyOff 不检查连通性
+	ConnectPolicyOff = "off"
+)
+
 type ConnectConf struct {
+	// Policy 连通性检查策略
+	Policy          string `json:",default=strict,options=strict|async|off"`
 	DNSServer       string
 	Timeout         time.Duration
 	MaxRetries      int
+	RetryBackoff    time.Duration `json:",default=50ms"` // 第一次重试前的等待时间，之后每次翻倍
+	RetryMaxBackoff time.Duration `json:",default=1s"`   // 重试等待时间的上限
 	MaxIdleConns    int
 	IdleConnTimeout time.Duration
+	// MaxRedirects 跟随重定向的最大次数，重定向链最终返回2xx时视为可访问，0表示不跟随
+	MaxRedirects int `json:",default=5"`
+	// FallbackStatusCodes HEAD返回这些状态码时改用GET重新检查，部分网站不支持HEAD
+	FallbackStatusCodes []int `json:",default=[403,405,501]"`
+	// FallbackMaxBytes GET检查时通过Range请求并最多读取的响应体字节数
+	FallbackMaxBytes int64 `json:",default=1024"`
+	// ResolveChain 逐跳解析重定向链并记录最终地址，拒绝跳回本服务短域名或经过公共短链服务的链接
+	ResolveChain bool `json:",default=false"`
+	// PublicShorteners 公共短链服务的域名，包含其子域名，ResolveChain开启时生效
+	PublicShorteners []string `json:",default=[bit.ly,t.co,tinyurl.com,goo.gl,ow.ly,is.gd,v.gd,buff.ly,rebrand.ly,cutt.ly,t.ly,rb.gy,s.id,tiny.cc,shorturl.at,dwz.cn,url.cn]"`
+	// AllowCIDRs 内部部署时允许检查的内网网段，如 10.0.0.0/8，默认禁止访问内网、环回、链路本地等地址
+	AllowCIDRs []string `json:",optional"`
 }
 
 type LimitConf struct {
-	Redis RedisConf
+	Redis RedisConf `json:",optional"`
 	Rate  int
 	Burst int
-	Key   string
+	Key   string `json:",optional"`
+}
+
+// hostOnly 统一为小写并去除端口
+func hostOnly(domain string) string {
+	domain = strings.ToLower(domain)
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		return host
+	}
+	return domain
 }
 
 func (db MysqlConf) DSN() string {
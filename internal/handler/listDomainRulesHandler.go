@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"net/http"
+	"shortener/internal/logic"
+	"shortener/internal/svc"
+	"shortener/internal/types/format"
+)
+
+func ListDomainRulesHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := logic.NewListDomainRulesLogic(r.Context(), svcCtx)
+		resp, err := l.ListDomainRules()
+		if err != nil {
+			format.ResponseError(w, err)
+		} else {
+			format.ResponseSuccess(w, resp)
+		}
+	}
+}
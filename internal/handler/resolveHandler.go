@@ -19,6 +19,11 @@ func ResolveHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			return
 		}
 
+		//X-Forwarded-Host可以由客户端任意设置，只在配置信任反向代理时使用，否则使用请求Host确定命名空间
+		if !svcCtx.Config.App.TrustForwardedHost || len(req.Domain) == 0 {
+			req.Domain = r.Host
+		}
+
 		//参数校验
 		if err := validate.Check(r.Context(), &req); err != nil {
 			format.ResponseError(w, err)
@@ -35,9 +35,39 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 					Path:    "/shorten",
 					Handler: ShortenHandler(serverCtx),
 				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/links/broken",
+					Handler: ListBrokenHandler(serverCtx),
+				},
 			}...,
 		),
 		rest.WithJwt(serverCtx.Config.Auth.AccessSecret),
 		rest.WithPrefix("/api/v1"),
 	)
+
+	server.AddRoutes(
+		rest.WithMiddlewares(
+			[]rest.Middleware{serverCtx.Limit},
+			[]rest.Route{
+				{
+					Method:  http.MethodGet,
+					Path:    "/domain-rules",
+					Handler: ListDomainRulesHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/domain-rules",
+					Handler: AddDomainRulesHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodDelete,
+					Path:    "/domain-rules",
+					Handler: RemoveDomainRulesHandler(serverCtx),
+				},
+			}...,
+		),
+		rest.WithJwt(serverCtx.Config.AdminAuth.AccessSecret),
+		rest.WithPrefix("/api/v1/admin"),
+	)
 }
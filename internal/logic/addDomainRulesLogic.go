@@ -0,0 +1,32 @@
+package logic
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"shortener/internal/svc"
+	"shortener/internal/types"
+)
+
+type AddDomainRulesLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewAddDomainRulesLogic(ctx context.Context, svcCtx *svc.ServiceContext) *AddDomainRulesLogic {
+	return &AddDomainRulesLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// AddDomainRules 添加目标域名规则，立即生效并同步到其他实例
+func (l *AddDomainRulesLogic) AddDomainRules(req *types.DomainRulesRequest) (*types.DomainRulesResponse, error) {
+	if err := l.svcCtx.DomainRules.Add(l.ctx, req.Rules); err != nil {
+		return nil, err
+	}
+
+	l.Infof("domain rules added,rules:%v", req.Rules)
+	return domainRulesResponse(l.svcCtx), nil
+}
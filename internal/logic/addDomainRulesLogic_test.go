@@ -0,0 +1,46 @@
+package logic
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"shortener/internal/config"
+	"shortener/internal/svc"
+	"shortener/internal/types"
+	"shortener/internal/types/errorx"
+	domainRuleMock "shortener/pkg/domainRule/mock"
+	"testing"
+)
+
+func TestAddDomainRulesLogic_AddDomainRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRules := domainRuleMock.NewMockRules(ctrl)
+	svcCtx := &svc.ServiceContext{
+		Config:      config.Config{DomainRule: config.DomainRuleConf{Mode: config.DomainRuleModeBlock}},
+		DomainRules: mockRules,
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockRules.EXPECT().Add(gomock.Any(), []string{"evil.example"}).Return(nil)
+		mockRules.EXPECT().List().Return([]string{"evil.example"})
+
+		l := NewAddDomainRulesLogic(context.Background(), svcCtx)
+		resp, err := l.AddDomainRules(&types.DomainRulesRequest{Rules: []string{"evil.example"}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &types.DomainRulesResponse{Mode: config.DomainRuleModeBlock, Rules: []string{"evil.example"}}, resp)
+	})
+
+	t.Run("invalid_rule", func(t *testing.T) {
+		mockRules.EXPECT().Add(gomock.Any(), []string{"bad domain"}).
+			Return(errorx.New(errorx.CodeParamError, "invalid domain rule"))
+
+		l := NewAddDomainRulesLogic(context.Background(), svcCtx)
+		resp, err := l.AddDomainRules(&types.DomainRulesRequest{Rules: []string{"bad domain"}})
+
+		assert.Nil(t, resp)
+		assert.True(t, errorx.Is(err, errorx.CodeParamError))
+	})
+}
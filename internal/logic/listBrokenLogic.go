@@ -0,0 +1,64 @@
+package logic
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"shortener/internal/model"
+	"shortener/internal/svc"
+	"shortener/internal/types"
+	"shortener/internal/types/errorx"
+	"time"
+)
+
+type ListBrokenLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewListBrokenLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ListBrokenLogic {
+	return &ListBrokenLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// ListBroken 按创建顺序分页列出命名空间内巡检判定为失效的短链
+func (l *ListBrokenLogic) ListBroken(req *types.ListBrokenRequest) (*types.ListBrokenResponse, error) {
+	//确定命名空间，为空时使用默认命名空间
+	namespace := ""
+	if len(req.Domain) != 0 {
+		var ok bool
+		if namespace, ok = l.svcCtx.Config.App.NamespaceOf(req.Domain); !ok {
+			return nil, errorx.New(errorx.CodeParamError, "unknown short url domain").
+				WithMeta("domain", req.Domain)
+		}
+	}
+
+	data, err := l.svcCtx.ShortUrlMapRepository.ListByCheckStatus(l.ctx, namespace, model.CheckStatusBroken, req.Cursor, req.Limit)
+	if err != nil {
+		return nil, errorx.Wrap(err, errorx.CodeDatabaseError, "fail to list broken links")
+	}
+
+	resp := &types.ListBrokenResponse{Links: make([]types.BrokenLink, 0, len(data))}
+	for _, link := range data {
+		broken := types.BrokenLink{
+			ShortCode:   fullShortLink(l.svcCtx.Config.App, namespace, link.ShortUrl),
+			LongUrl:     link.LongUrl,
+			FallbackUrl: link.FallbackUrl,
+			FailCount:   link.FailCount,
+			StatusCode:  link.CheckCode,
+		}
+		if link.CheckedAt.Valid {
+			broken.CheckedAt = link.CheckedAt.Time.Format(time.RFC3339)
+		}
+		resp.Links = append(resp.Links, broken)
+	}
+
+	//满页时可能还有下一页
+	if len(data) == req.Limit {
+		resp.NextCursor = data[len(data)-1].Id
+	}
+	return resp, nil
+}
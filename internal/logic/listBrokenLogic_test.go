@@ -0,0 +1,80 @@
+package logic
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"shortener/internal/config"
+	"shortener/internal/model"
+	repositoryMock "shortener/internal/repository/mock"
+	"shortener/internal/svc"
+	"shortener/internal/types"
+	"shortener/internal/types/errorx"
+	"testing"
+	"time"
+)
+
+func TestListBrokenLogic_ListBroken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShortUrlMap := repositoryMock.NewMockShortUrlMap(ctrl)
+
+	cfg := config.Config{
+		App: config.AppConf{
+			ShortUrlDomain: "https://s.example",
+			ShortUrlPath:   "/",
+			Namespaces:     []config.NamespaceConf{{Name: "brand", Domain: "brand.example"}},
+		},
+	}
+	svcCtx := &svc.ServiceContext{
+		Config:                cfg,
+		ShortUrlMapRepository: mockShortUrlMap,
+	}
+
+	checkedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("full_page_returns_cursor", func(t *testing.T) {
+		mockShortUrlMap.EXPECT().ListByCheckStatus(gomock.Any(), "brand", model.CheckStatusBroken, uint64(5), 2).
+			Return([]*model.ShortUrlMap{
+				{Id: 6, ShortUrl: "a", LongUrl: "https://example.com/a", FailCount: 3, CheckCode: 404,
+					CheckedAt: sql.NullTime{Time: checkedAt, Valid: true}, FallbackUrl: "https://example.com/"},
+				{Id: 9, ShortUrl: "b", LongUrl: "https://example.com/b", FailCount: 4},
+			}, nil)
+
+		l := NewListBrokenLogic(context.Background(), svcCtx)
+		resp, err := l.ListBroken(&types.ListBrokenRequest{Domain: "brand.example", Cursor: 5, Limit: 2})
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(9), resp.NextCursor)
+		assert.Equal(t, types.BrokenLink{
+			ShortCode:   "brand.example/a",
+			LongUrl:     "https://example.com/a",
+			FallbackUrl: "https://example.com/",
+			FailCount:   3,
+			StatusCode:  404,
+			CheckedAt:   "2025-01-02T03:04:05Z",
+		}, resp.Links[0])
+		assert.Empty(t, resp.Links[1].CheckedAt)
+	})
+
+	t.Run("last_page", func(t *testing.T) {
+		mockShortUrlMap.EXPECT().ListByCheckStatus(gomock.Any(), "", model.CheckStatusBroken, uint64(0), 20).
+			Return(nil, nil)
+
+		l := NewListBrokenLogic(context.Background(), svcCtx)
+		resp, err := l.ListBroken(&types.ListBrokenRequest{Limit: 20})
+
+		assert.NoError(t, err)
+		assert.Empty(t, resp.Links)
+		assert.Zero(t, resp.NextCursor)
+	})
+
+	t.Run("unknown_domain", func(t *testing.T) {
+		l := NewListBrokenLogic(context.Background(), svcCtx)
+		_, err := l.ListBroken(&types.ListBrokenRequest{Domain: "unknown.example", Limit: 20})
+
+		assert.True(t, errorx.Is(err, errorx.CodeParamError))
+	})
+}
@@ -0,0 +1,35 @@
+package logic
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"shortener/internal/svc"
+	"shortener/internal/types"
+)
+
+type ListDomainRulesLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewListDomainRulesLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ListDomainRulesLogic {
+	return &ListDomainRulesLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// ListDomainRules 列出当前的目标域名规则
+func (l *ListDomainRulesLogic) ListDomainRules() (*types.DomainRulesResponse, error) {
+	return domainRulesResponse(l.svcCtx), nil
+}
+
+// 当前的规则模式和全部规则
+func domainRulesResponse(svcCtx *svc.ServiceContext) *types.DomainRulesResponse {
+	return &types.DomainRulesResponse{
+		Mode:  svcCtx.Config.DomainRule.Mode,
+		Rules: svcCtx.DomainRules.List(),
+	}
+}
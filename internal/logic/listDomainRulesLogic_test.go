@@ -0,0 +1,30 @@
+package logic
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"shortener/internal/config"
+	"shortener/internal/svc"
+	domainRuleMock "shortener/pkg/domainRule/mock"
+	"testing"
+)
+
+func TestListDomainRulesLogic_ListDomainRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRules := domainRuleMock.NewMockRules(ctrl)
+	mockRules.EXPECT().List().Return([]string{"*.evil.example", "bad.example"})
+
+	svcCtx := &svc.ServiceContext{
+		Config:      config.Config{DomainRule: config.DomainRuleConf{Mode: config.DomainRuleModeBlock}},
+		DomainRules: mockRules,
+	}
+
+	resp, err := NewListDomainRulesLogic(context.Background(), svcCtx).ListDomainRules()
+
+	assert.NoError(t, err)
+	assert.Equal(t, config.DomainRuleModeBlock, resp.Mode)
+	assert.Equal(t, []string{"*.evil.example", "bad.example"}, resp.Rules)
+}
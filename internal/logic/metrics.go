@@ -0,0 +1,56 @@
+package logic
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// monitorMetrics contains all prometheus metrics of the link-rot monitor.
+var monitorMetrics = struct {
+	// checks tracks the destination checks of the monitor labeled by the resulting status
+	// ("reachable", "unreachable", "broken" or "rejected").
+	checks *prometheus.CounterVec
+
+	// brokenLinks reports the number of broken links found by the last monitor round.
+	brokenLinks prometheus.Gauge
+
+	// roundDuration tracks the time taken by each monitor round.
+	roundDuration prometheus.Histogram
+}{
+	checks: prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "shortener",
+			Subsystem: "monitor",
+			Name:      "checks_total",
+			Help:      "Number of destination checks by resulting status (reachable/unreachable/broken/rejected)",
+		},
+		[]string{"status"},
+	),
+	brokenLinks: prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "shortener",
+			Subsystem: "monitor",
+			Name:      "broken_links",
+			Help:      "Number of broken links found by the last monitor round",
+		},
+	),
+	roundDuration: prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "shortener",
+			Subsystem: "monitor",
+			Name:      "round_duration_seconds",
+			Help:      "Time taken by each monitor round",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+		},
+	),
+}
+
+// RegisterMetrics registers the link-rot monitor metrics with the provided prometheus registerer.
+//
+// Example:
+//
+//	logic.RegisterMetrics(prometheus.DefaultRegisterer)
+func RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(
+		monitorMetrics.checks,
+		monitorMetrics.brokenLinks,
+		monitorMetrics.roundDuration,
+	)
+}
@@ -0,0 +1,245 @@
+package logic
+
+import (
+	"context"
+	"database/sql"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/mr"
+	"github.com/zeromicro/go-zero/core/threading"
+	"golang.org/x/time/rate"
+	"net/url"
+	"shortener/internal/model"
+	"shortener/internal/svc"
+	"shortener/pkg/urlTool"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkStatusNames 检查结果在监控指标中的名称
+var checkStatusNames = map[uint64]string{
+	model.CheckStatusReachable:   "reachable",
+	model.CheckStatusUnreachable: "unreachable",
+	model.CheckStatusRejected:    "rejected",
+	model.CheckStatusBroken:      "broken",
+}
+
+type MonitorLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+	// 复用生成短链时的检查逻辑，开启重定向链解析时同样记录最终地址并检查重定向链
+	prober *ShortenLogic
+}
+
+func NewMonitorLogic(ctx context.Context, svcCtx *svc.ServiceContext, client urlTool.Client) *MonitorLogic {
+	return &MonitorLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+		prober: NewShortenLogic(ctx, svcCtx, client),
+	}
+}
+
+// Start 在后台立即执行第一轮巡检，之后按间隔执行，ctx取消后停止
+func (l *MonitorLogic) Start() {
+	threading.GoSafe(func() {
+		ticker := time.NewTicker(l.svcCtx.Config.Monitor.Interval)
+		defer ticker.Stop()
+
+		for {
+			l.RunOnce()
+
+			select {
+			case <-l.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// RunOnce 检查全部未过期的短链并记录结果，连续失败达到阈值的短链标记为失效，返回本轮发现的失效短链个数。
+// 违反安全策略被拒绝的短链不再检查，避免覆盖拒绝的标记。
+// 目标主机被限流的短链不占用工作协程等待，放回队列与下一批一起检查，遍历结束后再检查剩余的短链
+func (l *MonitorLogic) RunOnce() int {
+	conf := l.svcCtx.Config.Monitor
+	round := &monitorRound{limiters: newHostLimiters(conf.HostRate, conf.HostBurst)}
+
+	start := time.Now()
+	err := l.svcCtx.ShortUrlMapRepository.RangeLinks(l.ctx, conf.Batch, func(data []*model.ShortUrlMap) error {
+		links := round.takeRequeued()
+		for _, link := range data {
+			if shouldMonitor(link, start) {
+				links = append(links, link)
+			}
+		}
+		l.checkLinks(round, links)
+
+		return l.ctx.Err()
+	})
+	for err == nil {
+		links := round.takeRequeued()
+		if len(links) == 0 {
+			break
+		}
+
+		// 剩余的短链都在等待主机的令牌，等待一个令牌的时间后再检查
+		select {
+		case <-l.ctx.Done():
+			err = l.ctx.Err()
+		case <-time.After(round.limiters.interval()):
+			l.checkLinks(round, links)
+		}
+	}
+	if err != nil {
+		logx.Errorf("monitor links failed,checked:%v,err:%v", round.checked.Load(), err)
+		return int(round.broken.Load())
+	}
+
+	checked, broken := round.checked.Load(), round.broken.Load()
+	monitorMetrics.brokenLinks.Set(float64(broken))
+	monitorMetrics.roundDuration.Observe(time.Since(start).Seconds())
+	logx.Infof("monitor links finished,checked:%v,broken:%v,elapsed:%v", checked, broken, time.Since(start))
+	return int(broken)
+}
+
+// monitorRound 一轮巡检的状态
+type monitorRound struct {
+	limiters *hostLimiters
+	checked  atomic.Int64
+	broken   atomic.Int64
+
+	mu       sync.Mutex
+	requeued []*model.ShortUrlMap
+}
+
+func (r *monitorRound) requeue(link *model.ShortUrlMap) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requeued = append(r.requeued, link)
+}
+
+func (r *monitorRound) takeRequeued() []*model.ShortUrlMap {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	links := r.requeued
+	r.requeued = nil
+	return links
+}
+
+// checkLinks 并发检查一批短链，目标主机没有令牌的短链放回队列
+func (l *MonitorLogic) checkLinks(round *monitorRound, links []*model.ShortUrlMap) {
+	mr.ForEach(func(source chan<- *model.ShortUrlMap) {
+		for _, link := range links {
+			select {
+			case source <- link:
+			case <-l.ctx.Done():
+				return
+			}
+		}
+	}, func(link *model.ShortUrlMap) {
+		u, err := url.Parse(link.LongUrl)
+		if err != nil {
+			logx.Debugw("skip monitoring invalid url", logx.Field("shortUrl", link.ShortUrl), logx.Field("err", err.Error()))
+			return
+		}
+		if !round.limiters.allow(strings.ToLower(u.Hostname())) {
+			round.requeue(link)
+			return
+		}
+
+		round.checked.Add(1)
+		if l.checkLink(link) == model.CheckStatusBroken {
+			round.broken.Add(1)
+		}
+	}, mr.WithContext(l.ctx), mr.WithWorkers(l.svcCtx.Config.Monitor.Workers))
+}
+
+// checkLink 检查短链并记录结果，返回检查后的状态
+func (l *MonitorLogic) checkLink(link *model.ShortUrlMap) uint64 {
+	check, err := l.prober.probeDestination(link.LongUrl)
+	if err != nil {
+		logx.Debugw("monitor check failed", logx.Field("shortUrl", link.ShortUrl), logx.Field("err", err.Error()))
+	}
+
+	result := &model.ShortUrlMap{
+		Namespace:   link.Namespace,
+		ShortUrl:    link.ShortUrl,
+		FinalUrl:    link.FinalUrl,
+		CheckStatus: check.status,
+		CheckCode:   int64(check.code),
+		CheckedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+		FailCount:   link.FailCount,
+	}
+	if len(check.finalUrl) != 0 {
+		result.FinalUrl = check.finalUrl
+	}
+
+	switch check.status {
+	case model.CheckStatusReachable:
+		result.FailCount = 0
+	case model.CheckStatusUnreachable:
+		result.FailCount++
+		if result.FailCount >= l.svcCtx.Config.Monitor.FailureThreshold {
+			result.CheckStatus = model.CheckStatusBroken
+		}
+	}
+
+	if err = l.svcCtx.ShortUrlMapRepository.UpdateCheckResult(l.ctx, result); err != nil {
+		logx.WithContext(l.ctx).Errorf("record monitor result of %v failed,err:%v", link.ShortUrl, err)
+	}
+	monitorMetrics.checks.WithLabelValues(checkStatusNames[result.CheckStatus]).Inc()
+	return result.CheckStatus
+}
+
+// shouldMonitor 跳过已过期和违反安全策略被拒绝的短链
+func shouldMonitor(link *model.ShortUrlMap, now time.Time) bool {
+	if link.ExpireAt.Valid && !link.ExpireAt.Time.After(now) {
+		return false
+	}
+	return link.CheckStatus != model.CheckStatusRejected
+}
+
+// hostLimiters 每个目标主机独立的令牌桶，避免巡检时集中请求同一网站
+type hostLimiters struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	limit    rate.Limit
+	burst    int
+}
+
+// newHostLimiters 创建主机限流器，r不大于0时不限流
+func newHostLimiters(r float64, burst int) *hostLimiters {
+	limit := rate.Limit(r)
+	if r <= 0 {
+		limit = rate.Inf
+	}
+	return &hostLimiters{
+		limiters: make(map[string]*rate.Limiter),
+		limit:    limit,
+		burst:    max(burst, 1),
+	}
+}
+
+// allow 判断主机当前是否有令牌，有令牌时消耗一个
+func (h *hostLimiters) allow(host string) bool {
+	h.mu.Lock()
+	limiter, ok := h.limiters[host]
+	if !ok {
+		limiter = rate.NewLimiter(h.limit, h.burst)
+		h.limiters[host] = limiter
+	}
+	h.mu.Unlock()
+
+	return limiter.Allow()
+}
+
+// interval 每个主机产生一个令牌的时间
+func (h *hostLimiters) interval() time.Duration {
+	if h.limit == rate.Inf {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / float64(h.limit))
+}
@@ -0,0 +1,144 @@
+package logic
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"shortener/internal/config"
+	"shortener/internal/model"
+	repositoryMock "shortener/internal/repository/mock"
+	"shortener/internal/svc"
+	"shortener/internal/types/errorx"
+	"shortener/pkg/urlTool"
+	urlToolMock "shortener/pkg/urlTool/mock"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMonitorLogic_RunOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShortUrlMap := repositoryMock.NewMockShortUrlMap(ctrl)
+	mockURLClient := urlToolMock.NewMockClient(ctrl)
+
+	cfg := config.Config{}
+	cfg.Monitor = config.MonitorConf{Batch: 10, Workers: 2, FailureThreshold: 3}
+
+	svcCtx := &svc.ServiceContext{
+		Config:                cfg,
+		ShortUrlMapRepository: mockShortUrlMap,
+	}
+
+	links := []*model.ShortUrlMap{
+		{ShortUrl: "ok", LongUrl: "https://a.example.com/ok", FailCount: 2, CheckStatus: model.CheckStatusUnreachable},
+		{ShortUrl: "first", LongUrl: "https://b.example.com/first"},
+		{ShortUrl: "broken", LongUrl: "https://c.example.com/broken", FailCount: 2, CheckStatus: model.CheckStatusUnreachable},
+		{ShortUrl: "forbidden", LongUrl: "http://10.0.0.1/", FailCount: 1},
+		{ShortUrl: "expired", LongUrl: "https://d.example.com/expired",
+			ExpireAt: sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}},
+		{ShortUrl: "rejected", LongUrl: "https://e.example.com/rejected", CheckStatus: model.CheckStatusRejected},
+	}
+	mockShortUrlMap.EXPECT().RangeLinks(gomock.Any(), 10, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ int, fn func(data []*model.ShortUrlMap) error) error {
+			return fn(links)
+		})
+
+	mockURLClient.EXPECT().Check("https://a.example.com/ok").Return(&urlTool.Result{Reachable: true, StatusCode: 200}, nil)
+	mockURLClient.EXPECT().Check("https://b.example.com/first").Return(&urlTool.Result{StatusCode: 404}, nil)
+	mockURLClient.EXPECT().Check("https://c.example.com/broken").Return(&urlTool.Result{StatusCode: 500}, nil)
+	mockURLClient.EXPECT().Check("http://10.0.0.1/").
+		Return(nil, errorx.NewWithCause(errorx.CodeParamError, "the url points to a forbidden address", urlTool.ErrForbiddenAddress))
+
+	var mu sync.Mutex
+	results := make(map[string]*model.ShortUrlMap)
+	mockShortUrlMap.EXPECT().UpdateCheckResult(gomock.Any(), gomock.Any()).Times(4).
+		DoAndReturn(func(_ context.Context, data *model.ShortUrlMap) error {
+			mu.Lock()
+			defer mu.Unlock()
+			results[data.ShortUrl] = data
+			return nil
+		})
+
+	broken := NewMonitorLogic(context.Background(), svcCtx, mockURLClient).RunOnce()
+	assert.Equal(t, 1, broken)
+
+	// 恢复可访问后清零连续失败次数
+	assert.Equal(t, model.CheckStatusReachable, results["ok"].CheckStatus)
+	assert.Equal(t, uint64(0), results["ok"].FailCount)
+	// 未达到阈值时只标记为不可访问
+	assert.Equal(t, model.CheckStatusUnreachable, results["first"].CheckStatus)
+	assert.Equal(t, uint64(1), results["first"].FailCount)
+	assert.Equal(t, int64(404), results["first"].CheckCode)
+	// 连续失败达到阈值后标记为失效
+	assert.Equal(t, model.CheckStatusBroken, results["broken"].CheckStatus)
+	assert.Equal(t, uint64(3), results["broken"].FailCount)
+	// 违反安全策略的不计入连续失败
+	assert.Equal(t, model.CheckStatusRejected, results["forbidden"].CheckStatus)
+	assert.Equal(t, uint64(1), results["forbidden"].FailCount)
+	for _, data := range results {
+		assert.True(t, data.CheckedAt.Valid)
+	}
+}
+
+func TestMonitorLogic_RunOnceRequeue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShortUrlMap := repositoryMock.NewMockShortUrlMap(ctrl)
+	mockURLClient := urlToolMock.NewMockClient(ctrl)
+
+	cfg := config.Config{}
+	cfg.Monitor = config.MonitorConf{Batch: 10, Workers: 2, FailureThreshold: 3, HostRate: 20, HostBurst: 1}
+	svcCtx := &svc.ServiceContext{
+		Config:                cfg,
+		ShortUrlMapRepository: mockShortUrlMap,
+	}
+
+	// 同一主机的第二个短链被限流后放回队列，在遍历结束后检查
+	links := []*model.ShortUrlMap{
+		{ShortUrl: "a1", LongUrl: "https://a.example.com/1"},
+		{ShortUrl: "a2", LongUrl: "https://a.example.com/2"},
+		{ShortUrl: "b1", LongUrl: "https://b.example.com/1"},
+	}
+	mockShortUrlMap.EXPECT().RangeLinks(gomock.Any(), 10, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ int, fn func(data []*model.ShortUrlMap) error) error {
+			return fn(links)
+		})
+	for _, link := range links {
+		mockURLClient.EXPECT().Check(link.LongUrl).Return(&urlTool.Result{Reachable: true, StatusCode: 200}, nil)
+	}
+
+	var mu sync.Mutex
+	checked := make(map[string]bool)
+	mockShortUrlMap.EXPECT().UpdateCheckResult(gomock.Any(), gomock.Any()).Times(3).
+		DoAndReturn(func(_ context.Context, data *model.ShortUrlMap) error {
+			mu.Lock()
+			defer mu.Unlock()
+			checked[data.ShortUrl] = true
+			return nil
+		})
+
+	assert.Equal(t, 0, NewMonitorLogic(context.Background(), svcCtx, mockURLClient).RunOnce())
+	assert.Len(t, checked, 3)
+}
+
+func TestHostLimiters(t *testing.T) {
+	limiters := newHostLimiters(0.001, 1)
+
+	assert.True(t, limiters.allow("a.example.com"))
+	// 不同主机使用独立的令牌桶
+	assert.True(t, limiters.allow("b.example.com"))
+	// 同一主机的令牌用完后不再等待
+	assert.False(t, limiters.allow("a.example.com"))
+	assert.Equal(t, 1000*time.Second, limiters.interval())
+
+	// 速率不大于0时不限流
+	unlimited := newHostLimiters(0, 0)
+	for i := 0; i < 3; i++ {
+		assert.True(t, unlimited.allow("a.example.com"))
+	}
+	assert.Zero(t, unlimited.interval())
+}
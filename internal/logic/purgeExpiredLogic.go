@@ -0,0 +1,73 @@
+package logic
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+	"shortener/internal/model"
+	"shortener/internal/svc"
+	"shortener/internal/types/errorx"
+	"time"
+)
+
+type PurgeExpiredLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewPurgeExpiredLogic(ctx context.Context, svcCtx *svc.ServiceContext) *PurgeExpiredLogic {
+	return &PurgeExpiredLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// Start 在后台立即执行第一轮清理，之后按间隔执行，ctx取消后停止
+func (l *PurgeExpiredLogic) Start() {
+	threading.GoSafe(func() {
+		ticker := time.NewTicker(l.svcCtx.Config.Expiry.Interval)
+		defer ticker.Stop()
+
+		for {
+			l.RunOnce()
+
+			select {
+			case <-l.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// RunOnce 删除全部已过期的映射，返回本实例删除的个数。
+// 仓库删除后同时从过滤器中移除短码，已被其他实例删除的短码返回NotFound，不会重复移除
+func (l *PurgeExpiredLogic) RunOnce() int {
+	var purged int
+	start := time.Now()
+	err := l.svcCtx.ShortUrlMapRepository.RangeLinks(l.ctx, l.svcCtx.Config.Expiry.Batch, func(data []*model.ShortUrlMap) error {
+		for _, link := range data {
+			if !link.ExpireAt.Valid || link.ExpireAt.Time.After(start) {
+				continue
+			}
+
+			err := l.svcCtx.ShortUrlMapRepository.Delete(l.ctx, link.Namespace, link.ShortUrl)
+			if err == nil {
+				purged++
+			} else if !errorx.Is(err, errorx.CodeNotFound) {
+				l.Errorf("purge expired short link failed,namespace:%v,shortUrl:%v,err:%v", link.Namespace, link.ShortUrl, err)
+			}
+		}
+
+		return l.ctx.Err()
+	})
+	if err != nil {
+		logx.Errorf("purge expired links failed,purged:%v,err:%v", purged, err)
+		return purged
+	}
+
+	logx.Infof("purge expired links finished,purged:%v,elapsed:%v", purged, time.Since(start))
+	return purged
+}
@@ -0,0 +1,52 @@
+package logic
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"shortener/internal/config"
+	"shortener/internal/model"
+	repositoryMock "shortener/internal/repository/mock"
+	"shortener/internal/svc"
+	"shortener/internal/types/errorx"
+	"testing"
+	"time"
+)
+
+func TestPurgeExpiredLogic_RunOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShortUrlMap := repositoryMock.NewMockShortUrlMap(ctrl)
+
+	cfg := config.Config{}
+	cfg.Expiry.Batch = 10
+
+	svcCtx := &svc.ServiceContext{
+		Config:                cfg,
+		ShortUrlMapRepository: mockShortUrlMap,
+	}
+
+	expired := sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+	links := []*model.ShortUrlMap{
+		{Id: 1, ShortUrl: "forever"},
+		{Id: 2, ShortUrl: "future", ExpireAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}},
+		{Id: 3, Namespace: "brand", ShortUrl: "expired", ExpireAt: expired},
+		{Id: 4, ShortUrl: "purged", ExpireAt: expired},
+		{Id: 5, ShortUrl: "failed", ExpireAt: expired},
+	}
+	mockShortUrlMap.EXPECT().RangeLinks(gomock.Any(), 10, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ int, fn func([]*model.ShortUrlMap) error) error {
+			return fn(links)
+		})
+	mockShortUrlMap.EXPECT().Delete(gomock.Any(), "brand", "expired").Return(nil)
+	// 已被其他实例删除的短链不计数
+	mockShortUrlMap.EXPECT().Delete(gomock.Any(), "", "purged").
+		Return(errorx.New(errorx.CodeNotFound, "the data does not exist"))
+	mockShortUrlMap.EXPECT().Delete(gomock.Any(), "", "failed").
+		Return(errorx.New(errorx.CodeDatabaseError, "delete shortUrlMap failed"))
+
+	purged := NewPurgeExpiredLogic(context.Background(), svcCtx).RunOnce()
+	assert.Equal(t, 1, purged)
+}
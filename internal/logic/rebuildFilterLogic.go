@@ -0,0 +1,87 @@
+package logic
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+	"shortener/internal/model"
+	"shortener/internal/repository"
+	"shortener/internal/svc"
+	"shortener/internal/types/errorx"
+	"shortener/pkg/filter"
+)
+
+type RebuildFilterLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewRebuildFilterLogic(ctx context.Context, svcCtx *svc.ServiceContext) *RebuildFilterLogic {
+	return &RebuildFilterLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// RebuildFilter 从数据库分批读取全部短码重建过滤器
+func (l *RebuildFilterLogic) RebuildFilter() error {
+	rebuilder, err := l.rebuilder()
+	if err != nil {
+		return err
+	}
+
+	return rebuilder.Rebuild(l.ctx, l.source())
+}
+
+// CheckFilter 检查过滤器位图是否丢失，丢失时在后台重建，重建期间全部实例的解析请求跳过过滤
+func (l *RebuildFilterLogic) CheckFilter() error {
+	rebuilder, err := l.rebuilder()
+	if err != nil {
+		return err
+	}
+
+	lost, err := rebuilder.Lost(l.ctx)
+	if err != nil {
+		return err
+	}
+	if !lost {
+		return nil
+	}
+
+	logx.Severef("short code filter key is lost, rebuilding from database")
+	threading.GoSafe(func() {
+		err := rebuilder.Rebuild(context.Background(), l.source())
+		switch {
+		case errorx.Is(err, errorx.CodeTooFrequent):
+			// 多个实例同时启动时只有一个实例重建，其他实例通过共享的重建标记跳过过滤
+			logx.Infof("short code filter is being rebuilt by another instance")
+		case err != nil:
+			logx.Errorf("rebuild short code filter failed,err:%v", err)
+		}
+	})
+	return nil
+}
+
+func (l *RebuildFilterLogic) rebuilder() (filter.Rebuilder, error) {
+	rebuilder, ok := l.svcCtx.ShortCodeFilter.(filter.Rebuilder)
+	if !ok {
+		return nil, errorx.New(errorx.CodeSystemError, "short code filter does not support rebuild")
+	}
+	return rebuilder, nil
+}
+
+// source 将数据库中的映射转换为过滤器键
+func (l *RebuildFilterLogic) source() filter.Source {
+	return func(ctx context.Context, fn func(batch [][]byte) error) error {
+		return l.svcCtx.ShortUrlMapRepository.RangeShortUrls(ctx, l.svcCtx.Config.ShortUrlFilter.RebuildBatch,
+			func(data []*model.ShortUrlMap) error {
+				batch := make([][]byte, 0, len(data))
+				for _, item := range data {
+					batch = append(batch, repository.FilterKey(item.Namespace, item.ShortUrl))
+				}
+				return fn(batch)
+			})
+	}
+}
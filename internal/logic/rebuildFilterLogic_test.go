@@ -0,0 +1,74 @@
+package logic
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"shortener/internal/config"
+	"shortener/internal/model"
+	repositoryMock "shortener/internal/repository/mock"
+	"shortener/internal/svc"
+	"shortener/pkg/filter"
+	filterMock "shortener/pkg/filter/mock"
+	"testing"
+)
+
+func TestRebuildFilterLogic_RebuildFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShortUrlMap := repositoryMock.NewMockShortUrlMap(ctrl)
+	mockRebuilder := filterMock.NewMockRebuilder(ctrl)
+
+	cfg := config.Config{}
+	cfg.ShortUrlFilter.RebuildBatch = 2
+
+	svcCtx := &svc.ServiceContext{
+		Config:                cfg,
+		ShortUrlMapRepository: mockShortUrlMap,
+		ShortCodeFilter:       mockRebuilder,
+	}
+
+	mockShortUrlMap.EXPECT().RangeShortUrls(gomock.Any(), 2, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, batch int, fn func(data []*model.ShortUrlMap) error) error {
+			return fn([]*model.ShortUrlMap{
+				{ShortUrl: "abc"},
+				{ShortUrl: "abc", Namespace: "brand"},
+			})
+		})
+
+	var keys []string
+	mockRebuilder.EXPECT().Rebuild(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, source filter.Source) error {
+			return source(ctx, func(batch [][]byte) error {
+				for _, key := range batch {
+					keys = append(keys, string(key))
+				}
+				return nil
+			})
+		})
+
+	err := NewRebuildFilterLogic(context.Background(), svcCtx).RebuildFilter()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abc", "brand:abc"}, keys)
+}
+
+func TestRebuildFilterLogic_CheckFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRebuilder := filterMock.NewMockRebuilder(ctrl)
+	svcCtx := &svc.ServiceContext{ShortCodeFilter: mockRebuilder}
+
+	// 位图存在时不重建
+	mockRebuilder.EXPECT().Lost(gomock.Any()).Return(false, nil)
+
+	err := NewRebuildFilterLogic(context.Background(), svcCtx).CheckFilter()
+	assert.NoError(t, err)
+
+	// 不支持重建的过滤器
+	svcCtx.ShortCodeFilter = filterMock.NewMockFilter(ctrl)
+	err = NewRebuildFilterLogic(context.Background(), svcCtx).CheckFilter()
+	assert.Error(t, err)
+}
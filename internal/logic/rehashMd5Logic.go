@@ -0,0 +1,73 @@
+package logic
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"shortener/internal/model"
+	"shortener/internal/svc"
+	"shortener/internal/types/errorx"
+)
+
+// rehashBatch 重新计算MD5时每批读取的行数
+const rehashBatch = 1000
+
+type RehashMd5Logic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewRehashMd5Logic(ctx context.Context, svcCtx *svc.ServiceContext) *RehashMd5Logic {
+	return &RehashMd5Logic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// RehashMd5 按当前的规范化配置重新计算已有短链的MD5，使引入规范化之前生成的短链也能被去重。
+// 多个短链的长链接规范化后相同时，主键最小的短链使用新的MD5，其余保留原MD5，返回更新和跳过的行数
+func (l *RehashMd5Logic) RehashMd5() (updated, skipped int, err error) {
+	err = l.svcCtx.ShortUrlMapRepository.RangeLinks(l.ctx, rehashBatch, func(data []*model.ShortUrlMap) error {
+		for _, item := range data {
+			ok, err := l.rehash(item)
+			if err != nil {
+				return err
+			}
+			if ok {
+				updated++
+			} else {
+				skipped++
+			}
+		}
+		return nil
+	})
+	return updated, skipped, err
+}
+
+// rehash 更新一行的MD5，MD5未变化、长链接无法规范化或新的MD5已被占用时返回false
+func (l *RehashMd5Logic) rehash(data *model.ShortUrlMap) (bool, error) {
+	m, err := longUrlMD5(l.svcCtx.Config.Canonical, data.LongUrl, data.Tag, data.Utm)
+	if err != nil {
+		logx.Errorf("skip rehashing invalid long url,shortUrl:%v,err:%v", data.ShortUrl, err)
+		return false, nil
+	}
+	if m == data.Md5 {
+		return false, nil
+	}
+
+	existing, err := l.svcCtx.ShortUrlMapRepository.FindOneByMd5(l.ctx, data.Namespace, m)
+	switch {
+	case err == nil:
+		logx.Infof("skip rehashing duplicate long url,shortUrl:%v,duplicateOf:%v", data.ShortUrl, existing.ShortUrl)
+		return false, nil
+	case !errorx.Is(err, errorx.CodeNotFound):
+		return false, err
+	}
+
+	data.Md5 = m
+	if err = l.svcCtx.ShortUrlMapRepository.Update(l.ctx, data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
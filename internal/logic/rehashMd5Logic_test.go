@@ -0,0 +1,61 @@
+package logic
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"shortener/internal/config"
+	"shortener/internal/model"
+	repositoryMock "shortener/internal/repository/mock"
+	"shortener/internal/svc"
+	"shortener/internal/types/errorx"
+	"shortener/pkg/md5"
+	"testing"
+)
+
+func TestRehashMd5Logic_RehashMd5(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShortUrlMap := repositoryMock.NewMockShortUrlMap(ctrl)
+
+	svcCtx := &svc.ServiceContext{
+		Config:                config.Config{},
+		ShortUrlMapRepository: mockShortUrlMap,
+	}
+
+	sum := func(s string) string {
+		m, _ := md5.Sum([]byte(s))
+		return m
+	}
+	canonical := sum("https://example.com/a")
+
+	mockShortUrlMap.EXPECT().RangeLinks(gomock.Any(), rehashBatch, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ int, fn func(data []*model.ShortUrlMap) error) error {
+			return fn([]*model.ShortUrlMap{
+				// 已是规范形式
+				{Id: 1, ShortUrl: "a", LongUrl: "https://example.com/a", Md5: canonical},
+				// 规范化后与a相同，保留原MD5
+				{Id: 2, ShortUrl: "b", LongUrl: "HTTPS://Example.com/a", Md5: sum("HTTPS://Example.com/a")},
+				// 其他命名空间没有相同的链接，更新为规范形式的MD5
+				{Id: 3, ShortUrl: "a", Namespace: "brand", LongUrl: "https://example.com:443/a", Md5: sum("https://example.com:443/a")},
+			})
+		})
+
+	mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), "", canonical).Return(&model.ShortUrlMap{Id: 1, ShortUrl: "a"}, nil)
+	mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), "brand", canonical).
+		Return(nil, errorx.New(errorx.CodeNotFound, "the data does not exist"))
+	mockShortUrlMap.EXPECT().Update(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, data *model.ShortUrlMap) error {
+			assert.Equal(t, uint64(3), data.Id)
+			assert.Equal(t, canonical, data.Md5)
+			assert.Equal(t, "https://example.com:443/a", data.LongUrl)
+			return nil
+		})
+
+	updated, skipped, err := NewRehashMd5Logic(context.Background(), svcCtx).RehashMd5()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updated)
+	assert.Equal(t, 2, skipped)
+}
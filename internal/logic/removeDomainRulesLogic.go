@@ -0,0 +1,32 @@
+package logic
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"shortener/internal/svc"
+	"shortener/internal/types"
+)
+
+type RemoveDomainRulesLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewRemoveDomainRulesLogic(ctx context.Context, svcCtx *svc.ServiceContext) *RemoveDomainRulesLogic {
+	return &RemoveDomainRulesLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// RemoveDomainRules 删除目标域名规则，立即生效并同步到其他实例
+func (l *RemoveDomainRulesLogic) RemoveDomainRules(req *types.DomainRulesRequest) (*types.DomainRulesResponse, error) {
+	if err := l.svcCtx.DomainRules.Remove(l.ctx, req.Rules); err != nil {
+		return nil, err
+	}
+
+	l.Infof("domain rules removed,rules:%v", req.Rules)
+	return domainRulesResponse(l.svcCtx), nil
+}
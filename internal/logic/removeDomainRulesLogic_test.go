@@ -0,0 +1,46 @@
+package logic
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"shortener/internal/config"
+	"shortener/internal/svc"
+	"shortener/internal/types"
+	"shortener/internal/types/errorx"
+	domainRuleMock "shortener/pkg/domainRule/mock"
+	"testing"
+)
+
+func TestRemoveDomainRulesLogic_RemoveDomainRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRules := domainRuleMock.NewMockRules(ctrl)
+	svcCtx := &svc.ServiceContext{
+		Config:      config.Config{DomainRule: config.DomainRuleConf{Mode: config.DomainRuleModeAllow}},
+		DomainRules: mockRules,
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockRules.EXPECT().Remove(gomock.Any(), []string{"old.example"}).Return(nil)
+		mockRules.EXPECT().List().Return([]string{})
+
+		l := NewRemoveDomainRulesLogic(context.Background(), svcCtx)
+		resp, err := l.RemoveDomainRules(&types.DomainRulesRequest{Rules: []string{"old.example"}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, config.DomainRuleModeAllow, resp.Mode)
+		assert.Empty(t, resp.Rules)
+	})
+
+	t.Run("write_failed", func(t *testing.T) {
+		mockRules.EXPECT().Remove(gomock.Any(), []string{"old.example"}).
+			Return(errorx.New(errorx.CodeSystemError, "write the domain rule file failed"))
+
+		l := NewRemoveDomainRulesLogic(context.Background(), svcCtx)
+		_, err := l.RemoveDomainRules(&types.DomainRulesRequest{Rules: []string{"old.example"}})
+
+		assert.True(t, errorx.Is(err, errorx.CodeSystemError))
+	})
+}
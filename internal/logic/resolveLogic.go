@@ -3,9 +3,17 @@ package logic
 import (
 	"context"
 	"github.com/zeromicro/go-zero/core/logx"
+	"net/url"
+	"shortener/internal/config"
+	"shortener/internal/model"
+	"shortener/internal/repository"
 	"shortener/internal/svc"
 	"shortener/internal/types"
 	"shortener/internal/types/errorx"
+	"shortener/pkg/threat"
+	"shortener/pkg/urlTool"
+	"shortener/pkg/validate"
+	"time"
 )
 
 type ResolveLogic struct {
@@ -25,8 +33,19 @@ func NewResolveLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ResolveLo
 func (l *ResolveLogic) Resolve(req *types.ResolveRequest) (*types.ResolveResponse, error) {
 	//校验参数（handler进行初步处理）
 
+	//根据访问的短域名确定命名空间，未配置的域名使用默认命名空间
+	namespace, _ := l.svcCtx.Config.App.NamespaceOf(req.Domain)
+
+	//启用校验字符时直接拒绝输错的短码，旧短码按所在命名空间的序号判断
+	if l.svcCtx.Config.ShortCode.CheckCode &&
+		!validate.ValidCheckCode(req.ShortCode, l.svcCtx.Config.LegacyMaxIDOf(namespace)) {
+		return nil, errorx.New(errorx.CodeParamError, "invalid param").
+			WithMeta("namespace", namespace).
+			WithMeta("shortCode", req.ShortCode)
+	}
+
 	//进行过滤
-	exist, err := l.filter(req.ShortCode)
+	exist, err := l.filter(namespace, req.ShortCode)
 	if err != nil {
 		return nil, err
 	}
@@ -36,7 +55,7 @@ func (l *ResolveLogic) Resolve(req *types.ResolveRequest) (*types.ResolveRespons
 	}
 
 	//查询长链
-	longUrl, err := l.queryLongUrlByShortUrl(req.ShortCode)
+	longUrl, err := l.queryLongUrlByShortUrl(namespace, req.ShortCode)
 	if err != nil {
 		return nil, err
 	}
@@ -45,13 +64,18 @@ func (l *ResolveLogic) Resolve(req *types.ResolveRequest) (*types.ResolveRespons
 		return nil, errorx.New(errorx.CodeNotFound, "the short link does not exist")
 	}
 
+	//统计热点短链，用于下次启动预热
+	if l.svcCtx.HotLinks != nil {
+		l.svcCtx.HotLinks.Touch(namespace, req.ShortCode)
+	}
+
 	// 如果数据库中存在，则返回长链接
 	return &types.ResolveResponse{OriginalUrl: longUrl}, nil
 }
 
 // 查询原始长链接
-func (l *ResolveLogic) filter(shortUrl string) (bool, error) {
-	exist, err := l.svcCtx.ShortCodeFilter.ExistsCtx(l.ctx, []byte(shortUrl))
+func (l *ResolveLogic) filter(namespace, shortUrl string) (bool, error) {
+	exist, err := l.svcCtx.ShortCodeFilter.ExistsCtx(l.ctx, repository.FilterKey(namespace, shortUrl))
 	if err != nil {
 		return false, errorx.Wrap(err, errorx.CodeSystemError, "fail to check if there is a shortURL through the filter")
 	}
@@ -60,8 +84,8 @@ func (l *ResolveLogic) filter(shortUrl string) (bool, error) {
 }
 
 // 查询原始长链接
-func (l *ResolveLogic) queryLongUrlByShortUrl(shortUrl string) (string, error) {
-	data, err := l.svcCtx.ShortUrlMapRepository.FindOneByShortUrl(l.ctx, shortUrl)
+func (l *ResolveLogic) queryLongUrlByShortUrl(namespace, shortUrl string) (string, error) {
+	data, err := l.svcCtx.ShortUrlMapRepository.FindOneByShortUrl(l.ctx, namespace, shortUrl)
 	if err != nil {
 		// 对特定错误类型做特殊处理
 		if errorx.Is(err, errorx.CodeNotFound) {
@@ -70,11 +94,120 @@ func (l *ResolveLogic) queryLongUrlByShortUrl(shortUrl string) (string, error) {
 		// 其他错误统一包装
 		return "", errorx.Wrap(err, errorx.CodeSystemError, "query short link mapping failed").
 			WithContext(l.ctx).
+			WithMeta("namespace", namespace).
 			WithMeta("shortUrl", shortUrl)
 	}
 
 	if data == nil {
 		return "", nil
 	}
-	return data.LongUrl, nil
+
+	//已过期的短链不再跳转，解析是只读请求，映射由后台的过期清理删除
+	if data.ExpireAt.Valid && !data.ExpireAt.Time.After(time.Now()) {
+		return "", errorx.New(errorx.CodeNotFound, "the short link has expired").
+			WithMeta("namespace", namespace).
+			WithMeta("shortUrl", shortUrl)
+	}
+
+	//异步检查发现目标地址违反安全策略（如指向内网地址）时停用短链
+	if data.CheckStatus == model.CheckStatusRejected {
+		return "", errorx.New(errorx.CodeNotFound, "the short link has been disabled").
+			WithMeta("namespace", namespace).
+			WithMeta("shortUrl", shortUrl)
+	}
+
+	//目标地址已失效且配置了备用地址时跳转到备用地址
+	destination := data.LongUrl
+	if data.CheckStatus == model.CheckStatusBroken && len(data.FallbackUrl) != 0 {
+		destination = data.FallbackUrl
+	}
+
+	//解析时再次检查域名规则，规则修改后已有的短链立即失效
+	if !l.destinationAllowed(data, destination) {
+		return "", errorx.New(errorx.CodeNotFound, "the short link has been disabled").
+			WithMeta("namespace", namespace).
+			WithMeta("shortUrl", shortUrl)
+	}
+
+	//命中恶意链接列表时按配置拒绝跳转或跳转到提示页，列表更新后已有的短链立即生效
+	result, flagged := l.threatListOf(data, destination)
+	destination = l.appendUTM(data, destination)
+	if flagged {
+		return l.flagged(namespace, shortUrl, destination, result)
+	}
+	return destination, nil
+}
+
+// 检查目标地址以及长链接重定向后的最终地址是否命中恶意链接列表，优先返回命中完整哈希的结果
+func (l *ResolveLogic) threatListOf(data *model.ShortUrlMap, destination string) (threat.Result, bool) {
+	result, ok := threatListOf(l.svcCtx, destination)
+	if ok && result.Verified {
+		return result, true
+	}
+	if destination == data.LongUrl && len(data.FinalUrl) != 0 {
+		if final, finalOk := threatListOf(l.svcCtx, data.FinalUrl); finalOk && (final.Verified || !ok) {
+			return final, true
+		}
+	}
+	return result, ok
+}
+
+// 命中恶意链接列表的短链：配置了提示页时跳转到提示页，由用户确认后继续访问，否则拒绝跳转。
+// 只命中哈希前缀的结果未经确认，没有提示页时直接放行
+func (l *ResolveLogic) flagged(namespace, shortUrl, destination string, result threat.Result) (string, error) {
+	if result.Verified {
+		l.Infof("short link is on a threat list,namespace:%v,shortUrl:%v,list:%v", namespace, shortUrl, result.List)
+	} else {
+		l.Infof("short link matches a threat list prefix only,namespace:%v,shortUrl:%v,list:%v",
+			namespace, shortUrl, result.List)
+	}
+
+	conf := l.svcCtx.Config.Threat
+	if conf.Action == config.ThreatActionInterstitial && len(conf.InterstitialUrl) != 0 {
+		interstitial, err := url.Parse(conf.InterstitialUrl)
+		if err == nil {
+			query := interstitial.Query()
+			query.Set("url", destination)
+			query.Set("list", result.List)
+			interstitial.RawQuery = query.Encode()
+			return interstitial.String(), nil
+		}
+		l.Errorf("invalid interstitial url %v,err:%v", conf.InterstitialUrl, err)
+	}
+	if !result.Verified && !conf.RejectPrefixMatches {
+		return destination, nil
+	}
+
+	return "", errorx.New(errorx.CodeNotFound, "the short link is flagged as malicious").
+		WithMeta("namespace", namespace).
+		WithMeta("shortUrl", shortUrl).
+		WithMeta("list", result.List)
+}
+
+// 目标地址以及长链接重定向后的最终地址都需要符合域名规则
+func (l *ResolveLogic) destinationAllowed(data *model.ShortUrlMap, destination string) bool {
+	if !destinationAllowed(l.svcCtx, destination) {
+		return false
+	}
+	if destination == data.LongUrl && len(data.FinalUrl) != 0 {
+		return destinationAllowed(l.svcCtx, data.FinalUrl)
+	}
+	return true
+}
+
+// 追加短链和标签的UTM参数，短链自身的参数优先
+func (l *ResolveLogic) appendUTM(data *model.ShortUrlMap, destination string) string {
+	utm := urlTool.ParseUTM(data.Utm)
+	if len(data.Tag) != 0 {
+		if conf, ok := l.svcCtx.Config.Tracking.UtmOf(data.Tag); ok {
+			utm = utm.Or(urlTool.UTM{Source: conf.Source, Medium: conf.Medium, Campaign: conf.Campaign})
+		}
+	}
+
+	appended, err := urlTool.AppendUTM(destination, utm)
+	if err != nil {
+		l.Errorf("append utm to %v failed,err:%v", destination, err)
+		return destination
+	}
+	return appended
 }
@@ -2,7 +2,9 @@ package logic
 
 import (
 	"context"
+	"database/sql"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"shortener/internal/config"
 	"shortener/internal/model"
@@ -10,8 +12,12 @@ import (
 	"shortener/internal/svc"
 	"shortener/internal/types"
 	"shortener/internal/types/errorx"
+	"shortener/pkg/base62"
 	filterMock "shortener/pkg/filter/mock"
+	"shortener/pkg/threat"
+	threatMock "shortener/pkg/threat/mock"
 	"testing"
+	"time"
 )
 
 func TestResolveLogic_Resolve(t *testing.T) {
@@ -70,7 +76,7 @@ func TestResolveLogic_Resolve(t *testing.T) {
 		mockFilter.EXPECT().ExistsCtx(gomock.Any(), []byte(shortURL)).Return(true, nil)
 
 		// 设置数据库查询返回错误
-		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), shortURL).Return(nil, errorx.New(errorx.CodeSystemError, "database error"))
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", shortURL).Return(nil, errorx.New(errorx.CodeSystemError, "database error"))
 
 		l := NewResolveLogic(context.Background(), svcCtx)
 		resp, err := l.Resolve(&types.ResolveRequest{ShortCode: shortURL})
@@ -88,7 +94,7 @@ func TestResolveLogic_Resolve(t *testing.T) {
 		mockFilter.EXPECT().ExistsCtx(gomock.Any(), []byte(shortURL)).Return(true, nil)
 
 		// 设置数据库查询返回成功
-		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), shortURL).Return(&model.ShortUrlMap{
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", shortURL).Return(&model.ShortUrlMap{
 			ShortUrl: shortURL,
 			LongUrl:  longURL,
 		}, nil)
@@ -109,7 +115,7 @@ func TestResolveLogic_Resolve(t *testing.T) {
 		mockFilter.EXPECT().ExistsCtx(gomock.Any(), []byte(shortURL)).Return(true, nil)
 
 		// 设置数据库查询返回不存在
-		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), shortURL).Return(nil, errorx.New(errorx.CodeNotFound, "not found"))
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", shortURL).Return(nil, errorx.New(errorx.CodeNotFound, "not found"))
 
 		l := NewResolveLogic(context.Background(), svcCtx)
 		resp, err := l.Resolve(&types.ResolveRequest{ShortCode: shortURL})
@@ -136,7 +142,7 @@ func TestResolveLogic_filter(t *testing.T) {
 		mockFilter.EXPECT().ExistsCtx(gomock.Any(), []byte(shortURL)).Return(true, nil)
 
 		l := &ResolveLogic{ctx: context.Background(), svcCtx: svcCtx}
-		exists, err := l.filter(shortURL)
+		exists, err := l.filter("", shortURL)
 
 		assert.True(t, exists)
 		assert.Nil(t, err)
@@ -147,7 +153,7 @@ func TestResolveLogic_filter(t *testing.T) {
 		mockFilter.EXPECT().ExistsCtx(gomock.Any(), []byte(shortURL)).Return(false, nil)
 
 		l := &ResolveLogic{ctx: context.Background(), svcCtx: svcCtx}
-		exists, err := l.filter(shortURL)
+		exists, err := l.filter("", shortURL)
 
 		assert.False(t, exists)
 		assert.Nil(t, err)
@@ -158,7 +164,7 @@ func TestResolveLogic_filter(t *testing.T) {
 		mockFilter.EXPECT().ExistsCtx(gomock.Any(), []byte(shortURL)).Return(false, errorx.New(errorx.CodeSystemError, "filter error"))
 
 		l := &ResolveLogic{ctx: context.Background(), svcCtx: svcCtx}
-		exists, err := l.filter(shortURL)
+		exists, err := l.filter("", shortURL)
 
 		assert.False(t, exists)
 		assert.NotNil(t, err)
@@ -179,24 +185,211 @@ func TestResolveLogic_queryLongUrlByShortUrl(t *testing.T) {
 	t.Run("found", func(t *testing.T) {
 		shortURL := "abc123"
 		longURL := "http://example.com/page"
-		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), shortURL).Return(&model.ShortUrlMap{
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", shortURL).Return(&model.ShortUrlMap{
 			ShortUrl: shortURL,
 			LongUrl:  longURL,
 		}, nil)
 
 		l := &ResolveLogic{ctx: context.Background(), svcCtx: svcCtx}
-		result, err := l.queryLongUrlByShortUrl(shortURL)
+		result, err := l.queryLongUrlByShortUrl("", shortURL)
 
 		assert.Equal(t, longURL, result)
 		assert.Nil(t, err)
 	})
 
+	t.Run("broken_with_fallback", func(t *testing.T) {
+		shortURL := "broken"
+		fallbackURL := "http://example.com/fallback"
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", shortURL).Return(&model.ShortUrlMap{
+			ShortUrl:    shortURL,
+			LongUrl:     "http://example.com/gone",
+			CheckStatus: model.CheckStatusBroken,
+			FallbackUrl: fallbackURL,
+		}, nil)
+
+		l := &ResolveLogic{ctx: context.Background(), svcCtx: svcCtx}
+		result, err := l.queryLongUrlByShortUrl("", shortURL)
+
+		assert.Equal(t, fallbackURL, result)
+		assert.Nil(t, err)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		shortURL := "expired"
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", shortURL).Return(&model.ShortUrlMap{
+			ShortUrl: shortURL,
+			LongUrl:  "http://example.com/page",
+			ExpireAt: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+		}, nil)
+
+		l := NewResolveLogic(context.Background(), svcCtx)
+		result, err := l.queryLongUrlByShortUrl("", shortURL)
+
+		assert.Empty(t, result)
+		assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		shortURL := "rejected"
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", shortURL).Return(&model.ShortUrlMap{
+			ShortUrl:    shortURL,
+			LongUrl:     "http://10.0.0.1/admin",
+			CheckStatus: model.CheckStatusRejected,
+			FallbackUrl: "http://example.com/fallback",
+		}, nil)
+
+		l := &ResolveLogic{ctx: context.Background(), svcCtx: svcCtx}
+		result, err := l.queryLongUrlByShortUrl("", shortURL)
+
+		assert.Empty(t, result)
+		assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+	})
+
+	t.Run("unreachable_keeps_destination", func(t *testing.T) {
+		shortURL := "flaky"
+		longURL := "http://example.com/flaky"
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", shortURL).Return(&model.ShortUrlMap{
+			ShortUrl:    shortURL,
+			LongUrl:     longURL,
+			CheckStatus: model.CheckStatusUnreachable,
+			FallbackUrl: "http://example.com/fallback",
+		}, nil)
+
+		l := &ResolveLogic{ctx: context.Background(), svcCtx: svcCtx}
+		result, err := l.queryLongUrlByShortUrl("", shortURL)
+
+		assert.Equal(t, longURL, result)
+		assert.Nil(t, err)
+	})
+
+	t.Run("append_utm", func(t *testing.T) {
+		svcCtx := &svc.ServiceContext{
+			ShortUrlMapRepository: mockShortUrlMap,
+		}
+		svcCtx.Config.Tracking.Tags = []config.UtmTagConf{{Tag: "spring", Source: "tag", Medium: "email"}}
+
+		shortURL := "campaign"
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", shortURL).Return(&model.ShortUrlMap{
+			ShortUrl: shortURL,
+			LongUrl:  "http://example.com/page?id=1",
+			Tag:      "spring",
+			Utm:      "utm_source=link&utm_campaign=sale",
+		}, nil)
+
+		l := &ResolveLogic{ctx: context.Background(), svcCtx: svcCtx}
+		result, err := l.queryLongUrlByShortUrl("", shortURL)
+
+		assert.Equal(t, "http://example.com/page?id=1&utm_source=link&utm_medium=email&utm_campaign=sale", result)
+		assert.Nil(t, err)
+	})
+
+	t.Run("blocked_domain", func(t *testing.T) {
+		svcCtx := &svc.ServiceContext{
+			ShortUrlMapRepository: mockShortUrlMap,
+			DomainRules:           newBlockedDomains(t, ".evil.example"),
+		}
+		l := &ResolveLogic{ctx: context.Background(), svcCtx: svcCtx}
+
+		// 长链接的域名在规则修改后被拒绝
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", "blocked").Return(&model.ShortUrlMap{
+			ShortUrl: "blocked",
+			LongUrl:  "https://www.evil.example/a",
+		}, nil)
+		result, err := l.queryLongUrlByShortUrl("", "blocked")
+		assert.Empty(t, result)
+		assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+
+		// 重定向后的最终地址被拒绝
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", "redirect").Return(&model.ShortUrlMap{
+			ShortUrl: "redirect",
+			LongUrl:  "https://good.example/a",
+			FinalUrl: "https://evil.example/landing",
+		}, nil)
+		_, err = l.queryLongUrlByShortUrl("", "redirect")
+		assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+
+		// 目标失效时跳转到允许的备用地址
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", "fallback").Return(&model.ShortUrlMap{
+			ShortUrl:    "fallback",
+			LongUrl:     "https://evil.example/a",
+			CheckStatus: model.CheckStatusBroken,
+			FallbackUrl: "https://good.example/",
+		}, nil)
+		result, err = l.queryLongUrlByShortUrl("", "fallback")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://good.example/", result)
+	})
+
+	t.Run("threat_list", func(t *testing.T) {
+		mockMatcher := threatMock.NewMockMatcher(ctrl)
+		svcCtx := &svc.ServiceContext{
+			ShortUrlMapRepository: mockShortUrlMap,
+			ThreatMatcher:         mockMatcher,
+		}
+		svcCtx.Config.Tracking.Tags = []config.UtmTagConf{{Tag: "spring", Source: "tag"}}
+		l := NewResolveLogic(context.Background(), svcCtx)
+
+		link := &model.ShortUrlMap{ShortUrl: "flagged", LongUrl: "https://malware.example/a", Tag: "spring"}
+
+		// 默认拒绝跳转
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", "flagged").Return(link, nil)
+		mockMatcher.EXPECT().Match("https://malware.example/a").Return(threat.Result{List: "malware", Verified: true}, true)
+		result, err := l.queryLongUrlByShortUrl("", "flagged")
+		assert.Empty(t, result)
+		assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+
+		// 未配置拒绝时只命中哈希前缀不拒绝跳转
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", "flagged").Return(link, nil)
+		mockMatcher.EXPECT().Match("https://malware.example/a").Return(threat.Result{List: "malware"}, true)
+		result, err = l.queryLongUrlByShortUrl("", "flagged")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://malware.example/a?utm_source=tag", result)
+
+		// 配置拒绝时只命中哈希前缀也拒绝跳转
+		svcCtx.Config.Threat.RejectPrefixMatches = true
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", "flagged").Return(link, nil)
+		mockMatcher.EXPECT().Match("https://malware.example/a").Return(threat.Result{List: "malware"}, true)
+		result, err = l.queryLongUrlByShortUrl("", "flagged")
+		assert.Empty(t, result)
+		assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+
+		// 跳转到提示页，保留提示页自身的参数
+		svcCtx.Config.Threat = config.ThreatConf{
+			Action:          config.ThreatActionInterstitial,
+			InterstitialUrl: "https://s.example/warning?lang=zh",
+		}
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", "flagged").Return(link, nil)
+		mockMatcher.EXPECT().Match("https://malware.example/a").Return(threat.Result{List: "malware", Verified: true}, true)
+		result, err = l.queryLongUrlByShortUrl("", "flagged")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://s.example/warning?lang=zh&list=malware&url=https%3A%2F%2Fmalware.example%2Fa%3Futm_source%3Dtag", result)
+
+		// 重定向后的最终地址命中列表
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", "redirect").Return(&model.ShortUrlMap{
+			ShortUrl: "redirect",
+			LongUrl:  "https://good.example/a",
+			FinalUrl: "https://malware.example/landing",
+		}, nil)
+		mockMatcher.EXPECT().Match("https://good.example/a").Return(threat.Result{}, false)
+		mockMatcher.EXPECT().Match("https://malware.example/landing").Return(threat.Result{List: "malware", Verified: true}, true)
+		result, err = l.queryLongUrlByShortUrl("", "redirect")
+		assert.NoError(t, err)
+		assert.Contains(t, result, "https://s.example/warning?")
+
+		// 配置了提示页时，只命中哈希前缀也跳转到提示页
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", "flagged").Return(link, nil)
+		mockMatcher.EXPECT().Match("https://malware.example/a").Return(threat.Result{List: "malware"}, true)
+		result, err = l.queryLongUrlByShortUrl("", "flagged")
+		assert.NoError(t, err)
+		assert.Contains(t, result, "https://s.example/warning?")
+	})
+
 	t.Run("not_found", func(t *testing.T) {
 		shortURL := "notFound"
-		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), shortURL).Return(nil, errorx.New(errorx.CodeNotFound, "not found"))
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", shortURL).Return(nil, errorx.New(errorx.CodeNotFound, "not found"))
 
 		l := &ResolveLogic{ctx: context.Background(), svcCtx: svcCtx}
-		result, err := l.queryLongUrlByShortUrl(shortURL)
+		result, err := l.queryLongUrlByShortUrl("", shortURL)
 
 		assert.Empty(t, result)
 		assert.Nil(t, err)
@@ -204,12 +397,75 @@ func TestResolveLogic_queryLongUrlByShortUrl(t *testing.T) {
 
 	t.Run("database_error", func(t *testing.T) {
 		shortURL := "dbError"
-		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), shortURL).Return(nil, errorx.New(errorx.CodeSystemError, "database error"))
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", shortURL).Return(nil, errorx.New(errorx.CodeSystemError, "database error"))
 
 		l := &ResolveLogic{ctx: context.Background(), svcCtx: svcCtx}
-		result, err := l.queryLongUrlByShortUrl(shortURL)
+		result, err := l.queryLongUrlByShortUrl("", shortURL)
 
 		assert.Empty(t, result)
 		assert.NotNil(t, err)
 	})
 }
+
+// 测试根据访问域名解析命名空间
+func TestResolveLogic_Namespace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShortUrlMap := repositoryMock.NewMockShortUrlMap(ctrl)
+	mockFilter := filterMock.NewMockFilter(ctrl)
+
+	svcCtx := &svc.ServiceContext{
+		Config: config.Config{
+			App: config.AppConf{
+				ShortUrlDomain: "example.com",
+				Namespaces:     []config.NamespaceConf{{Name: "brand", Domain: "brand.example"}},
+			},
+		},
+		ShortUrlMapRepository: mockShortUrlMap,
+		ShortCodeFilter:       mockFilter,
+	}
+
+	t.Run("brand_domain", func(t *testing.T) {
+		mockFilter.EXPECT().ExistsCtx(gomock.Any(), []byte("brand:abc")).Return(true, nil)
+		mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "brand", "abc").Return(&model.ShortUrlMap{LongUrl: "http://brand.com"}, nil)
+
+		l := NewResolveLogic(context.Background(), svcCtx)
+		resp, err := l.Resolve(&types.ResolveRequest{ShortCode: "abc", Domain: "Brand.example:443"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "http://brand.com", resp.OriginalUrl)
+	})
+
+	t.Run("unknown_domain_uses_default", func(t *testing.T) {
+		mockFilter.EXPECT().ExistsCtx(gomock.Any(), []byte("abc")).Return(false, nil)
+
+		l := NewResolveLogic(context.Background(), svcCtx)
+		_, err := l.Resolve(&types.ResolveRequest{ShortCode: "abc", Domain: "127.0.0.1:8888"})
+
+		assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+	})
+
+	t.Run("legacy_codes_per_namespace", func(t *testing.T) {
+		// 品牌命名空间开启校验字符前发放到1000，默认命名空间没有旧短码
+		defaultMax, brandMax := uint64(0), uint64(1000)
+		checked := *svcCtx
+		checked.Config.ShortCode = config.ShortCodeConf{CheckCode: true, LegacyMaxID: &defaultMax}
+		checked.Config.App.Namespaces = []config.NamespaceConf{{Name: "brand", Domain: "brand.example", LegacyMaxID: &brandMax}}
+
+		legacy := base62.Convert(500)
+		require.False(t, base62.ValidCheck(legacy))
+
+		mockFilter.EXPECT().ExistsCtx(gomock.Any(), []byte("brand:"+legacy)).Return(false, nil)
+		l := NewResolveLogic(context.Background(), &checked)
+		_, err := l.Resolve(&types.ResolveRequest{ShortCode: legacy, Domain: "brand.example"})
+		assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+
+		_, err = l.Resolve(&types.ResolveRequest{ShortCode: legacy, Domain: "example.com"})
+		assert.True(t, errorx.Is(err, errorx.CodeParamError))
+
+		mockFilter.EXPECT().ExistsCtx(gomock.Any(), []byte(base62.AppendCheck(legacy))).Return(false, nil)
+		_, err = l.Resolve(&types.ResolveRequest{ShortCode: base62.AppendCheck(legacy), Domain: "example.com"})
+		assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+	})
+}
@@ -2,17 +2,28 @@ package logic
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+	"net/url"
+	"shortener/internal/config"
 	"shortener/internal/model"
+	"shortener/internal/repository"
 	"shortener/internal/svc"
 	"shortener/internal/types"
 	"shortener/internal/types/errorx"
 	"shortener/pkg/base62"
 	"shortener/pkg/md5"
+	"shortener/pkg/threat"
 	"shortener/pkg/urlTool"
 	"strings"
+	"time"
 )
 
+// maxUtmLen 存储UTM参数的utm列长度，编码后超过时拒绝
+const maxUtmLen = 512
+
 type ShortenLogic struct {
 	logx.Logger
 	ctx    context.Context
@@ -30,53 +41,86 @@ func NewShortenLogic(ctx context.Context, svcCtx *svc.ServiceContext, client url
 }
 
 func (l *ShortenLogic) Shorten(req *types.ShortenRequest) (*types.ShortenResponse, error) {
-	//校验参数
-	isValidUrl, err := l.testConnectivity(req.LongUrl)
+	//确定命名空间
+	namespace, err := l.namespaceOf(req.Domain)
 	if err != nil {
 		return nil, err
 	}
-	if !isValidUrl {
-		return nil, errorx.New(errorx.CodeParamError, "failed to connect this URL")
+
+	//编码后的UTM参数需要能够完整存储
+	if err = checkUTM(req); err != nil {
+		return nil, err
 	}
 
-	logx.Infof("this URL is valid:%v", req.LongUrl)
+	//去掉跟踪参数，之后的检查、去重和存储都使用去掉后的长链接
+	req.LongUrl, err = l.stripTrackingParams(req.LongUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	//检查目标域名规则，在连通性检查之前拒绝，避免访问被禁止的域名
+	if err = l.checkDomainRules(req); err != nil {
+		return nil, err
+	}
+
+	//检查本地同步的恶意链接列表
+	if err = l.checkThreatLists(req); err != nil {
+		return nil, err
+	}
+
+	//校验参数
+	check, err := l.checkBeforeCreate(req.LongUrl)
+	if err != nil {
+		return nil, err
+	}
 
 	isShortUrl := l.inShortUrlDomainPath(req.LongUrl)
 	if isShortUrl {
 		return nil, errorx.New(errorx.CodeParamError, "URL is already shortUrl")
 	}
 
+	//备用地址同样不能是本服务的短链，否则目标失效时可能循环跳转
+	if len(req.FallbackUrl) != 0 && l.inShortUrlDomainPath(req.FallbackUrl) {
+		return nil, errorx.New(errorx.CodeParamError, "fallback URL is already shortUrl")
+	}
+
 	//检查此链接是否已有转链
 	//计算长链接的MD5
-	m, err := l.convertLongUrlIntoMD5(req.LongUrl)
+	m, err := l.convertLongUrlIntoMD5(req.LongUrl, req.Tag, requestUTM(req).Encode())
 	if err != nil {
 		return nil, err
 	}
 
 	//数据库查询MD5
-	shortUrl, err := l.findShortUrlByMD5(m)
+	shortUrl, err := l.findShortUrlByMD5(namespace, m)
 	if errorx.Is(err, errorx.CodeNotFound) || len(shortUrl) == 0 {
 		//转链
-		shortUrl, err = l.generateNonSensitiveShortUrl()
+		shortUrl, err = l.generateNonSensitiveShortUrl(namespace)
 		if err != nil {
 			return nil, err
 		}
 
 		//存储映射
-		err = l.storeInRepository(m, req.LongUrl, shortUrl)
+		err = l.storeInRepository(namespace, m, req, shortUrl, check)
 		if err != nil {
 			return nil, err
 		}
 
 		//存储过滤
-		err = l.storeShortUrlInFilter(shortUrl)
+		err = l.storeShortUrlInFilter(namespace, shortUrl)
 		if err != nil {
 			return nil, err
 		}
 
+		//异步策略在后台检查连通性
+		if l.svcCtx.Config.Connect.Policy == config.ConnectPolicyAsync {
+			l.checkInBackground(namespace, shortUrl, req.LongUrl)
+		}
+
 		//返回响应
 		return &types.ShortenResponse{
-			ShortCode: l.getFullShortLink(shortUrl),
+			ShortCode: l.getFullShortLink(namespace, shortUrl),
+			FinalUrl:  check.finalUrl,
 		}, nil
 	}
 
@@ -85,20 +129,179 @@ func (l *ShortenLogic) Shorten(req *types.ShortenRequest) (*types.ShortenRespons
 	}
 
 	if len(shortUrl) != 0 {
-		return &types.ShortenResponse{ShortCode: l.getFullShortLink(shortUrl)}, nil
+		return &types.ShortenResponse{ShortCode: l.getFullShortLink(namespace, shortUrl), FinalUrl: check.finalUrl}, nil
 	}
 
 	return nil, errorx.New(errorx.CodeDatabaseError, "shortUrl is empty")
 }
 
-func (l *ShortenLogic) testConnectivity(URL string) (bool, error) {
+// 根据品牌短域名确定命名空间，为空时使用默认命名空间
+func (l *ShortenLogic) namespaceOf(domain string) (string, error) {
+	if len(domain) == 0 {
+		return "", nil
+	}
+
+	namespace, ok := l.svcCtx.Config.App.NamespaceOf(domain)
+	if !ok {
+		return "", errorx.New(errorx.CodeParamError, "unknown short url domain").
+			WithMeta("domain", domain)
+	}
+	return namespace, nil
+}
+
+func (l *ShortenLogic) testConnectivity(URL string) (*urlTool.Result, error) {
 	return l.client.Check(URL)
 }
 
+// 长链接的连通性检查结果
+type destinationCheck struct {
+	status   uint64
+	code     int
+	finalUrl string
+}
+
+// 按连通性检查策略在生成短链前检查长链接，只有严格策略会同步检查并拒绝不可访问的链接
+func (l *ShortenLogic) checkBeforeCreate(URL string) (*destinationCheck, error) {
+	switch l.svcCtx.Config.Connect.Policy {
+	case config.ConnectPolicyOff, config.ConnectPolicyAsync:
+		return &destinationCheck{status: model.CheckStatusUnchecked}, nil
+	}
+
+	check, err := l.probeDestination(URL)
+	if err != nil {
+		return nil, err
+	}
+	if check.status != model.CheckStatusReachable {
+		return nil, errorx.New(errorx.CodeParamError, "failed to connect this URL").
+			WithMeta("statusCode", check.code)
+	}
+
+	logx.Infof("this URL is valid:%v", URL)
+	return check, nil
+}
+
+// 在后台检查长链接并记录结果，不可访问或违反安全策略的链接只做标记，不影响已生成的短链
+func (l *ShortenLogic) checkInBackground(namespace, shortUrl, URL string) {
+	ctx := context.WithoutCancel(l.ctx)
+	threading.GoSafe(func() {
+		check, err := l.probeDestination(URL)
+		if err != nil {
+			logx.WithContext(ctx).Infof("async check of %v failed,err:%v", URL, err)
+		}
+
+		err = l.svcCtx.ShortUrlMapRepository.UpdateCheckResult(ctx, &model.ShortUrlMap{
+			Namespace:   namespace,
+			ShortUrl:    shortUrl,
+			FinalUrl:    check.finalUrl,
+			CheckStatus: check.status,
+			CheckCode:   int64(check.code),
+			CheckedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+			FailCount:   failCountOf(check.status),
+		})
+		if err != nil {
+			logx.WithContext(ctx).Errorf("record check result of %v failed,err:%v", shortUrl, err)
+		}
+	})
+}
+
+// 检查长链接，开启重定向链解析时记录最终地址。
+// 返回错误时检查结果仍然有效，用于区分不可访问和违反安全策略被拒绝
+func (l *ShortenLogic) probeDestination(URL string) (*destinationCheck, error) {
+	if !l.svcCtx.Config.Connect.ResolveChain {
+		result, err := l.testConnectivity(URL)
+		if err != nil {
+			return failedCheck(err), err
+		}
+		return &destinationCheck{status: checkStatusOf(result.Reachable), code: result.StatusCode}, nil
+	}
+
+	chain, err := l.client.Resolve(URL)
+	if err != nil {
+		return failedCheck(err), err
+	}
+
+	check := &destinationCheck{
+		status:   checkStatusOf(chain.Reachable),
+		code:     chain.StatusCode,
+		finalUrl: chain.FinalUrl(),
+	}
+	if err = l.checkRedirectChain(chain); err != nil {
+		check.status = model.CheckStatusRejected
+		return check, err
+	}
+
+	logx.Infof("this URL is resolved:%v -> %v", URL, check.finalUrl)
+	return check, nil
+}
+
+func failedCheck(err error) *destinationCheck {
+	if errors.Is(err, urlTool.ErrForbiddenAddress) {
+		return &destinationCheck{status: model.CheckStatusRejected}
+	}
+	return &destinationCheck{status: model.CheckStatusUnreachable}
+}
+
+// 首次检查不可访问时记为一次连续失败，由巡检累计到阈值后判定为失效
+func failCountOf(status uint64) uint64 {
+	if status == model.CheckStatusUnreachable {
+		return 1
+	}
+	return 0
+}
+
+func checkStatusOf(reachable bool) uint64 {
+	if reachable {
+		return model.CheckStatusReachable
+	}
+	return model.CheckStatusUnreachable
+}
+
+// 拒绝跳回本服务短域名的重定向链，否则可以绕过已是短链的检查；
+// 拒绝经过不符合域名规则的域名、恶意链接或公共短链服务的重定向链，其目标随时可能被修改
+func (l *ShortenLogic) checkRedirectChain(chain *urlTool.Chain) error {
+	for i, hop := range chain.Hops {
+		u, err := url.Parse(hop.URL)
+		if err != nil {
+			return errorx.NewWithCause(errorx.CodeParamError, "invalid url in redirect chain", err)
+		}
+		host := strings.ToLower(u.Hostname())
+
+		if _, ok := l.svcCtx.Config.App.NamespaceOf(host); ok && i > 0 {
+			return errorx.New(errorx.CodeParamError, "URL redirects to a short link of this service").
+				WithMeta("hop", hop.URL)
+		}
+		if !hostAllowed(l.svcCtx, host) {
+			return errorx.New(errorx.CodeParamError, "URL redirects to a domain that is not allowed").
+				WithMeta("hop", hop.URL)
+		}
+		if list, ok := l.maliciousListOf(hop.URL); ok {
+			return errorx.New(errorx.CodeParamError, "URL redirects to a malicious url").
+				WithMeta("hop", hop.URL).
+				WithMeta("list", list)
+		}
+		if matchDomain(host, l.svcCtx.Config.Connect.PublicShorteners) {
+			return errorx.New(errorx.CodeParamError, "URL redirects through a public url shortener").
+				WithMeta("hop", hop.URL)
+		}
+	}
+	return nil
+}
+
+// 判断主机是否为列表中的域名或其子域名
+func matchDomain(host string, domains []string) bool {
+	for _, domain := range domains {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
 func (l *ShortenLogic) inShortUrlDomainPath(url string) bool {
 	domain, path := urlTool.GetDomainAndPath(url)
 
-	if domain == l.svcCtx.Config.App.ShortUrlDomain {
+	if _, ok := l.svcCtx.Config.App.NamespaceOf(domain); ok {
 		// 处理配置路径可能带有前导斜杠的情况
 		configPath := l.svcCtx.Config.App.ShortUrlPath
 		configPath = strings.TrimPrefix(configPath, "/")
@@ -108,9 +311,114 @@ func (l *ShortenLogic) inShortUrlDomainPath(url string) bool {
 	return false
 }
 
+// 长链接和备用地址的域名都需要符合域名规则
+func (l *ShortenLogic) checkDomainRules(req *types.ShortenRequest) error {
+	if !destinationAllowed(l.svcCtx, req.LongUrl) {
+		return errorx.New(errorx.CodeParamError, "the domain of this URL is not allowed").
+			WithMeta("longUrl", req.LongUrl)
+	}
+	if len(req.FallbackUrl) != 0 && !destinationAllowed(l.svcCtx, req.FallbackUrl) {
+		return errorx.New(errorx.CodeParamError, "the domain of fallback URL is not allowed").
+			WithMeta("fallbackUrl", req.FallbackUrl)
+	}
+	return nil
+}
+
+// 判断目标地址的域名是否符合域名规则，无法解析的地址视为不符合
+func destinationAllowed(svcCtx *svc.ServiceContext, rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return false
+	}
+	return hostAllowed(svcCtx, u.Hostname())
+}
+
+func hostAllowed(svcCtx *svc.ServiceContext, host string) bool {
+	if svcCtx.DomainRules == nil {
+		return true
+	}
+	return svcCtx.DomainRules.Allowed(host)
+}
+
+// 长链接和备用地址都不能命中恶意链接列表
+func (l *ShortenLogic) checkThreatLists(req *types.ShortenRequest) error {
+	if list, ok := l.maliciousListOf(req.LongUrl); ok {
+		return errorx.New(errorx.CodeParamError, "URL is on a malicious url list").
+			WithMeta("longUrl", req.LongUrl).
+			WithMeta("list", list)
+	}
+	if len(req.FallbackUrl) != 0 {
+		if list, ok := l.maliciousListOf(req.FallbackUrl); ok {
+			return errorx.New(errorx.CodeParamError, "fallback URL is on a malicious url list").
+				WithMeta("fallbackUrl", req.FallbackUrl).
+				WithMeta("list", list)
+		}
+	}
+	return nil
+}
+
+// 返回地址命中的列表名，只命中哈希前缀的地址未经确认，未配置拒绝时记录日志后放行
+func (l *ShortenLogic) maliciousListOf(rawUrl string) (string, bool) {
+	result, ok := threatListOf(l.svcCtx, rawUrl)
+	if !ok {
+		return "", false
+	}
+	if !result.Verified && !l.svcCtx.Config.Threat.RejectPrefixMatches {
+		l.Infof("url matches a threat list prefix only,url:%v,list:%v", rawUrl, result.List)
+		return "", false
+	}
+	return result.List, true
+}
+
+// 判断地址是否命中恶意链接列表
+func threatListOf(svcCtx *svc.ServiceContext, rawUrl string) (threat.Result, bool) {
+	if svcCtx.ThreatMatcher == nil {
+		return threat.Result{}, false
+	}
+	return svcCtx.ThreatMatcher.Match(rawUrl)
+}
+
+// 去掉配置的跟踪参数
+func (l *ShortenLogic) stripTrackingParams(longUrl string) (string, error) {
+	stripped, err := urlTool.StripParams(longUrl, l.svcCtx.Config.Tracking.StripParams)
+	if err != nil {
+		return "", errorx.NewWithCause(errorx.CodeParamError, "invalid long url", err)
+	}
+	return stripped, nil
+}
+
+// 非ASCII字符编码后长度会成倍增加，单个参数的长度校验不能保证编码后的结果不超过utm列的长度
+func checkUTM(req *types.ShortenRequest) error {
+	if utm := requestUTM(req).Encode(); len(utm) > maxUtmLen {
+		return errorx.New(errorx.CodeParamError, "the encoded utm parameters are too long").
+			WithMeta("length", len(utm)).
+			WithMeta("max", maxUtmLen)
+	}
+	return nil
+}
+
+// 请求中的UTM参数
+func requestUTM(req *types.ShortenRequest) urlTool.UTM {
+	return urlTool.UTM{Source: req.UtmSource, Medium: req.UtmMedium, Campaign: req.UtmCampaign}
+}
+
 // 将长链接转换为MD5
-func (l *ShortenLogic) convertLongUrlIntoMD5(longUrl string) (string, error) {
-	m, err := md5.Sum([]byte(longUrl))
+func (l *ShortenLogic) convertLongUrlIntoMD5(longUrl, tag, utm string) (string, error) {
+	return longUrlMD5(l.svcCtx.Config.Canonical, longUrl, tag, utm)
+}
+
+// 计算长链接规范形式的MD5，写法不同但指向同一地址的长链接复用同一个短链。
+// 标签或UTM参数不同的短链分别去重，同一个目标地址可以用于不同的推广活动
+func longUrlMD5(conf config.CanonicalConf, longUrl, tag, utm string) (string, error) {
+	canonical, err := urlTool.Canonicalize(longUrl, conf)
+	if err != nil {
+		return "", errorx.NewWithCause(errorx.CodeParamError, "invalid long url", err)
+	}
+	if len(tag) != 0 || len(utm) != 0 {
+		canonical += "\x00" + tag + "\x00" + utm
+	}
+
+	m, err := md5.Sum([]byte(canonical))
 	if err != nil {
 		return "", errorx.Wrap(err, errorx.CodeSystemError, "fail to convert longUrl into MD5")
 	}
@@ -118,8 +426,8 @@ func (l *ShortenLogic) convertLongUrlIntoMD5(longUrl string) (string, error) {
 }
 
 // 根据md5查询是否已有转链
-func (l *ShortenLogic) findShortUrlByMD5(m string) (string, error) {
-	data, err := l.svcCtx.ShortUrlMapRepository.FindOneByMd5(l.ctx, m)
+func (l *ShortenLogic) findShortUrlByMD5(namespace, m string) (string, error) {
+	data, err := l.svcCtx.ShortUrlMapRepository.FindOneByMd5(l.ctx, namespace, m)
 	if err == nil {
 		return data.ShortUrl, nil
 	}
@@ -132,18 +440,26 @@ func (l *ShortenLogic) findShortUrlByMD5(m string) (string, error) {
 }
 
 // 转化为短链
-func (l *ShortenLogic) generateNonSensitiveShortUrl() (string, error) {
+func (l *ShortenLogic) generateNonSensitiveShortUrl(namespace string) (string, error) {
 	maxAttempts := 5
 
 	for i := 0; i < maxAttempts; i++ {
 		//获取序号ID
-		id, err := l.svcCtx.SequenceRepository.NextID(l.ctx)
+		id, err := l.svcCtx.SequenceRepository.NextID(l.ctx, namespace)
 		if err != nil {
 			return "", errorx.Wrap(err, errorx.CodeDatabaseError, "fail to get sequence next ID")
 		}
 
 		//ID转链
 		url := base62.Convert(id)
+		if l.svcCtx.Config.ShortCode.CheckCode {
+			url = base62.AppendCheck(url)
+		}
+		if len(url) > base62.MaxCodeLen {
+			return "", errorx.New(errorx.CodeSystemError, "the short code exceeds the maximum length").
+				WithMeta("id", id).
+				WithMeta("max", base62.MaxCodeLen)
+		}
 
 		// 检查敏感词
 		if !l.svcCtx.SensitiveFilter.ContainsBadWord(url) {
@@ -157,14 +473,22 @@ func (l *ShortenLogic) generateNonSensitiveShortUrl() (string, error) {
 }
 
 // 数据持久化
-func (l *ShortenLogic) storeInRepository(md5 string, longUrl, shortUrl string) error {
+func (l *ShortenLogic) storeInRepository(namespace, md5 string, req *types.ShortenRequest, shortUrl string, check *destinationCheck) error {
 	//存储到仓库中
 	err := l.svcCtx.ShortUrlMapRepository.Insert(l.ctx, &model.ShortUrlMap{
-		CreateBy: l.svcCtx.Config.App.Operator,
-		IsDel:    0,
-		LongUrl:  longUrl,
-		Md5:      md5,
-		ShortUrl: shortUrl,
+		CreateBy:    l.svcCtx.Config.App.Operator,
+		IsDel:       0,
+		LongUrl:     req.LongUrl,
+		Md5:         md5,
+		ShortUrl:    shortUrl,
+		Namespace:   namespace,
+		FinalUrl:    check.finalUrl,
+		CheckStatus: check.status,
+		CheckCode:   int64(check.code),
+		CheckedAt:   sql.NullTime{Time: time.Now(), Valid: check.status != model.CheckStatusUnchecked},
+		FallbackUrl: req.FallbackUrl,
+		Tag:         req.Tag,
+		Utm:         requestUTM(req).Encode(),
 	})
 
 	if err != nil {
@@ -174,14 +498,19 @@ func (l *ShortenLogic) storeInRepository(md5 string, longUrl, shortUrl string) e
 }
 
 // 添加到过滤器中
-func (l *ShortenLogic) storeShortUrlInFilter(shortUrl string) error {
-	err := l.svcCtx.ShortCodeFilter.AddCtx(l.ctx, []byte(shortUrl))
+func (l *ShortenLogic) storeShortUrlInFilter(namespace, shortUrl string) error {
+	err := l.svcCtx.ShortCodeFilter.AddCtx(l.ctx, repository.FilterKey(namespace, shortUrl))
 	if err != nil {
 		return errorx.Wrap(err, errorx.CodeSystemError, "fail to store shortUrl in filter")
 	}
 	return nil
 }
 
-func (l *ShortenLogic) getFullShortLink(shortUrl string) string {
-	return l.svcCtx.Config.App.ShortUrlDomain + l.svcCtx.Config.App.ShortUrlPath + shortUrl
+func (l *ShortenLogic) getFullShortLink(namespace, shortUrl string) string {
+	return fullShortLink(l.svcCtx.Config.App, namespace, shortUrl)
+}
+
+// fullShortLink 拼接命名空间的短域名、短链路径和短码
+func fullShortLink(app config.AppConf, namespace, shortUrl string) string {
+	return app.DomainOf(namespace) + app.ShortUrlPath + shortUrl
 }
@@ -5,17 +5,24 @@ import (
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
+	"math"
 	"shortener/internal/config"
 	"shortener/internal/model"
 	repositoryMock "shortener/internal/repository/mock"
 	"shortener/internal/svc"
 	"shortener/internal/types"
 	"shortener/internal/types/errorx"
+	"shortener/pkg/domainRule"
 	filterMock "shortener/pkg/filter/mock"
 	"shortener/pkg/md5"
 	sensitiveMock "shortener/pkg/sensitive/mock"
+	"shortener/pkg/threat"
+	threatMock "shortener/pkg/threat/mock"
+	"shortener/pkg/urlTool"
 	urlToolMock "shortener/pkg/urlTool/mock"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestShortenLogic_Shorten(t *testing.T) {
@@ -50,7 +57,7 @@ func TestShortenLogic_Shorten(t *testing.T) {
 	// 测试场景一：无效URL
 	t.Run("invalid_url", func(t *testing.T) {
 		longURL := "invalid-url"
-		mockURLClient.EXPECT().Check(longURL).Return(false, nil)
+		mockURLClient.EXPECT().Check(longURL).Return(&urlTool.Result{StatusCode: 500}, nil)
 
 		l := NewShortenLogic(context.Background(), svcCtx, mockURLClient)
 		resp, err := l.Shorten(&types.ShortenRequest{LongUrl: longURL})
@@ -63,7 +70,7 @@ func TestShortenLogic_Shorten(t *testing.T) {
 	// 测试场景二：已经是短链接
 	t.Run("already_short_url", func(t *testing.T) {
 		url := "http://example.com/short/abc123"
-		mockURLClient.EXPECT().Check(url).Return(true, nil)
+		mockURLClient.EXPECT().Check(url).Return(&urlTool.Result{Reachable: true, StatusCode: 200}, nil)
 
 		l := NewShortenLogic(context.Background(), svcCtx, mockURLClient)
 		resp, err := l.Shorten(&types.ShortenRequest{LongUrl: url})
@@ -78,14 +85,14 @@ func TestShortenLogic_Shorten(t *testing.T) {
 		longURL := "http://example.com"
 		shortURL := "abc123"
 
-		// 动态计算MD5值，与代码中使用相同的算法
-		correctMd5, _ := md5.Sum([]byte(longURL))
+		// 动态计算MD5值，与代码中使用相同的算法，空路径规范化为"/"
+		correctMd5, _ := md5.Sum([]byte(longURL + "/"))
 
 		// 设置URL检查返回有效
-		mockURLClient.EXPECT().Check(longURL).Return(true, nil)
+		mockURLClient.EXPECT().Check(longURL).Return(&urlTool.Result{Reachable: true, StatusCode: 200}, nil)
 
 		// 使用正确计算出的MD5值
-		mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), correctMd5).Return(&model.ShortUrlMap{
+		mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), "", correctMd5).Return(&model.ShortUrlMap{
 			ShortUrl: shortURL,
 		}, nil)
 
@@ -102,11 +109,11 @@ func TestShortenLogic_Shorten(t *testing.T) {
 		longURL := "http://newtest.com/page"
 		correctMd5, _ := md5.Sum([]byte(longURL))
 
-		mockURLClient.EXPECT().Check(longURL).Return(true, nil)
-		mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), correctMd5).Return(nil, errorx.New(errorx.CodeNotFound, "data is not found"))
+		mockURLClient.EXPECT().Check(longURL).Return(&urlTool.Result{Reachable: true, StatusCode: 200}, nil)
+		mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), "", correctMd5).Return(nil, errorx.New(errorx.CodeNotFound, "data is not found"))
 
 		// 期望生成序列号并转为短链接
-		mockSequence.EXPECT().NextID(gomock.Any()).Return(uint64(12345), nil)
+		mockSequence.EXPECT().NextID(gomock.Any(), "").Return(uint64(12345), nil)
 
 		// 模拟敏感词检测，返回不包含敏感词
 		mockSensitiveFilter.EXPECT().ContainsBadWord(gomock.Any()).Return(false)
@@ -130,12 +137,12 @@ func TestShortenLogic_Shorten(t *testing.T) {
 		longURL := "http://sensitive.com/page"
 		md5Hex, _ := md5.Sum([]byte(longURL))
 
-		mockURLClient.EXPECT().Check(longURL).Return(true, nil)
-		mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), md5Hex).Return(nil, errorx.New(errorx.CodeNotFound, "data is not found"))
+		mockURLClient.EXPECT().Check(longURL).Return(&urlTool.Result{Reachable: true, StatusCode: 200}, nil)
+		mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), "", md5Hex).Return(nil, errorx.New(errorx.CodeNotFound, "data is not found"))
 
 		// 模拟5次尝试都生成了包含敏感词的短链接
 		for i := 0; i < 5; i++ {
-			mockSequence.EXPECT().NextID(gomock.Any()).Return(uint64(i+1), nil)
+			mockSequence.EXPECT().NextID(gomock.Any(), "").Return(uint64(i+1), nil)
 			mockSensitiveFilter.EXPECT().ContainsBadWord(gomock.Any()).Return(true)
 		}
 
@@ -157,26 +164,355 @@ func TestShortenLogic_testConnectivity(t *testing.T) {
 
 	t.Run("valid_url", func(t *testing.T) {
 		url := "http://valid.com"
-		mockURLClient.EXPECT().Check(url).Return(true, nil)
+		mockURLClient.EXPECT().Check(url).Return(&urlTool.Result{Reachable: true, StatusCode: 200}, nil)
 
 		l := &ShortenLogic{client: mockURLClient}
 		result, _ := l.testConnectivity(url)
 
-		assert.True(t, result)
+		assert.True(t, result.Reachable)
 	})
 
 	t.Run("invalid_url", func(t *testing.T) {
 		url := "invalid-url"
-		mockURLClient.EXPECT().Check(url).Return(false, nil)
+		mockURLClient.EXPECT().Check(url).Return(&urlTool.Result{StatusCode: 500}, nil)
 
 		l := &ShortenLogic{client: mockURLClient}
 		result, _ := l.testConnectivity(url)
 
-		assert.False(t, result)
+		assert.False(t, result.Reachable)
+		assert.Equal(t, 500, result.StatusCode)
 	})
 }
 
 // 测试短链接检查函数
+// 测试重定向链解析
+func TestShortenLogic_probeDestination(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockURLClient := urlToolMock.NewMockClient(ctrl)
+	svcCtx := &svc.ServiceContext{
+		Config: config.Config{
+			App: config.AppConf{
+				ShortUrlDomain: "s.example.com",
+				ShortUrlPath:   "/short/",
+				Namespaces:     []config.NamespaceConf{{Name: "brand", Domain: "go.brand.com"}},
+			},
+			Connect: config.ConnectConf{
+				ResolveChain:     true,
+				PublicShorteners: []string{"bit.ly", "t.co"},
+			},
+		},
+	}
+	svcCtx.DomainRules = newBlockedDomains(t, "evil.example")
+	l := NewShortenLogic(context.Background(), svcCtx, mockURLClient)
+
+	chainOf := func(reachable bool, urls ...string) *urlTool.Chain {
+		chain := &urlTool.Chain{Result: urlTool.Result{Reachable: reachable}}
+		for _, u := range urls {
+			chain.Hops = append(chain.Hops, urlTool.Hop{URL: u, StatusCode: 302})
+		}
+		return chain
+	}
+
+	tests := []struct {
+		name         string
+		chain        *urlTool.Chain
+		expectUrl    string
+		expectStatus uint64
+		expectErr    string
+	}{
+		{
+			name:         "resolved",
+			chain:        chainOf(true, "http://a.com/x", "https://www.a.com/x"),
+			expectUrl:    "https://www.a.com/x",
+			expectStatus: model.CheckStatusReachable,
+		},
+		{
+			name:         "no_redirect",
+			chain:        chainOf(true, "http://a.com/x"),
+			expectUrl:    "http://a.com/x",
+			expectStatus: model.CheckStatusReachable,
+		},
+		{
+			name:         "loop_back_to_own_domain",
+			chain:        chainOf(true, "http://a.com/x", "https://S.example.com/short/abc"),
+			expectUrl:    "https://S.example.com/short/abc",
+			expectStatus: model.CheckStatusRejected,
+			expectErr:    "URL redirects to a short link of this service",
+		},
+		{
+			name:         "loop_back_to_brand_domain",
+			chain:        chainOf(true, "http://a.com/x", "https://go.brand.com:443/abc"),
+			expectUrl:    "https://go.brand.com:443/abc",
+			expectStatus: model.CheckStatusRejected,
+			expectErr:    "URL redirects to a short link of this service",
+		},
+		{
+			name:         "public_shortener",
+			chain:        chainOf(true, "https://bit.ly/abc", "https://a.com/x"),
+			expectUrl:    "https://a.com/x",
+			expectStatus: model.CheckStatusRejected,
+			expectErr:    "URL redirects through a public url shortener",
+		},
+		{
+			name:         "public_shortener_subdomain",
+			chain:        chainOf(true, "https://a.com/x", "https://www.t.co/abc", "https://b.com/"),
+			expectUrl:    "https://b.com/",
+			expectStatus: model.CheckStatusRejected,
+			expectErr:    "URL redirects through a public url shortener",
+		},
+		{
+			name:         "blocked_domain",
+			chain:        chainOf(true, "http://a.com/x", "https://Evil.example/"),
+			expectUrl:    "https://Evil.example/",
+			expectStatus: model.CheckStatusRejected,
+			expectErr:    "URL redirects to a domain that is not allowed",
+		},
+		{
+			name:         "unreachable",
+			chain:        chainOf(false, "http://a.com/x", "http://a.com/missing"),
+			expectUrl:    "http://a.com/missing",
+			expectStatus: model.CheckStatusUnreachable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockURLClient.EXPECT().Resolve(tt.chain.Hops[0].URL).Return(tt.chain, nil)
+
+			check, err := l.probeDestination(tt.chain.Hops[0].URL)
+			if len(tt.expectErr) > 0 {
+				assert.True(t, errorx.Is(err, errorx.CodeParamError), "实际: %v", err)
+				assert.Contains(t, err.Error(), tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectUrl, check.finalUrl)
+			assert.Equal(t, tt.expectStatus, check.status)
+		})
+	}
+
+	t.Run("resolve_error", func(t *testing.T) {
+		mockURLClient.EXPECT().Resolve("http://a.com/loop").
+			Return(nil, errorx.New(errorx.CodeParamError, "the url redirects in a loop"))
+
+		check, err := l.probeDestination("http://a.com/loop")
+		assert.True(t, errorx.Is(err, errorx.CodeParamError))
+		assert.Equal(t, model.CheckStatusUnreachable, check.status)
+	})
+
+	t.Run("forbidden_address", func(t *testing.T) {
+		mockURLClient.EXPECT().Resolve("http://internal.example/").
+			Return(nil, errorx.NewWithCause(errorx.CodeParamError, "the url points to a forbidden address", urlTool.ErrForbiddenAddress))
+
+		check, err := l.probeDestination("http://internal.example/")
+		assert.Error(t, err)
+		assert.Equal(t, model.CheckStatusRejected, check.status)
+	})
+}
+
+// 测试生成短链时检查目标域名规则
+func TestShortenLogic_DomainRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// 域名被拒绝时不进行连通性检查
+	mockURLClient := urlToolMock.NewMockClient(ctrl)
+	svcCtx := &svc.ServiceContext{DomainRules: newBlockedDomains(t, ".evil.example")}
+	l := NewShortenLogic(context.Background(), svcCtx, mockURLClient)
+
+	t.Run("blocked_long_url", func(t *testing.T) {
+		resp, err := l.Shorten(&types.ShortenRequest{LongUrl: "https://www.evil.example/a"})
+
+		assert.Nil(t, resp)
+		assert.True(t, errorx.Is(err, errorx.CodeParamError))
+		assert.Contains(t, err.Error(), "the domain of this URL is not allowed")
+	})
+
+	t.Run("blocked_fallback_url", func(t *testing.T) {
+		resp, err := l.Shorten(&types.ShortenRequest{
+			LongUrl:     "https://good.example/a",
+			FallbackUrl: "https://evil.example/",
+		})
+
+		assert.Nil(t, resp)
+		assert.True(t, errorx.Is(err, errorx.CodeParamError))
+		assert.Contains(t, err.Error(), "the domain of fallback URL is not allowed")
+	})
+}
+
+// 测试生成短链时检查恶意链接列表
+func TestShortenLogic_ThreatLists(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// 命中列表时不进行连通性检查
+	mockURLClient := urlToolMock.NewMockClient(ctrl)
+	mockMatcher := threatMock.NewMockMatcher(ctrl)
+	svcCtx := &svc.ServiceContext{ThreatMatcher: mockMatcher}
+	l := NewShortenLogic(context.Background(), svcCtx, mockURLClient)
+
+	t.Run("malicious_long_url", func(t *testing.T) {
+		mockMatcher.EXPECT().Match("https://malware.example/a").Return(threat.Result{List: "malware", Verified: true}, true)
+
+		resp, err := l.Shorten(&types.ShortenRequest{LongUrl: "https://malware.example/a"})
+
+		assert.Nil(t, resp)
+		assert.True(t, errorx.Is(err, errorx.CodeParamError))
+		assert.Contains(t, err.Error(), "URL is on a malicious url list")
+	})
+
+	t.Run("malicious_fallback_url", func(t *testing.T) {
+		mockMatcher.EXPECT().Match("https://good.example/a").Return(threat.Result{}, false)
+		mockMatcher.EXPECT().Match("https://phish.example/").Return(threat.Result{List: "phishing", Verified: true}, true)
+
+		resp, err := l.Shorten(&types.ShortenRequest{
+			LongUrl:     "https://good.example/a",
+			FallbackUrl: "https://phish.example/",
+		})
+
+		assert.Nil(t, resp)
+		assert.True(t, errorx.Is(err, errorx.CodeParamError))
+		assert.Contains(t, err.Error(), "fallback URL is on a malicious url list")
+	})
+
+	t.Run("unverified_prefix_match", func(t *testing.T) {
+		// 未配置拒绝时只命中哈希前缀的地址放行，继续连通性检查
+		mockMatcher.EXPECT().Match("https://prefix.example/a").Return(threat.Result{List: "malware"}, true)
+
+		err := l.checkThreatLists(&types.ShortenRequest{LongUrl: "https://prefix.example/a"})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejected_prefix_match", func(t *testing.T) {
+		rejecting := *svcCtx
+		rejecting.Config.Threat.RejectPrefixMatches = true
+		l := NewShortenLogic(context.Background(), &rejecting, mockURLClient)
+		mockMatcher.EXPECT().Match("https://prefix.example/a").Return(threat.Result{List: "malware"}, true)
+
+		err := l.checkThreatLists(&types.ShortenRequest{LongUrl: "https://prefix.example/a"})
+
+		assert.True(t, errorx.Is(err, errorx.CodeParamError))
+		assert.Contains(t, err.Error(), "URL is on a malicious url list")
+	})
+
+	t.Run("malicious_redirect", func(t *testing.T) {
+		mockMatcher.EXPECT().Match("https://good.example/a").Return(threat.Result{}, false)
+		mockMatcher.EXPECT().Match("https://malware.example/").Return(threat.Result{List: "malware", Verified: true}, true)
+
+		err := l.checkRedirectChain(&urlTool.Chain{Hops: []urlTool.Hop{
+			{URL: "https://good.example/a", StatusCode: 302},
+			{URL: "https://malware.example/", StatusCode: 200},
+		}})
+
+		assert.True(t, errorx.Is(err, errorx.CodeParamError))
+		assert.Contains(t, err.Error(), "URL redirects to a malicious url")
+	})
+}
+
+// newBlockedDomains 创建只在内存中的拒绝规则
+func newBlockedDomains(t *testing.T, rules ...string) domainRule.Rules {
+	r, err := domainRule.NewRules(config.DomainRuleConf{Mode: config.DomainRuleModeBlock})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Add(context.Background(), rules))
+	return r
+}
+
+// 测试连通性检查策略
+func TestShortenLogic_ConnectPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShortUrlMap := repositoryMock.NewMockShortUrlMap(ctrl)
+	mockSequence := repositoryMock.NewMockSequence(ctrl)
+	mockFilter := filterMock.NewMockFilter(ctrl)
+	mockSensitiveFilter := sensitiveMock.NewMockFilter(ctrl)
+	mockURLClient := urlToolMock.NewMockClient(ctrl)
+
+	newSvcCtx := func(policy string) *svc.ServiceContext {
+		return &svc.ServiceContext{
+			Config: config.Config{
+				App:     config.AppConf{ShortUrlDomain: "example.com", ShortUrlPath: "/short/"},
+				Connect: config.ConnectConf{Policy: policy},
+			},
+			ShortUrlMapRepository: mockShortUrlMap,
+			SequenceRepository:    mockSequence,
+			ShortCodeFilter:       mockFilter,
+			SensitiveFilter:       mockSensitiveFilter,
+		}
+	}
+
+	expectCreate := func(longURL string, id uint64, verify func(data *model.ShortUrlMap)) {
+		correctMd5, _ := md5.Sum([]byte(longURL))
+		mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), "", correctMd5).Return(nil, errorx.New(errorx.CodeNotFound, "data is not found"))
+		mockSequence.EXPECT().NextID(gomock.Any(), "").Return(id, nil)
+		mockSensitiveFilter.EXPECT().ContainsBadWord(gomock.Any()).Return(false)
+		mockShortUrlMap.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, data *model.ShortUrlMap) error {
+			verify(data)
+			return nil
+		})
+		mockFilter.EXPECT().AddCtx(gomock.Any(), gomock.Any()).Return(nil)
+	}
+
+	t.Run("strict_records_result", func(t *testing.T) {
+		longURL := "http://strict.com/page"
+		mockURLClient.EXPECT().Check(longURL).Return(&urlTool.Result{Reachable: true, StatusCode: 204}, nil)
+		expectCreate(longURL, 1, func(data *model.ShortUrlMap) {
+			assert.Equal(t, model.CheckStatusReachable, data.CheckStatus)
+			assert.Equal(t, int64(204), data.CheckCode)
+			assert.True(t, data.CheckedAt.Valid)
+		})
+
+		_, err := NewShortenLogic(context.Background(), newSvcCtx(config.ConnectPolicyStrict), mockURLClient).
+			Shorten(&types.ShortenRequest{LongUrl: longURL})
+		assert.NoError(t, err)
+	})
+
+	t.Run("off_skips_check", func(t *testing.T) {
+		longURL := "http://off.com/page"
+		expectCreate(longURL, 2, func(data *model.ShortUrlMap) {
+			assert.Equal(t, model.CheckStatusUnchecked, data.CheckStatus)
+			assert.False(t, data.CheckedAt.Valid)
+		})
+
+		_, err := NewShortenLogic(context.Background(), newSvcCtx(config.ConnectPolicyOff), mockURLClient).
+			Shorten(&types.ShortenRequest{LongUrl: longURL})
+		assert.NoError(t, err)
+	})
+
+	t.Run("async_flags_unreachable_link", func(t *testing.T) {
+		longURL := "http://async.com/page"
+		expectCreate(longURL, 3, func(data *model.ShortUrlMap) {
+			assert.Equal(t, model.CheckStatusUnchecked, data.CheckStatus)
+		})
+
+		done := make(chan *model.ShortUrlMap, 1)
+		mockURLClient.EXPECT().Check(longURL).Return(&urlTool.Result{StatusCode: 503}, nil)
+		mockShortUrlMap.EXPECT().UpdateCheckResult(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, data *model.ShortUrlMap) error {
+			done <- data
+			return nil
+		})
+
+		// 目标暂时不可访问时仍然生成短链
+		resp, err := NewShortenLogic(context.Background(), newSvcCtx(config.ConnectPolicyAsync), mockURLClient).
+			Shorten(&types.ShortenRequest{LongUrl: longURL})
+		assert.NoError(t, err)
+		assert.Equal(t, "example.com/short/3", resp.ShortCode)
+
+		select {
+		case data := <-done:
+			assert.Equal(t, "3", data.ShortUrl)
+			assert.Equal(t, model.CheckStatusUnreachable, data.CheckStatus)
+			assert.Equal(t, int64(503), data.CheckCode)
+			assert.True(t, data.CheckedAt.Valid)
+		case <-time.After(time.Second):
+			t.Fatal("check result is not recorded")
+		}
+	})
+}
+
 func TestShortenLogic_inShortUrlDomainPath(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -222,15 +558,72 @@ func TestShortenLogic_inShortUrlDomainPath(t *testing.T) {
 
 // 测试MD5转换函数
 func TestShortenLogic_convertLongUrlIntoMD5(t *testing.T) {
-	l := &ShortenLogic{}
+	l := &ShortenLogic{svcCtx: &svc.ServiceContext{}}
 
 	t.Run("valid_conversion", func(t *testing.T) {
 		url := "http://example.com/page"
-		m, err := l.convertLongUrlIntoMD5(url)
+		m, err := l.convertLongUrlIntoMD5(url, "", "")
 
 		assert.Nil(t, err)
 		assert.NotEmpty(t, m)
 	})
+
+	t.Run("same_md5_for_equivalent_urls", func(t *testing.T) {
+		want, err := l.convertLongUrlIntoMD5("https://example.com/a", "", "")
+		assert.Nil(t, err)
+
+		for _, url := range []string{"HTTPS://Example.com/a", "https://example.com:443/a", "https://example.com/a#", "https://example.com/%61"} {
+			m, err := l.convertLongUrlIntoMD5(url, "", "")
+			assert.Nil(t, err)
+			assert.Equal(t, want, m, url)
+		}
+	})
+
+	t.Run("campaigns_are_deduplicated_separately", func(t *testing.T) {
+		plain, _ := l.convertLongUrlIntoMD5("https://example.com/a", "", "")
+		tagged, _ := l.convertLongUrlIntoMD5("https://example.com/a", "spring", "")
+		campaign, _ := l.convertLongUrlIntoMD5("https://example.com/a", "", "utm_campaign=spring")
+
+		assert.NotEqual(t, plain, tagged)
+		assert.NotEqual(t, plain, campaign)
+		assert.NotEqual(t, tagged, campaign)
+	})
+
+	t.Run("invalid_url", func(t *testing.T) {
+		_, err := l.convertLongUrlIntoMD5("http://example.com/%zz\x7f:", "", "")
+		assert.True(t, errorx.Is(err, errorx.CodeParamError))
+	})
+}
+
+// 测试去掉跟踪参数
+func TestShortenLogic_stripTrackingParams(t *testing.T) {
+	svcCtx := &svc.ServiceContext{}
+	svcCtx.Config.Tracking.StripParams = []string{"fbclid", "gclid"}
+	l := &ShortenLogic{ctx: context.Background(), svcCtx: svcCtx}
+
+	stripped, err := l.stripTrackingParams("https://example.com/a?fbclid=x&id=1&gclid=y")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/a?id=1", stripped)
+
+	_, err = l.stripTrackingParams("http://[::1")
+	assert.True(t, errorx.Is(err, errorx.CodeParamError))
+}
+
+// 测试编码后的UTM参数长度
+func TestCheckUTM(t *testing.T) {
+	assert.NoError(t, checkUTM(&types.ShortenRequest{}))
+	assert.NoError(t, checkUTM(&types.ShortenRequest{
+		UtmSource:   strings.Repeat("a", 128),
+		UtmMedium:   strings.Repeat("b", 128),
+		UtmCampaign: strings.Repeat("c", 128),
+	}))
+
+	// 单个参数不超过128个字符，编码后超过utm列的长度
+	err := checkUTM(&types.ShortenRequest{
+		UtmSource:   strings.Repeat("中", 128),
+		UtmCampaign: strings.Repeat("文", 128),
+	})
+	assert.True(t, errorx.Is(err, errorx.CodeParamError))
 }
 
 // 测试根据MD5查询短链接函数
@@ -247,12 +640,12 @@ func TestShortenLogic_findShortUrlByMD5(t *testing.T) {
 	t.Run("found", func(t *testing.T) {
 		m := "testmd5"
 		shortURL := "abc123"
-		mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), m).Return(&model.ShortUrlMap{
+		mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), "", m).Return(&model.ShortUrlMap{
 			ShortUrl: shortURL,
 		}, nil)
 
 		l := &ShortenLogic{ctx: context.Background(), svcCtx: svcCtx}
-		result, err := l.findShortUrlByMD5(m)
+		result, err := l.findShortUrlByMD5("", m)
 
 		assert.Nil(t, err)
 		assert.Equal(t, shortURL, result)
@@ -260,10 +653,10 @@ func TestShortenLogic_findShortUrlByMD5(t *testing.T) {
 
 	t.Run("not_found", func(t *testing.T) {
 		m := "nonexistmd5"
-		mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), m).Return(nil, errorx.New(errorx.CodeNotFound, "data is not found"))
+		mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), "", m).Return(nil, errorx.New(errorx.CodeNotFound, "data is not found"))
 
 		l := &ShortenLogic{ctx: context.Background(), svcCtx: svcCtx}
-		result, err := l.findShortUrlByMD5(m)
+		result, err := l.findShortUrlByMD5("", m)
 
 		assert.Empty(t, result)
 		assert.Nil(t, err)
@@ -271,10 +664,10 @@ func TestShortenLogic_findShortUrlByMD5(t *testing.T) {
 
 	t.Run("repository_error", func(t *testing.T) {
 		m := "errormd5"
-		mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), m).Return(nil, errors.New("repository error"))
+		mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), "", m).Return(nil, errors.New("repository error"))
 
 		l := &ShortenLogic{ctx: context.Background(), svcCtx: svcCtx}
-		result, err := l.findShortUrlByMD5(m)
+		result, err := l.findShortUrlByMD5("", m)
 
 		assert.Empty(t, result)
 		assert.NotNil(t, err)
@@ -302,11 +695,11 @@ func TestShortenLogic_generateNonSensitiveShortUrl(t *testing.T) {
 
 	t.Run("success", func(t *testing.T) {
 		// 设置正确的模拟调用预期
-		mockSequence.EXPECT().NextID(gomock.Any()).Return(uint64(12345), nil)
+		mockSequence.EXPECT().NextID(gomock.Any(), "").Return(uint64(12345), nil)
 		mockSensitiveFilter.EXPECT().ContainsBadWord(gomock.Any()).Return(false)
 
 		l := &ShortenLogic{ctx: context.Background(), svcCtx: svcCtx}
-		result, err := l.generateNonSensitiveShortUrl()
+		result, err := l.generateNonSensitiveShortUrl("")
 
 		assert.Nil(t, err)
 		assert.NotEmpty(t, result)
@@ -314,19 +707,32 @@ func TestShortenLogic_generateNonSensitiveShortUrl(t *testing.T) {
 
 	t.Run("sensitive_word_detected", func(t *testing.T) {
 		// A sequence that would generate a sensitive short URL
-		mockSequence.EXPECT().NextID(gomock.Any()).Return(uint64(12345), nil)
+		mockSequence.EXPECT().NextID(gomock.Any(), "").Return(uint64(12345), nil)
 		mockSensitiveFilter.EXPECT().ContainsBadWord(gomock.Any()).Return(true)
 
 		// Try again with a different sequence
-		mockSequence.EXPECT().NextID(gomock.Any()).Return(uint64(67890), nil)
+		mockSequence.EXPECT().NextID(gomock.Any(), "").Return(uint64(67890), nil)
 		mockSensitiveFilter.EXPECT().ContainsBadWord(gomock.Any()).Return(false)
 
 		l := &ShortenLogic{ctx: context.Background(), svcCtx: svcCtx}
-		result, err := l.generateNonSensitiveShortUrl()
+		result, err := l.generateNonSensitiveShortUrl("")
 
 		assert.Nil(t, err)
 		assert.NotEmpty(t, result)
 	})
+
+	t.Run("exceeds_max_length", func(t *testing.T) {
+		// 11位的短码追加校验字符后超出长度
+		checked := *svcCtx
+		checked.Config.ShortCode.CheckCode = true
+		mockSequence.EXPECT().NextID(gomock.Any(), "").Return(uint64(math.MaxUint64), nil)
+
+		l := &ShortenLogic{ctx: context.Background(), svcCtx: &checked}
+		result, err := l.generateNonSensitiveShortUrl("")
+
+		assert.Empty(t, result)
+		assert.True(t, errorx.Is(err, errorx.CodeSystemError))
+	})
 }
 
 // 测试存储函数
@@ -350,26 +756,31 @@ func TestShortenLogic_storeInRepository(t *testing.T) {
 
 	t.Run("success", func(t *testing.T) {
 		m := "testmd5"
-		longURL := "http://example.com/page"
+		req := &types.ShortenRequest{LongUrl: "http://example.com/page", FallbackUrl: "http://example.com/fallback"}
 		shortURL := "abc123"
 
-		mockShortUrlMap.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(nil)
+		mockShortUrlMap.EXPECT().Insert(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, data *model.ShortUrlMap) error {
+				assert.Equal(t, req.LongUrl, data.LongUrl)
+				assert.Equal(t, req.FallbackUrl, data.FallbackUrl)
+				return nil
+			})
 
 		l := &ShortenLogic{ctx: context.Background(), svcCtx: svcCtx}
-		err := l.storeInRepository(m, longURL, shortURL)
+		err := l.storeInRepository("", m, req, shortURL, &destinationCheck{})
 
 		assert.Nil(t, err)
 	})
 
 	t.Run("repository_error", func(t *testing.T) {
 		m := "errormd5"
-		longURL := "http://example.com/error"
+		req := &types.ShortenRequest{LongUrl: "http://example.com/error"}
 		shortURL := "error"
 
 		mockShortUrlMap.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(errors.New("insert error"))
 
 		l := &ShortenLogic{ctx: context.Background(), svcCtx: svcCtx}
-		err := l.storeInRepository(m, longURL, shortURL)
+		err := l.storeInRepository("", m, req, shortURL, &destinationCheck{})
 
 		assert.NotNil(t, err)
 	})
@@ -391,7 +802,7 @@ func TestShortenLogic_storeShortUrlInFilter(t *testing.T) {
 		mockFilter.EXPECT().AddCtx(gomock.Any(), []byte(shortURL)).Return(nil)
 
 		l := &ShortenLogic{ctx: context.Background(), svcCtx: svcCtx}
-		err := l.storeShortUrlInFilter(shortURL)
+		err := l.storeShortUrlInFilter("", shortURL)
 
 		assert.Nil(t, err)
 	})
@@ -401,8 +812,74 @@ func TestShortenLogic_storeShortUrlInFilter(t *testing.T) {
 		mockFilter.EXPECT().AddCtx(gomock.Any(), []byte(shortURL)).Return(errors.New("filter error"))
 
 		l := &ShortenLogic{ctx: context.Background(), svcCtx: svcCtx}
-		err := l.storeShortUrlInFilter(shortURL)
+		err := l.storeShortUrlInFilter("", shortURL)
 
 		assert.NotNil(t, err)
 	})
 }
+
+// 测试品牌短域名使用独立命名空间
+func TestShortenLogic_Namespace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShortUrlMap := repositoryMock.NewMockShortUrlMap(ctrl)
+	mockSequence := repositoryMock.NewMockSequence(ctrl)
+	mockFilter := filterMock.NewMockFilter(ctrl)
+	mockSensitiveFilter := sensitiveMock.NewMockFilter(ctrl)
+	mockURLClient := urlToolMock.NewMockClient(ctrl)
+
+	svcCtx := &svc.ServiceContext{
+		Config: config.Config{
+			App: config.AppConf{
+				ShortUrlDomain: "example.com",
+				ShortUrlPath:   "/short/",
+				Namespaces:     []config.NamespaceConf{{Name: "brand", Domain: "brand.example"}},
+			},
+		},
+		ShortUrlMapRepository: mockShortUrlMap,
+		SequenceRepository:    mockSequence,
+		ShortCodeFilter:       mockFilter,
+		SensitiveFilter:       mockSensitiveFilter,
+	}
+
+	t.Run("unknown_domain", func(t *testing.T) {
+		l := NewShortenLogic(context.Background(), svcCtx, mockURLClient)
+		resp, err := l.Shorten(&types.ShortenRequest{LongUrl: "http://newtest.com/page", Domain: "unknown.example"})
+
+		assert.Nil(t, resp)
+		assert.True(t, errorx.Is(err, errorx.CodeParamError))
+	})
+
+	t.Run("brand_domain", func(t *testing.T) {
+		longURL := "http://newtest.com/page"
+		correctMd5, _ := md5.Sum([]byte(longURL))
+
+		mockURLClient.EXPECT().Check(longURL).Return(&urlTool.Result{Reachable: true, StatusCode: 200}, nil)
+		mockShortUrlMap.EXPECT().FindOneByMd5(gomock.Any(), "brand", correctMd5).Return(nil, errorx.New(errorx.CodeNotFound, "data is not found"))
+		mockSequence.EXPECT().NextID(gomock.Any(), "brand").Return(uint64(1), nil)
+		mockSensitiveFilter.EXPECT().ContainsBadWord("1").Return(false)
+		mockShortUrlMap.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, data *model.ShortUrlMap) error {
+			assert.Equal(t, "brand", data.Namespace)
+			return nil
+		})
+		mockFilter.EXPECT().AddCtx(gomock.Any(), []byte("brand:1")).Return(nil)
+
+		l := NewShortenLogic(context.Background(), svcCtx, mockURLClient)
+		resp, err := l.Shorten(&types.ShortenRequest{LongUrl: longURL, Domain: "brand.example"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "brand.example/short/1", resp.ShortCode)
+	})
+
+	t.Run("brand_short_url", func(t *testing.T) {
+		url := "http://brand.example/short/abc"
+		mockURLClient.EXPECT().Check(url).Return(&urlTool.Result{Reachable: true, StatusCode: 200}, nil)
+
+		l := NewShortenLogic(context.Background(), svcCtx, mockURLClient)
+		resp, err := l.Shorten(&types.ShortenRequest{LongUrl: url})
+
+		assert.Nil(t, resp)
+		assert.Contains(t, err.Error(), "URL is already shortUrl")
+	})
+}
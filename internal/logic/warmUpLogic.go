@@ -0,0 +1,69 @@
+package logic
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/mr"
+	"shortener/internal/repository"
+	"shortener/internal/svc"
+	"sync/atomic"
+	"time"
+)
+
+// warmUpWorkers 并发预热的协程数
+const warmUpWorkers = 16
+
+type WarmUpLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewWarmUpLogic(ctx context.Context, svcCtx *svc.ServiceContext) *WarmUpLogic {
+	return &WarmUpLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// WarmUp 在服务就绪前查询上一批实例持久化的热点短链，填充进程内缓存和Redis缓存。
+// 过滤器副本在创建时已同步加载快照。超过时间预算后放弃剩余短链，返回已预热的个数。
+func (l *WarmUpLogic) WarmUp() int {
+	if l.svcCtx.HotLinks == nil {
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(l.ctx, l.svcCtx.Config.WarmUp.Budget)
+	defer cancel()
+
+	start := time.Now()
+	links, err := l.svcCtx.HotLinks.Load(ctx)
+	if err != nil {
+		logx.Errorf("load hot links failed,err:%v", err)
+		return 0
+	}
+
+	var warmed atomic.Int64
+	mr.ForEach(func(source chan<- repository.Link) {
+		for _, link := range links {
+			select {
+			case source <- link:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}, func(link repository.Link) {
+		if _, err := l.svcCtx.ShortUrlMapRepository.FindOneByShortUrl(ctx, link.Namespace, link.ShortUrl); err != nil {
+			logx.WithContext(ctx).Debugw("warm up hot link failed",
+				logx.Field("namespace", link.Namespace),
+				logx.Field("shortUrl", link.ShortUrl),
+				logx.Field("err", err.Error()))
+			return
+		}
+		warmed.Add(1)
+	}, mr.WithContext(ctx), mr.WithWorkers(warmUpWorkers))
+
+	logx.Infof("warm up finished,warmed:%v,total:%v,elapsed:%v", warmed.Load(), len(links), time.Since(start))
+	return int(warmed.Load())
+}
@@ -0,0 +1,58 @@
+package logic
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"shortener/internal/config"
+	"shortener/internal/model"
+	"shortener/internal/repository"
+	repositoryMock "shortener/internal/repository/mock"
+	"shortener/internal/svc"
+	"shortener/internal/types/errorx"
+	"testing"
+	"time"
+)
+
+// fakeHotLinks 返回固定的热点短链
+type fakeHotLinks struct {
+	links []repository.Link
+}
+
+func (f *fakeHotLinks) Touch(string, string) {}
+
+func (f *fakeHotLinks) Save(context.Context) error { return nil }
+
+func (f *fakeHotLinks) Load(context.Context) ([]repository.Link, error) { return f.links, nil }
+
+func TestWarmUpLogic_WarmUp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockShortUrlMap := repositoryMock.NewMockShortUrlMap(ctrl)
+
+	cfg := config.Config{}
+	cfg.WarmUp.Budget = time.Second
+
+	svcCtx := &svc.ServiceContext{
+		Config:                cfg,
+		ShortUrlMapRepository: mockShortUrlMap,
+		HotLinks: &fakeHotLinks{links: []repository.Link{
+			{ShortUrl: "a"},
+			{Namespace: "brand", ShortUrl: "b"},
+			{ShortUrl: "deleted"},
+		}},
+	}
+
+	mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", "a").Return(&model.ShortUrlMap{}, nil)
+	mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "brand", "b").Return(&model.ShortUrlMap{}, nil)
+	mockShortUrlMap.EXPECT().FindOneByShortUrl(gomock.Any(), "", "deleted").
+		Return(nil, errorx.New(errorx.CodeNotFound, "the data does not exist"))
+
+	warmed := NewWarmUpLogic(context.Background(), svcCtx).WarmUp()
+	assert.Equal(t, 2, warmed)
+
+	// 未启用时不预热
+	svcCtx.HotLinks = nil
+	assert.Equal(t, 0, NewWarmUpLogic(context.Background(), svcCtx).WarmUp())
+}
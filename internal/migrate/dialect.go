@@ -0,0 +1,85 @@
+package migrate
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+	"shortener/internal/config"
+	"shortener/internal/types/errorx"
+)
+
+// migrationLockName 多个实例同时迁移时使用的锁
+const migrationLockName = "shortener:migrate"
+
+const selectApplied = `SELECT version, applied_at FROM schema_migrations`
+
+// dialect 不同数据库的迁移记录语句
+type dialect struct {
+	createTable string
+	count       string
+	insert      string
+	delete      string
+	// lock 获取迁移锁并返回释放函数，为nil时不加锁
+	lock func(ctx context.Context, session sqlx.Session) (func(), error)
+}
+
+var dialects = map[string]dialect{
+	config.StorageDriverMysql: {
+		createTable: "CREATE TABLE IF NOT EXISTS `schema_migrations` (" +
+			"`version` BIGINT UNSIGNED NOT NULL, " +
+			"`name` VARCHAR(255) NOT NULL DEFAULT '', " +
+			"`applied_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+			"PRIMARY KEY (`version`)) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COMMENT ='结构迁移记录'",
+		count:  "SELECT COUNT(*) FROM `schema_migrations` WHERE `version` = ?",
+		insert: "INSERT INTO `schema_migrations`(`version`, `name`) VALUES (?, ?)",
+		delete: "DELETE FROM `schema_migrations` WHERE `version` = ?",
+		lock:   mysqlLock,
+	},
+	config.StorageDriverPostgres: {
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    BIGINT       NOT NULL PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL DEFAULT '',
+    applied_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`,
+		count:  `SELECT COUNT(*) FROM schema_migrations WHERE version = $1`,
+		insert: `INSERT INTO schema_migrations(version, name) VALUES ($1, $2)`,
+		delete: `DELETE FROM schema_migrations WHERE version = $1`,
+		lock:   postgresLock,
+	},
+	// sqlite只有一个连接，不需要加锁
+	config.StorageDriverSqlite: {
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INTEGER   NOT NULL PRIMARY KEY,
+    name       TEXT      NOT NULL DEFAULT '',
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`,
+		count:  `SELECT COUNT(*) FROM schema_migrations WHERE version = ?`,
+		insert: `INSERT INTO schema_migrations(version, name) VALUES (?, ?)`,
+		delete: `DELETE FROM schema_migrations WHERE version = ?`,
+	},
+}
+
+// mysqlLock 使用会话级的命名锁，DDL隐式提交事务后锁仍然有效，需要显式释放
+func mysqlLock(ctx context.Context, session sqlx.Session) (func(), error) {
+	var locked int
+	if err := session.QueryRowCtx(ctx, &locked, `SELECT GET_LOCK(?, 60)`, migrationLockName); err != nil {
+		return nil, err
+	}
+	if locked != 1 {
+		return nil, errorx.New(errorx.CodeTimeout, "wait for migration lock timed out")
+	}
+
+	return func() {
+		if _, err := session.ExecCtx(context.Background(), `SELECT RELEASE_LOCK(?)`, migrationLockName); err != nil {
+			logx.Errorf("release migration lock failed,err:%v", err)
+		}
+	}, nil
+}
+
+// postgresLock 使用事务级的咨询锁，事务结束时自动释放
+func postgresLock(ctx context.Context, session sqlx.Session) (func(), error) {
+	if _, err := session.ExecCtx(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, migrationLockName); err != nil {
+		return nil, err
+	}
+	return func() {}, nil
+}
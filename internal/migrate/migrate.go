@@ -0,0 +1,361 @@
+package migrate
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+	"io/fs"
+	"path"
+	"shortener/internal/types/errorx"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrations 内嵌的迁移脚本，目录结构为 migrations/<驱动>/<目标库>/<版本>_<名称>.<up|down>.sql，
+// 同一驱动下的版本号全局唯一，目标库共用一个数据库时迁移记录不会冲突
+//
+//go:embed migrations
+var migrations embed.FS
+
+const (
+	// TargetSequence 序号表所在的数据库
+	TargetSequence = "sequence"
+	// TargetShortUrlMap 长短链映射表所在的数据库
+	TargetShortUrlMap = "shorturlmap"
+
+	// baselineVersion 基线版本，之前（含）的迁移创建表结构，与最初的建表语句一致。
+	// 回滚基线会删除全部数据，因此基线迁移没有回滚脚本，Down不会越过基线
+	baselineVersion = 2
+
+	migrationsDir = "migrations"
+	upSuffix      = ".up.sql"
+	downSuffix    = ".down.sql"
+)
+
+// Migrator 对一个数据库执行版本化的结构迁移，已执行的版本记录在 schema_migrations 表中
+type Migrator interface {
+	// Up 按版本顺序执行全部未执行的迁移，返回本次执行的迁移
+	Up(ctx context.Context) ([]Migration, error)
+	// Down 按版本倒序回滚最近执行的steps个迁移，返回本次回滚的迁移。需要越过基线版本时不回滚任何迁移并返回错误
+	Down(ctx context.Context, steps int) ([]Migration, error)
+	// Status 获取全部迁移及其执行状态
+	Status(ctx context.Context) ([]Status, error)
+}
+
+// Migration 一个版本的迁移脚本
+type Migration struct {
+	Version uint64
+	Name    string
+	Target  string
+	up      string
+	down    string
+}
+
+// Status 迁移的执行状态
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// NewMigrator 创建迁移器，只执行driver下属于targets的迁移
+func NewMigrator(conn sqlx.SqlConn, driver string, targets ...string) (Migrator, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		return nil, errorx.New(errorx.CodeParamError, "migration is not supported by the storage driver").
+			WithMeta("driver", driver)
+	}
+
+	all, err := load(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []Migration
+	for _, m := range all {
+		for _, target := range targets {
+			if m.Target == target {
+				selected = append(selected, m)
+				break
+			}
+		}
+	}
+
+	return &migrator{
+		conn:       conn,
+		dialect:    d,
+		migrations: selected,
+	}, nil
+}
+
+// load 读取驱动的全部迁移并按版本排序
+func load(driver string) ([]Migration, error) {
+	root := path.Join(migrationsDir, driver)
+	byVersion := make(map[uint64]*Migration)
+
+	err := fs.WalkDir(migrations, root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		base := path.Base(p)
+		var up bool
+		switch {
+		case strings.HasSuffix(base, upSuffix):
+			up = true
+			base = strings.TrimSuffix(base, upSuffix)
+		case strings.HasSuffix(base, downSuffix):
+			base = strings.TrimSuffix(base, downSuffix)
+		default:
+			return fmt.Errorf("unexpected migration file %s", p)
+		}
+
+		versionText, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return fmt.Errorf("migration file %s has no name", p)
+		}
+		version, err := strconv.ParseUint(versionText, 10, 64)
+		if err != nil {
+			return fmt.Errorf("migration file %s has an invalid version: %w", p, err)
+		}
+
+		content, err := fs.ReadFile(migrations, p)
+		if err != nil {
+			return err
+		}
+
+		target := path.Base(path.Dir(p))
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name, Target: target}
+			byVersion[version] = m
+		}
+		if m.Name != name || m.Target != target {
+			return fmt.Errorf("migration version %d is used by both %s/%s and %s/%s", version, m.Target, m.Name, target, name)
+		}
+
+		if up {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errorx.NewWithCause(errorx.CodeSystemError, "load migrations failed", err).
+			WithMeta("driver", driver)
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if len(m.up) == 0 || (len(m.down) == 0) != (m.Version <= baselineVersion) {
+			return nil, errorx.New(errorx.CodeSystemError, "baseline migrations have only an up script, others must have both up and down scripts").
+				WithMeta("driver", driver).WithMeta("version", m.Version)
+		}
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	return result, nil
+}
+
+type migrator struct {
+	conn       sqlx.SqlConn
+	dialect    dialect
+	migrations []Migration
+}
+
+// appliedRow schema_migrations 表中的一行
+type appliedRow struct {
+	Version   uint64    `db:"version"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+func (m *migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		ran, err := m.run(ctx, migration, true)
+		if err != nil {
+			return done, err
+		}
+		if ran {
+			done = append(done, migration)
+		}
+	}
+	return done, nil
+}
+
+func (m *migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(pending) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Version <= baselineVersion {
+			return nil, errorx.New(errorx.CodeParamError, "cannot roll back past the baseline migration").
+				WithMeta("version", migration.Version).
+				WithMeta("name", migration.Name)
+		}
+		pending = append(pending, migration)
+	}
+
+	var done []Migration
+	for _, migration := range pending {
+		ran, err := m.run(ctx, migration, false)
+		if err != nil {
+			return done, err
+		}
+		if ran {
+			done = append(done, migration)
+		}
+	}
+	return done, nil
+}
+
+func (m *migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		at, ok := applied[migration.Version]
+		result = append(result, Status{Migration: migration, Applied: ok, AppliedAt: at})
+	}
+	return result, nil
+}
+
+// applied 创建迁移记录表并读取已执行的版本
+func (m *migrator) applied(ctx context.Context) (map[uint64]time.Time, error) {
+	if _, err := m.conn.ExecCtx(ctx, m.dialect.createTable); err != nil {
+		return nil, errorx.NewWithCause(errorx.CodeDatabaseError, "create schema_migrations table failed", err)
+	}
+
+	var rows []*appliedRow
+	if err := m.conn.QueryRowsCtx(ctx, &rows, selectApplied); err != nil {
+		return nil, errorx.NewWithCause(errorx.CodeDatabaseError, "query applied migrations failed", err)
+	}
+
+	applied := make(map[uint64]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
+
+// run 在事务中执行一个迁移并更新迁移记录。多个实例同时迁移时通过数据库锁串行执行，
+// 获得锁后重新检查版本，已被其他实例执行过的迁移返回false。
+// mysql的DDL会隐式提交事务，脚本执行失败时需要根据错误手动修复。
+func (m *migrator) run(ctx context.Context, migration Migration, up bool) (bool, error) {
+	script, record, direction := migration.up, m.dialect.insert, "up"
+	if !up {
+		script, record, direction = migration.down, m.dialect.delete, "down"
+	}
+
+	var ran bool
+	err := m.conn.TransactCtx(ctx, func(ctx context.Context, session sqlx.Session) (err error) {
+		if m.dialect.lock != nil {
+			unlock, err := m.dialect.lock(ctx, session)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+		}
+
+		var count int
+		if err = session.QueryRowCtx(ctx, &count, m.dialect.count, migration.Version); err != nil {
+			return err
+		}
+		if (count > 0) == up {
+			return nil
+		}
+
+		for _, stmt := range splitStatements(script) {
+			if _, err = session.ExecCtx(ctx, stmt); err != nil {
+				return err
+			}
+		}
+
+		if up {
+			_, err = session.ExecCtx(ctx, record, migration.Version, migration.Name)
+		} else {
+			_, err = session.ExecCtx(ctx, record, migration.Version)
+		}
+		if err != nil {
+			return err
+		}
+
+		ran = true
+		return nil
+	})
+	if err != nil {
+		return false, errorx.NewWithCause(errorx.CodeDatabaseError, "run migration failed", err).
+			WithMeta("version", migration.Version).
+			WithMeta("name", migration.Name).
+			WithMeta("direction", direction)
+	}
+
+	if ran {
+		logx.WithContext(ctx).Infow("migration applied",
+			logx.Field("version", migration.Version),
+			logx.Field("name", migration.Name),
+			logx.Field("direction", direction))
+	}
+	return ran, nil
+}
+
+// splitStatements 按行尾的分号拆分脚本，postgres函数体 $$...$$ 中的分号不拆分，只有注释的语句被忽略
+func splitStatements(script string) []string {
+	var (
+		statements []string
+		current    []string
+		inBody     bool
+	)
+
+	flush := func() {
+		var lines []string
+		for _, line := range current {
+			if trimmed := strings.TrimSpace(line); len(trimmed) > 0 && !strings.HasPrefix(trimmed, "--") {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 0 {
+			statements = append(statements, strings.Join(lines, "\n"))
+		}
+		current = nil
+	}
+
+	for _, line := range strings.Split(script, "\n") {
+		current = append(current, line)
+		if strings.Count(line, "$$")%2 == 1 {
+			inBody = !inBody
+		}
+
+		if !inBody && strings.HasSuffix(strings.TrimSpace(line), ";") {
+			flush()
+		}
+	}
+	flush()
+
+	return statements
+}
@@ -0,0 +1,163 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+	_ "modernc.org/sqlite"
+	"shortener/internal/config"
+	"shortener/internal/types/errorx"
+)
+
+func newSqliteConn(t *testing.T) sqlx.SqlConn {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	db.SetMaxOpenConns(1)
+	return sqlx.NewSqlConnFromDB(db)
+}
+
+// 各驱动相同版本的迁移名称一致，基线之后的迁移都有回滚脚本。
+// MySQL的基线是最初的表结构，命名空间由紧接基线的0003、0004添加，其他驱动的基线已经包含命名空间，没有这两个版本
+func TestLoad(t *testing.T) {
+	names := make(map[uint64]string)
+	for _, driver := range []string{config.StorageDriverMysql, config.StorageDriverPostgres, config.StorageDriverSqlite} {
+		migrations, err := load(driver)
+		require.NoError(t, err, driver)
+		require.NotEmpty(t, migrations, driver)
+		assert.Equal(t, uint64(1), migrations[0].Version, driver)
+		assert.Equal(t, uint64(baselineVersion), migrations[1].Version, driver)
+
+		for _, m := range migrations {
+			assert.NotEmpty(t, splitStatements(m.up), m.Name)
+			if m.Version > baselineVersion {
+				assert.NotEmpty(t, splitStatements(m.down), m.Name)
+			}
+			assert.Contains(t, []string{TargetSequence, TargetShortUrlMap}, m.Target)
+
+			if name, ok := names[m.Version]; ok {
+				assert.Equal(t, name, m.Name, driver)
+			}
+			names[m.Version] = m.Name
+		}
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	conn := newSqliteConn(t)
+
+	m, err := NewMigrator(conn, config.StorageDriverSqlite, TargetSequence, TargetShortUrlMap)
+	require.NoError(t, err)
+	all, err := load(config.StorageDriverSqlite)
+	require.NoError(t, err)
+	latest := all[len(all)-1]
+
+	t.Run("执行全部迁移", func(t *testing.T) {
+		applied, err := m.Up(ctx)
+		require.NoError(t, err)
+		assert.Len(t, applied, len(all))
+
+		status, err := m.Status(ctx)
+		require.NoError(t, err)
+		for _, s := range status {
+			assert.True(t, s.Applied, s.Name)
+			assert.False(t, s.AppliedAt.IsZero(), s.Name)
+		}
+
+		_, err = conn.ExecCtx(ctx, `INSERT INTO short_url_map(short_url, md5) VALUES ('a', 'md5')`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("重复执行不会再次迁移", func(t *testing.T) {
+		applied, err := m.Up(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("回滚最近的迁移", func(t *testing.T) {
+		reverted, err := m.Down(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, reverted, 1)
+		assert.Equal(t, latest.Version, reverted[0].Version)
+
+		status, err := m.Status(ctx)
+		require.NoError(t, err)
+		assert.True(t, status[len(status)-2].Applied)
+		assert.False(t, status[len(status)-1].Applied)
+
+		applied, err := m.Up(ctx)
+		require.NoError(t, err)
+		assert.Len(t, applied, 1)
+	})
+
+	t.Run("不回滚基线", func(t *testing.T) {
+		reverted, err := m.Down(ctx, len(all))
+		assert.True(t, errorx.Is(err, errorx.CodeParamError))
+		assert.Empty(t, reverted)
+
+		status, err := m.Status(ctx)
+		require.NoError(t, err)
+		for _, s := range status {
+			assert.True(t, s.Applied, s.Name)
+		}
+	})
+
+	t.Run("回滚到基线", func(t *testing.T) {
+		reverted, err := m.Down(ctx, len(all)-baselineVersion)
+		require.NoError(t, err)
+		require.Len(t, reverted, len(all)-baselineVersion)
+		// 命名空间的版本只有MySQL需要，sqlite基线之后的版本不连续
+		assert.Equal(t, all[baselineVersion].Version, reverted[len(reverted)-1].Version)
+		assert.Greater(t, reverted[len(reverted)-1].Version, uint64(baselineVersion))
+
+		_, err = conn.ExecCtx(ctx, `SELECT 1 FROM short_url_map`)
+		assert.NoError(t, err)
+
+		applied, err := m.Up(ctx)
+		require.NoError(t, err)
+		assert.Len(t, applied, len(all)-baselineVersion)
+	})
+
+	t.Run("只执行指定目标库的迁移", func(t *testing.T) {
+		other := newSqliteConn(t)
+		seq, err := NewMigrator(other, config.StorageDriverSqlite, TargetSequence)
+		require.NoError(t, err)
+
+		applied, err := seq.Up(ctx)
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+		assert.Equal(t, TargetSequence, applied[0].Target)
+	})
+
+	t.Run("不支持的驱动", func(t *testing.T) {
+		_, err := NewMigrator(conn, config.StorageDriverMemory, TargetSequence)
+		assert.Error(t, err)
+	})
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- 注释;
+CREATE TABLE a (id INT);
+
+CREATE FUNCTION touch() RETURNS TRIGGER AS
+$$
+BEGIN
+    NEW.update_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- INSERT INTO a VALUES (1);
+INSERT INTO a VALUES (2);`
+
+	statements := splitStatements(script)
+	require.Len(t, statements, 3)
+	assert.Equal(t, "CREATE TABLE a (id INT);", statements[0])
+	assert.Contains(t, statements[1], "RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;")
+	assert.Equal(t, "INSERT INTO a VALUES (2);", statements[2])
+}
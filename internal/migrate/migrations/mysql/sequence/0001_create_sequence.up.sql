@@ -1,6 +1,4 @@
-CREATE DATABASE IF NOT EXISTS shortener;
-USE shortener;
-
+-- 基线版本：与最初的 ddl/sequence.sql 一致，已有的数据库不会有任何变化，之后的结构修改通过新的版本迁移
 CREATE TABLE IF NOT EXISTS `sequence`
 (
     `id`        BIGINT UNSIGNED NOT NULL,
@@ -12,4 +10,4 @@ CREATE TABLE IF NOT EXISTS `sequence`
 ) ENGINE = InnoDB
   DEFAULT CHARSET = utf8 COMMENT ='序号表';
 
-INSERT INTO sequence(id,stub) VALUES (0,'a');
\ No newline at end of file
+INSERT IGNORE INTO sequence(id, stub) VALUES (0, 'a');
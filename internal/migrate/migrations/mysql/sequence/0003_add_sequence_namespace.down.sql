@@ -0,0 +1,6 @@
+-- 回滚前需要删除默认命名空间以外的行以及 multistub 创建的其他stub行，否则无法恢复 id 主键和 stub 唯一索引
+ALTER TABLE `sequence`
+    DROP PRIMARY KEY,
+    DROP COLUMN `namespace`,
+    ADD PRIMARY KEY (`id`),
+    ADD UNIQUE KEY `uniq_stub` (`stub`);
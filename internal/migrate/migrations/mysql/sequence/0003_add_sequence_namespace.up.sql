@@ -0,0 +1,7 @@
+-- 为序号表增加命名空间，主键改为 (namespace, stub)，已有的行属于默认命名空间。
+-- multistub 后端不需要预先插入额外的stub行：缺少的行在首次使用时以同一命名空间已有行的最大计数值创建，不会与已发放的ID冲突
+ALTER TABLE `sequence`
+    ADD COLUMN `namespace` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '命名空间' AFTER `id`,
+    DROP INDEX `uniq_stub`,
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (`namespace`, `stub`);
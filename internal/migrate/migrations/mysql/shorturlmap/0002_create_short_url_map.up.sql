@@ -1,6 +1,4 @@
-CREATE DATABASE IF NOT EXISTS shortener;
-USE shortener;
-
+-- 基线版本：与最初的 ddl/shortUrlMap.sql 一致，已有的数据库不会有任何变化，之后的结构修改通过新的版本迁移
 CREATE TABLE IF NOT EXISTS `short_url_map`
 (
     `id`          BIGINT UNSIGNED  NOT NULL AUTO_INCREMENT COMMENT '主键ID',
@@ -21,4 +19,4 @@ CREATE TABLE IF NOT EXISTS `short_url_map`
     UNIQUE `uniq_md5` (`md5`),
     UNIQUE `uniq_short_url` (`short_url`)
 ) ENGINE = InnoDB
-  DEFAULT CHARSET = utf8mb4 COMMENT ='长短链映射表';
\ No newline at end of file
+  DEFAULT CHARSET = utf8mb4 COMMENT ='长短链映射表';
@@ -0,0 +1,7 @@
+-- 回滚前需要删除默认命名空间以外的数据，否则无法恢复 md5 和 short_url 的唯一索引
+ALTER TABLE `short_url_map`
+    DROP INDEX `uniq_namespace_md5`,
+    DROP INDEX `uniq_namespace_short_url`,
+    DROP COLUMN `namespace`,
+    ADD UNIQUE `uniq_md5` (`md5`),
+    ADD UNIQUE `uniq_short_url` (`short_url`);
@@ -0,0 +1,7 @@
+-- 为长短链映射表增加命名空间，唯一索引改为在命名空间内唯一，已有的行属于默认命名空间
+ALTER TABLE `short_url_map`
+    ADD COLUMN `namespace` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '命名空间' AFTER `click_count`,
+    DROP INDEX `uniq_md5`,
+    DROP INDEX `uniq_short_url`,
+    ADD UNIQUE `uniq_namespace_md5` (`namespace`, `md5`),
+    ADD UNIQUE `uniq_namespace_short_url` (`namespace`, `short_url`);
@@ -0,0 +1,2 @@
+ALTER TABLE `short_url_map`
+    ADD COLUMN `final_url` VARCHAR(2048) NOT NULL DEFAULT '' COMMENT '重定向后的最终地址';
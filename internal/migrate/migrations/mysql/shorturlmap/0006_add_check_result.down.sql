@@ -0,0 +1,4 @@
+ALTER TABLE `short_url_map`
+    DROP COLUMN `check_status`,
+    DROP COLUMN `check_code`,
+    DROP COLUMN `checked_at`;
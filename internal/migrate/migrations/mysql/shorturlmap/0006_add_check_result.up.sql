@@ -0,0 +1,4 @@
+ALTER TABLE `short_url_map`
+    ADD COLUMN `check_status` TINYINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '连通性检查结果：0未检查1可访问2不可访问3已拒绝',
+    ADD COLUMN `check_code`   INT              NOT NULL DEFAULT 0 COMMENT '最近一次检查的HTTP状态码',
+    ADD COLUMN `checked_at`   TIMESTAMP        NULL     DEFAULT NULL COMMENT '最近一次检查时间';
@@ -0,0 +1,5 @@
+ALTER TABLE `short_url_map`
+    DROP INDEX `idx_namespace_check_status`,
+    MODIFY COLUMN `check_status` TINYINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '连通性检查结果：0未检查1可访问2不可访问3已拒绝',
+    DROP COLUMN `fail_count`,
+    DROP COLUMN `fallback_url`;
@@ -0,0 +1,5 @@
+ALTER TABLE `short_url_map`
+    MODIFY COLUMN `check_status` TINYINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '连通性检查结果：0未检查1可访问2不可访问3已拒绝4已失效',
+    ADD COLUMN `fail_count`   INT UNSIGNED  NOT NULL DEFAULT 0 COMMENT '连续检查失败次数',
+    ADD COLUMN `fallback_url` VARCHAR(2048) NOT NULL DEFAULT '' COMMENT '目标失效时使用的备用地址',
+    ADD INDEX `idx_namespace_check_status` (`namespace`, `check_status`);
@@ -0,0 +1,3 @@
+ALTER TABLE `short_url_map`
+    DROP COLUMN `tag`,
+    DROP COLUMN `utm`;
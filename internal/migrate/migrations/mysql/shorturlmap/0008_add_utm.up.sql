@@ -0,0 +1,3 @@
+ALTER TABLE `short_url_map`
+    ADD COLUMN `tag` VARCHAR(64)  NOT NULL DEFAULT '' COMMENT '短链标签，跳转时追加标签配置的UTM参数',
+    ADD COLUMN `utm` VARCHAR(512) NOT NULL DEFAULT '' COMMENT '跳转时追加的UTM参数，查询字符串格式';
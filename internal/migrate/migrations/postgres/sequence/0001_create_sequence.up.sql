@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS sequence
+(
+    id        BIGINT      NOT NULL,
+    namespace VARCHAR(64) NOT NULL DEFAULT '',
+    stub      CHAR(1)     NOT NULL DEFAULT '0',
+    timestamp TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (namespace, stub)
+);
+
+COMMENT ON TABLE sequence IS '序号表';
+COMMENT ON COLUMN sequence.namespace IS '命名空间';
+COMMENT ON COLUMN sequence.stub IS '占位符';
+
+-- MySQL 的 ON UPDATE CURRENT_TIMESTAMP 由触发器实现
+CREATE OR REPLACE FUNCTION sequence_touch_timestamp() RETURNS TRIGGER AS
+$$
+BEGIN
+    NEW.timestamp = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS sequence_touch_timestamp ON sequence;
+CREATE TRIGGER sequence_touch_timestamp
+    BEFORE UPDATE
+    ON sequence
+    FOR EACH ROW
+EXECUTE FUNCTION sequence_touch_timestamp();
+
+INSERT INTO sequence(id, stub) VALUES (0, 'a') ON CONFLICT DO NOTHING;
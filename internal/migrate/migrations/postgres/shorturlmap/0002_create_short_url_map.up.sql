@@ -0,0 +1,52 @@
+CREATE TABLE IF NOT EXISTS short_url_map
+(
+    id          BIGSERIAL     NOT NULL,
+    create_at   TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    create_by   VARCHAR(64)   NOT NULL DEFAULT 'system',
+    update_at   TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_by   VARCHAR(64)   NOT NULL DEFAULT 'system',
+    is_del      SMALLINT      NOT NULL DEFAULT 0,
+    long_url    VARCHAR(2048) NOT NULL DEFAULT '',
+    md5         CHAR(32)      NOT NULL DEFAULT '',
+    short_url   VARCHAR(11)   NOT NULL DEFAULT '',
+    expire_at   TIMESTAMP     NULL     DEFAULT NULL,
+    click_count BIGINT        NOT NULL DEFAULT 0,
+    namespace   VARCHAR(64)   NOT NULL DEFAULT '',
+    PRIMARY KEY (id),
+    CONSTRAINT uniq_namespace_md5 UNIQUE (namespace, md5),
+    CONSTRAINT uniq_namespace_short_url UNIQUE (namespace, short_url)
+);
+
+CREATE INDEX IF NOT EXISTS idx_is_del ON short_url_map (is_del);
+CREATE INDEX IF NOT EXISTS idx_create_at ON short_url_map (create_at);
+CREATE INDEX IF NOT EXISTS idx_expire_at ON short_url_map (expire_at);
+
+COMMENT ON TABLE short_url_map IS '长短链映射表';
+COMMENT ON COLUMN short_url_map.id IS '主键ID';
+COMMENT ON COLUMN short_url_map.create_at IS '创建时间';
+COMMENT ON COLUMN short_url_map.create_by IS '创建者';
+COMMENT ON COLUMN short_url_map.update_at IS '更新时间';
+COMMENT ON COLUMN short_url_map.update_by IS '更新者';
+COMMENT ON COLUMN short_url_map.is_del IS '是否删除：0正常1删除';
+COMMENT ON COLUMN short_url_map.long_url IS '长链接';
+COMMENT ON COLUMN short_url_map.md5 IS '长链接MD5';
+COMMENT ON COLUMN short_url_map.short_url IS '短链接';
+COMMENT ON COLUMN short_url_map.expire_at IS '过期时间';
+COMMENT ON COLUMN short_url_map.click_count IS '点击次数';
+COMMENT ON COLUMN short_url_map.namespace IS '命名空间';
+
+-- MySQL 的 ON UPDATE CURRENT_TIMESTAMP 由触发器实现
+CREATE OR REPLACE FUNCTION short_url_map_touch_update_at() RETURNS TRIGGER AS
+$$
+BEGIN
+    NEW.update_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS short_url_map_touch_update_at ON short_url_map;
+CREATE TRIGGER short_url_map_touch_update_at
+    BEFORE UPDATE
+    ON short_url_map
+    FOR EACH ROW
+EXECUTE FUNCTION short_url_map_touch_update_at();
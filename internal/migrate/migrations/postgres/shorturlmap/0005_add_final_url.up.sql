@@ -0,0 +1,4 @@
+ALTER TABLE short_url_map
+    ADD COLUMN final_url VARCHAR(2048) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN short_url_map.final_url IS '重定向后的最终地址';
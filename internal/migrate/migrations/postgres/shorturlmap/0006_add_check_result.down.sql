@@ -0,0 +1,4 @@
+ALTER TABLE short_url_map
+    DROP COLUMN IF EXISTS check_status,
+    DROP COLUMN IF EXISTS check_code,
+    DROP COLUMN IF EXISTS checked_at;
@@ -0,0 +1,8 @@
+ALTER TABLE short_url_map
+    ADD COLUMN check_status SMALLINT  NOT NULL DEFAULT 0,
+    ADD COLUMN check_code   INTEGER   NOT NULL DEFAULT 0,
+    ADD COLUMN checked_at   TIMESTAMP NULL     DEFAULT NULL;
+
+COMMENT ON COLUMN short_url_map.check_status IS '连通性检查结果：0未检查1可访问2不可访问3已拒绝';
+COMMENT ON COLUMN short_url_map.check_code IS '最近一次检查的HTTP状态码';
+COMMENT ON COLUMN short_url_map.checked_at IS '最近一次检查时间';
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_namespace_check_status;
+
+ALTER TABLE short_url_map
+    DROP COLUMN IF EXISTS fail_count,
+    DROP COLUMN IF EXISTS fallback_url;
+
+COMMENT ON COLUMN short_url_map.check_status IS '连通性检查结果：0未检查1可访问2不可访问3已拒绝';
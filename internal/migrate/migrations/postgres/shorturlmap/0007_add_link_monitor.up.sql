@@ -0,0 +1,9 @@
+ALTER TABLE short_url_map
+    ADD COLUMN fail_count   INTEGER       NOT NULL DEFAULT 0,
+    ADD COLUMN fallback_url VARCHAR(2048) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_namespace_check_status ON short_url_map (namespace, check_status);
+
+COMMENT ON COLUMN short_url_map.check_status IS '连通性检查结果：0未检查1可访问2不可访问3已拒绝4已失效';
+COMMENT ON COLUMN short_url_map.fail_count IS '连续检查失败次数';
+COMMENT ON COLUMN short_url_map.fallback_url IS '目标失效时使用的备用地址';
@@ -0,0 +1,3 @@
+ALTER TABLE short_url_map
+    DROP COLUMN IF EXISTS tag,
+    DROP COLUMN IF EXISTS utm;
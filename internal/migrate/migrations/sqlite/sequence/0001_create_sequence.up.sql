@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS sequence
+(
+    id        INTEGER NOT NULL DEFAULT 0,
+    namespace TEXT    NOT NULL DEFAULT '',
+    stub      TEXT    NOT NULL DEFAULT 'a',
+    PRIMARY KEY (namespace, stub)
+);
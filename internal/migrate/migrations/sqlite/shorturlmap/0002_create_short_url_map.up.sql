@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS short_url_map
+(
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    create_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    create_by   TEXT      NOT NULL DEFAULT 'system',
+    update_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_by   TEXT      NOT NULL DEFAULT 'system',
+    is_del      INTEGER   NOT NULL DEFAULT 0,
+    long_url    TEXT      NOT NULL DEFAULT '',
+    md5         TEXT      NOT NULL DEFAULT '',
+    short_url   TEXT      NOT NULL DEFAULT '',
+    expire_at   TIMESTAMP NULL     DEFAULT NULL,
+    click_count INTEGER   NOT NULL DEFAULT 0,
+    namespace   TEXT      NOT NULL DEFAULT '',
+    UNIQUE (namespace, md5),
+    UNIQUE (namespace, short_url)
+);
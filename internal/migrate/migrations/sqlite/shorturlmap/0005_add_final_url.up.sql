@@ -0,0 +1,2 @@
+ALTER TABLE short_url_map
+    ADD COLUMN final_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE short_url_map DROP COLUMN check_status;
+ALTER TABLE short_url_map DROP COLUMN check_code;
+ALTER TABLE short_url_map DROP COLUMN checked_at;
@@ -0,0 +1,3 @@
+ALTER TABLE short_url_map ADD COLUMN check_status INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE short_url_map ADD COLUMN check_code INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE short_url_map ADD COLUMN checked_at TIMESTAMP NULL DEFAULT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_namespace_check_status;
+ALTER TABLE short_url_map DROP COLUMN fail_count;
+ALTER TABLE short_url_map DROP COLUMN fallback_url;
@@ -0,0 +1,3 @@
+ALTER TABLE short_url_map ADD COLUMN fail_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE short_url_map ADD COLUMN fallback_url TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_namespace_check_status ON short_url_map (namespace, check_status);
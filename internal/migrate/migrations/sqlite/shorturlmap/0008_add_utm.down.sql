@@ -0,0 +1,2 @@
+ALTER TABLE short_url_map DROP COLUMN tag;
+ALTER TABLE short_url_map DROP COLUMN utm;
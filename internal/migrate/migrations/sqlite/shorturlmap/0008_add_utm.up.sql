@@ -0,0 +1,2 @@
+ALTER TABLE short_url_map ADD COLUMN tag TEXT NOT NULL DEFAULT '';
+ALTER TABLE short_url_map ADD COLUMN utm TEXT NOT NULL DEFAULT '';
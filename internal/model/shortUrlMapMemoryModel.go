@@ -0,0 +1,248 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrDuplicateEntry 违反命名空间内md5或短链的唯一约束
+var ErrDuplicateEntry = errors.New("duplicate entry")
+
+var _ ShortUrlMapModel = (*memoryShortUrlMapModel)(nil)
+
+// NewShortUrlMapMemoryModel 进程内的模型，按与数据表相同的唯一约束保存数据，重启后数据丢失，仅用于单机模式和测试
+func NewShortUrlMapMemoryModel() ShortUrlMapModel {
+	return &memoryShortUrlMapModel{
+		rows:       make(map[uint64]*ShortUrlMap),
+		byMd5:      make(map[string]uint64),
+		byShortUrl: make(map[string]uint64),
+	}
+}
+
+type memoryShortUrlMapModel struct {
+	mu     sync.RWMutex
+	lastID uint64
+	rows   map[uint64]*ShortUrlMap
+	// 唯一索引：命名空间+md5、命名空间+短链 -> 主键
+	byMd5      map[string]uint64
+	byShortUrl map[string]uint64
+}
+
+// memoryResult 插入结果，LastInsertId 返回自增主键
+type memoryResult int64
+
+func (r memoryResult) LastInsertId() (int64, error) {
+	return int64(r), nil
+}
+
+func (r memoryResult) RowsAffected() (int64, error) {
+	return 1, nil
+}
+
+func (m *memoryShortUrlMapModel) Insert(_ context.Context, data *ShortUrlMap) (sql.Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.conflicts(data, 0) {
+		return nil, ErrDuplicateEntry
+	}
+
+	m.lastID++
+	now := time.Now()
+	row := *data
+	row.Id = m.lastID
+	row.CreateAt = now
+	row.UpdateAt = now
+	m.put(&row)
+
+	return memoryResult(row.Id), nil
+}
+
+func (m *memoryShortUrlMapModel) FindOne(_ context.Context, id uint64) (*ShortUrlMap, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.copyOf(id)
+}
+
+func (m *memoryShortUrlMapModel) FindOneByNamespaceMd5(_ context.Context, namespace string, md5 string) (*ShortUrlMap, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	id, ok := m.byMd5[uniqueKey(namespace, md5)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return m.copyOf(id)
+}
+
+func (m *memoryShortUrlMapModel) FindOneByNamespaceShortUrl(_ context.Context, namespace string, shortUrl string) (*ShortUrlMap, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	id, ok := m.byShortUrl[uniqueKey(namespace, shortUrl)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return m.copyOf(id)
+}
+
+func (m *memoryShortUrlMapModel) Update(_ context.Context, data *ShortUrlMap) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.rows[data.Id]
+	if !ok {
+		return nil
+	}
+	if m.conflicts(data, data.Id) {
+		return ErrDuplicateEntry
+	}
+
+	row := *data
+	row.CreateAt = old.CreateAt
+	row.UpdateAt = time.Now()
+	m.remove(old)
+	m.put(&row)
+	return nil
+}
+
+func (m *memoryShortUrlMapModel) UpdateCheckResult(_ context.Context, data *ShortUrlMap) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row, ok := m.rows[data.Id]
+	if !ok {
+		return nil
+	}
+
+	updated := *row
+	updated.FinalUrl = data.FinalUrl
+	updated.CheckStatus = data.CheckStatus
+	updated.CheckCode = data.CheckCode
+	updated.CheckedAt = data.CheckedAt
+	updated.FailCount = data.FailCount
+	updated.UpdateAt = time.Now()
+	m.rows[data.Id] = &updated
+	return nil
+}
+
+func (m *memoryShortUrlMapModel) Delete(_ context.Context, id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if row, ok := m.rows[id]; ok {
+		m.remove(row)
+	}
+	return nil
+}
+
+func (m *memoryShortUrlMapModel) Remove(_ context.Context, id uint64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row, ok := m.rows[id]
+	if !ok {
+		return false, nil
+	}
+	m.remove(row)
+	return true, nil
+}
+
+func (m *memoryShortUrlMapModel) FindShortUrlsAfter(_ context.Context, id uint64, limit int) ([]*ShortUrlMap, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := m.idsAfter(id, limit, func(*ShortUrlMap) bool { return true })
+	resp := make([]*ShortUrlMap, 0, len(ids))
+	for _, rowID := range ids {
+		row := m.rows[rowID]
+		resp = append(resp, &ShortUrlMap{Id: row.Id, Namespace: row.Namespace, ShortUrl: row.ShortUrl})
+	}
+	return resp, nil
+}
+
+func (m *memoryShortUrlMapModel) FindLinksAfter(_ context.Context, id uint64, limit int) ([]*ShortUrlMap, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.copiesOf(m.idsAfter(id, limit, func(*ShortUrlMap) bool { return true })), nil
+}
+
+func (m *memoryShortUrlMapModel) FindByCheckStatusAfter(_ context.Context, namespace string, status uint64, id uint64, limit int) ([]*ShortUrlMap, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := m.idsAfter(id, limit, func(row *ShortUrlMap) bool {
+		return row.Namespace == namespace && row.CheckStatus == status
+	})
+	return m.copiesOf(ids), nil
+}
+
+// idsAfter 按主键顺序返回id之后满足条件的未删除行的主键，最多limit个，调用方需持有锁
+func (m *memoryShortUrlMapModel) idsAfter(id uint64, limit int, match func(row *ShortUrlMap) bool) []uint64 {
+	ids := make([]uint64, 0, len(m.rows))
+	for rowID, row := range m.rows {
+		if rowID > id && row.IsDel == 0 && match(row) {
+			ids = append(ids, rowID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids
+}
+
+// copiesOf 返回多行的副本，调用方需持有锁
+func (m *memoryShortUrlMapModel) copiesOf(ids []uint64) []*ShortUrlMap {
+	resp := make([]*ShortUrlMap, 0, len(ids))
+	for _, rowID := range ids {
+		data := *m.rows[rowID]
+		resp = append(resp, &data)
+	}
+	return resp
+}
+
+// conflicts 判断数据是否与主键不为id的其他行冲突，调用方需持有锁
+func (m *memoryShortUrlMapModel) conflicts(data *ShortUrlMap, id uint64) bool {
+	if other, ok := m.byMd5[uniqueKey(data.Namespace, data.Md5)]; ok && other != id {
+		return true
+	}
+	if other, ok := m.byShortUrl[uniqueKey(data.Namespace, data.ShortUrl)]; ok && other != id {
+		return true
+	}
+	return false
+}
+
+func (m *memoryShortUrlMapModel) put(row *ShortUrlMap) {
+	m.rows[row.Id] = row
+	m.byMd5[uniqueKey(row.Namespace, row.Md5)] = row.Id
+	m.byShortUrl[uniqueKey(row.Namespace, row.ShortUrl)] = row.Id
+}
+
+func (m *memoryShortUrlMapModel) remove(row *ShortUrlMap) {
+	delete(m.rows, row.Id)
+	delete(m.byMd5, uniqueKey(row.Namespace, row.Md5))
+	delete(m.byShortUrl, uniqueKey(row.Namespace, row.ShortUrl))
+}
+
+// copyOf 返回行的副本，避免调用方修改内部数据，调用方需持有锁
+func (m *memoryShortUrlMapModel) copyOf(id uint64) (*ShortUrlMap, error) {
+	row, ok := m.rows[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	data := *row
+	return &data, nil
+}
+
+func uniqueKey(namespace, value string) string {
+	return namespace + "\x00" + value
+}
@@ -1,17 +1,41 @@
 package model
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
 	"github.com/zeromicro/go-zero/core/stores/cache"
 	"github.com/zeromicro/go-zero/core/stores/sqlx"
 )
 
 var _ ShortUrlMapModel = (*customShortUrlMapModel)(nil)
 
+// 连通性检查结果 ShortUrlMap.CheckStatus
+const (
+	CheckStatusUnchecked   uint64 = iota // 未检查
+	CheckStatusReachable                 // 可访问
+	CheckStatusUnreachable               // 不可访问
+	CheckStatusRejected                  // 违反安全策略被拒绝，如指向内网地址或跳回本服务短链
+	CheckStatusBroken                    // 连续检查失败次数达到阈值，判定为失效
+)
+
 type (
 	// ShortUrlMapModel is an interface to be customized, add more methods here,
 	// and implement the added methods in customShortUrlMapModel.
 	ShortUrlMapModel interface {
 		shortUrlMapModel
+		// FindShortUrlsAfter 按主键顺序查询id之后未删除的短链，用于分批遍历全表
+		FindShortUrlsAfter(ctx context.Context, id uint64, limit int) ([]*ShortUrlMap, error)
+		// FindLinksAfter 按主键顺序查询id之后未删除的完整短链，用于巡检目标地址
+		FindLinksAfter(ctx context.Context, id uint64, limit int) ([]*ShortUrlMap, error)
+		// FindByCheckStatusAfter 按主键顺序查询命名空间内id之后检查结果为status的未删除短链
+		FindByCheckStatusAfter(ctx context.Context, namespace string, status uint64, id uint64, limit int) ([]*ShortUrlMap, error)
+		// UpdateCheckResult 只更新主键为data.Id的连通性检查结果、连续失败次数和最终地址
+		UpdateCheckResult(ctx context.Context, data *ShortUrlMap) error
+		// Remove 删除主键为id的行并返回是否删除了行，多个实例并发删除同一行时只有一个返回true
+		Remove(ctx context.Context, id uint64) (bool, error)
 	}
 
 	customShortUrlMapModel struct {
@@ -25,3 +49,81 @@ func NewShortUrlMapModel(conn sqlx.SqlConn, c cache.CacheConf, opts ...cache.Opt
 		defaultShortUrlMapModel: newShortUrlMapModel(conn, c, opts...),
 	}
 }
+
+func (m *customShortUrlMapModel) FindShortUrlsAfter(ctx context.Context, id uint64, limit int) ([]*ShortUrlMap, error) {
+	var resp []*ShortUrlMap
+	query := fmt.Sprintf("select `id`, `namespace`, `short_url` from %s where `id` > ? and `is_del` = 0 order by `id` limit ?", m.table)
+	err := m.QueryRowsPartialNoCacheCtx(ctx, &resp, query, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (m *customShortUrlMapModel) FindLinksAfter(ctx context.Context, id uint64, limit int) ([]*ShortUrlMap, error) {
+	var resp []*ShortUrlMap
+	query := fmt.Sprintf("select %s from %s where `id` > ? and `is_del` = 0 order by `id` limit ?", shortUrlMapRows, m.table)
+	err := m.QueryRowsNoCacheCtx(ctx, &resp, query, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (m *customShortUrlMapModel) FindByCheckStatusAfter(ctx context.Context, namespace string, status uint64, id uint64, limit int) ([]*ShortUrlMap, error) {
+	var resp []*ShortUrlMap
+	query := fmt.Sprintf("select %s from %s where `namespace` = ? and `check_status` = ? and `id` > ? and `is_del` = 0 order by `id` limit ?", shortUrlMapRows, m.table)
+	err := m.QueryRowsNoCacheCtx(ctx, &resp, query, namespace, status, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (m *customShortUrlMapModel) UpdateCheckResult(ctx context.Context, data *ShortUrlMap) error {
+	old, err := m.FindOne(ctx, data.Id)
+	if err != nil {
+		return err
+	}
+
+	shortUrlMapIdKey := fmt.Sprintf("%s%v", cacheShortUrlMapIdPrefix, old.Id)
+	shortUrlMapNamespaceMd5Key := fmt.Sprintf("%s%v:%v", cacheShortUrlMapNamespaceMd5Prefix, old.Namespace, old.Md5)
+	shortUrlMapNamespaceShortUrlKey := fmt.Sprintf("%s%v:%v", cacheShortUrlMapNamespaceShortUrlPrefix, old.Namespace, old.ShortUrl)
+	_, err = m.ExecCtx(ctx, func(ctx context.Context, conn sqlx.SqlConn) (sql.Result, error) {
+		query := fmt.Sprintf("update %s set `final_url` = ?, `check_status` = ?, `check_code` = ?, `checked_at` = ?, `fail_count` = ? where `id` = ?", m.table)
+		return conn.ExecCtx(ctx, query, data.FinalUrl, data.CheckStatus, data.CheckCode, data.CheckedAt, data.FailCount, data.Id)
+	}, shortUrlMapIdKey, shortUrlMapNamespaceMd5Key, shortUrlMapNamespaceShortUrlKey)
+	return err
+}
+
+func (m *customShortUrlMapModel) Remove(ctx context.Context, id uint64) (bool, error) {
+	data, err := m.FindOne(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	shortUrlMapIdKey := fmt.Sprintf("%s%v", cacheShortUrlMapIdPrefix, id)
+	shortUrlMapNamespaceMd5Key := fmt.Sprintf("%s%v:%v", cacheShortUrlMapNamespaceMd5Prefix, data.Namespace, data.Md5)
+	shortUrlMapNamespaceShortUrlKey := fmt.Sprintf("%s%v:%v", cacheShortUrlMapNamespaceShortUrlPrefix, data.Namespace, data.ShortUrl)
+	result, err := m.ExecCtx(ctx, func(ctx context.Context, conn sqlx.SqlConn) (sql.Result, error) {
+		query := fmt.Sprintf("delete from %s where `id` = ?", m.table)
+		return conn.ExecCtx(ctx, query, id)
+	}, shortUrlMapIdKey, shortUrlMapNamespaceMd5Key, shortUrlMapNamespaceShortUrlKey)
+	return removed(result, err)
+}
+
+// removed 根据删除语句的影响行数判断是否删除了行
+func removed(result sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -24,17 +24,17 @@ var (
 	shortUrlMapRowsExpectAutoSet   = strings.Join(stringx.Remove(shortUrlMapFieldNames, "`id`", "`create_at`", "`create_time`", "`created_at`", "`update_at`", "`update_time`", "`updated_at`"), ",")
 	shortUrlMapRowsWithPlaceHolder = strings.Join(stringx.Remove(shortUrlMapFieldNames, "`id`", "`create_at`", "`create_time`", "`created_at`", "`update_at`", "`update_time`", "`updated_at`"), "=?,") + "=?"
 
-	cacheShortUrlMapIdPrefix       = "cache:shortUrlMap:id:"
-	cacheShortUrlMapMd5Prefix      = "cache:shortUrlMap:md5:"
-	cacheShortUrlMapShortUrlPrefix = "cache:shortUrlMap:shortUrl:"
+	cacheShortUrlMapIdPrefix                = "cache:shortUrlMap:id:"
+	cacheShortUrlMapNamespaceMd5Prefix      = "cache:shortUrlMap:namespace:md5:"
+	cacheShortUrlMapNamespaceShortUrlPrefix = "cache:shortUrlMap:namespace:shortUrl:"
 )
 
 type (
 	shortUrlMapModel interface {
 		Insert(ctx context.Context, data *ShortUrlMap) (sql.Result, error)
 		FindOne(ctx context.Context, id uint64) (*ShortUrlMap, error)
-		FindOneByMd5(ctx context.Context, md5 string) (*ShortUrlMap, error)
-		FindOneByShortUrl(ctx context.Context, shortUrl string) (*ShortUrlMap, error)
+		FindOneByNamespaceMd5(ctx context.Context, namespace string, md5 string) (*ShortUrlMap, error)
+		FindOneByNamespaceShortUrl(ctx context.Context, namespace string, shortUrl string) (*ShortUrlMap, error)
 		Update(ctx context.Context, data *ShortUrlMap) error
 		Delete(ctx context.Context, id uint64) error
 	}
@@ -45,17 +45,26 @@ type (
 	}
 
 	ShortUrlMap struct {
-		Id         uint64       `db:"id"`          // 主键ID
-		CreateAt   time.Time    `db:"create_at"`   // 创建时间
-		CreateBy   string       `db:"create_by"`   // 创建者
-		UpdateAt   time.Time    `db:"update_at"`   // 更新时间
-		UpdateBy   string       `db:"update_by"`   // 更新者
-		IsDel      uint64       `db:"is_del"`      // 是否删除：0正常1删除
-		LongUrl    string       `db:"long_url"`    // 长链接
-		Md5        string       `db:"md5"`         // 长链接MD5
-		ShortUrl   string       `db:"short_url"`   // 短链接
-		ExpireAt   sql.NullTime `db:"expire_at"`   // 过期时间
-		ClickCount uint64       `db:"click_count"` // 点击次数
+		Id          uint64       `db:"id"`           // 主键ID
+		CreateAt    time.Time    `db:"create_at"`    // 创建时间
+		CreateBy    string       `db:"create_by"`    // 创建者
+		UpdateAt    time.Time    `db:"update_at"`    // 更新时间
+		UpdateBy    string       `db:"update_by"`    // 更新者
+		IsDel       uint64       `db:"is_del"`       // 是否删除：0正常1删除
+		LongUrl     string       `db:"long_url"`     // 长链接
+		Md5         string       `db:"md5"`          // 长链接MD5
+		ShortUrl    string       `db:"short_url"`    // 短链接
+		ExpireAt    sql.NullTime `db:"expire_at"`    // 过期时间
+		ClickCount  uint64       `db:"click_count"`  // 点击次数
+		Namespace   string       `db:"namespace"`    // 命名空间
+		FinalUrl    string       `db:"final_url"`    // 重定向后的最终地址
+		CheckStatus uint64       `db:"check_status"` // 连通性检查结果：0未检查1可访问2不可访问3已拒绝4已失效
+		CheckCode   int64        `db:"check_code"`   // 最近一次检查的HTTP状态码
+		CheckedAt   sql.NullTime `db:"checked_at"`   // 最近一次检查时间
+		FailCount   uint64       `db:"fail_count"`   // 连续检查失败次数
+		FallbackUrl string       `db:"fallback_url"` // 目标失效时使用的备用地址
+		Tag         string       `db:"tag"`          // 短链标签，跳转时追加标签配置的UTM参数
+		Utm         string       `db:"utm"`          // 跳转时追加的UTM参数，查询字符串格式
 	}
 )
 
@@ -73,12 +82,12 @@ func (m *defaultShortUrlMapModel) Delete(ctx context.Context, id uint64) error {
 	}
 
 	shortUrlMapIdKey := fmt.Sprintf("%s%v", cacheShortUrlMapIdPrefix, id)
-	shortUrlMapMd5Key := fmt.Sprintf("%s%v", cacheShortUrlMapMd5Prefix, data.Md5)
-	shortUrlMapShortUrlKey := fmt.Sprintf("%s%v", cacheShortUrlMapShortUrlPrefix, data.ShortUrl)
+	shortUrlMapNamespaceMd5Key := fmt.Sprintf("%s%v:%v", cacheShortUrlMapNamespaceMd5Prefix, data.Namespace, data.Md5)
+	shortUrlMapNamespaceShortUrlKey := fmt.Sprintf("%s%v:%v", cacheShortUrlMapNamespaceShortUrlPrefix, data.Namespace, data.ShortUrl)
 	_, err = m.ExecCtx(ctx, func(ctx context.Context, conn sqlx.SqlConn) (result sql.Result, err error) {
 		query := fmt.Sprintf("delete from %s where `id` = ?", m.table)
 		return conn.ExecCtx(ctx, query, id)
-	}, shortUrlMapIdKey, shortUrlMapMd5Key, shortUrlMapShortUrlKey)
+	}, shortUrlMapIdKey, shortUrlMapNamespaceMd5Key, shortUrlMapNamespaceShortUrlKey)
 	return err
 }
 
@@ -99,12 +108,12 @@ func (m *defaultShortUrlMapModel) FindOne(ctx context.Context, id uint64) (*Shor
 	}
 }
 
-func (m *defaultShortUrlMapModel) FindOneByMd5(ctx context.Context, md5 string) (*ShortUrlMap, error) {
-	shortUrlMapMd5Key := fmt.Sprintf("%s%v", cacheShortUrlMapMd5Prefix, md5)
+func (m *defaultShortUrlMapModel) FindOneByNamespaceMd5(ctx context.Context, namespace string, md5 string) (*ShortUrlMap, error) {
+	shortUrlMapNamespaceMd5Key := fmt.Sprintf("%s%v:%v", cacheShortUrlMapNamespaceMd5Prefix, namespace, md5)
 	var resp ShortUrlMap
-	err := m.QueryRowIndexCtx(ctx, &resp, shortUrlMapMd5Key, m.formatPrimary, func(ctx context.Context, conn sqlx.SqlConn, v any) (i any, e error) {
-		query := fmt.Sprintf("select %s from %s where `md5` = ? limit 1", shortUrlMapRows, m.table)
-		if err := conn.QueryRowCtx(ctx, &resp, query, md5); err != nil {
+	err := m.QueryRowIndexCtx(ctx, &resp, shortUrlMapNamespaceMd5Key, m.formatPrimary, func(ctx context.Context, conn sqlx.SqlConn, v any) (i any, e error) {
+		query := fmt.Sprintf("select %s from %s where `namespace` = ? and `md5` = ? limit 1", shortUrlMapRows, m.table)
+		if err := conn.QueryRowCtx(ctx, &resp, query, namespace, md5); err != nil {
 			return nil, err
 		}
 		return resp.Id, nil
@@ -119,12 +128,12 @@ func (m *defaultShortUrlMapModel) FindOneByMd5(ctx context.Context, md5 string)
 	}
 }
 
-func (m *defaultShortUrlMapModel) FindOneByShortUrl(ctx context.Context, shortUrl string) (*ShortUrlMap, error) {
-	shortUrlMapShortUrlKey := fmt.Sprintf("%s%v", cacheShortUrlMapShortUrlPrefix, shortUrl)
+func (m *defaultShortUrlMapModel) FindOneByNamespaceShortUrl(ctx context.Context, namespace string, shortUrl string) (*ShortUrlMap, error) {
+	shortUrlMapNamespaceShortUrlKey := fmt.Sprintf("%s%v:%v", cacheShortUrlMapNamespaceShortUrlPrefix, namespace, shortUrl)
 	var resp ShortUrlMap
-	err := m.QueryRowIndexCtx(ctx, &resp, shortUrlMapShortUrlKey, m.formatPrimary, func(ctx context.Context, conn sqlx.SqlConn, v any) (i any, e error) {
-		query := fmt.Sprintf("select %s from %s where `short_url` = ? limit 1", shortUrlMapRows, m.table)
-		if err := conn.QueryRowCtx(ctx, &resp, query, shortUrl); err != nil {
+	err := m.QueryRowIndexCtx(ctx, &resp, shortUrlMapNamespaceShortUrlKey, m.formatPrimary, func(ctx context.Context, conn sqlx.SqlConn, v any) (i any, e error) {
+		query := fmt.Sprintf("select %s from %s where `namespace` = ? and `short_url` = ? limit 1", shortUrlMapRows, m.table)
+		if err := conn.QueryRowCtx(ctx, &resp, query, namespace, shortUrl); err != nil {
 			return nil, err
 		}
 		return resp.Id, nil
@@ -141,12 +150,12 @@ func (m *defaultShortUrlMapModel) FindOneByShortUrl(ctx context.Context, shortUr
 
 func (m *defaultShortUrlMapModel) Insert(ctx context.Context, data *ShortUrlMap) (sql.Result, error) {
 	shortUrlMapIdKey := fmt.Sprintf("%s%v", cacheShortUrlMapIdPrefix, data.Id)
-	shortUrlMapMd5Key := fmt.Sprintf("%s%v", cacheShortUrlMapMd5Prefix, data.Md5)
-	shortUrlMapShortUrlKey := fmt.Sprintf("%s%v", cacheShortUrlMapShortUrlPrefix, data.ShortUrl)
+	shortUrlMapNamespaceMd5Key := fmt.Sprintf("%s%v:%v", cacheShortUrlMapNamespaceMd5Prefix, data.Namespace, data.Md5)
+	shortUrlMapNamespaceShortUrlKey := fmt.Sprintf("%s%v:%v", cacheShortUrlMapNamespaceShortUrlPrefix, data.Namespace, data.ShortUrl)
 	ret, err := m.ExecCtx(ctx, func(ctx context.Context, conn sqlx.SqlConn) (result sql.Result, err error) {
-		query := fmt.Sprintf("insert into %s (%s) values (?, ?, ?, ?, ?, ?, ?, ?)", m.table, shortUrlMapRowsExpectAutoSet)
-		return conn.ExecCtx(ctx, query, data.CreateBy, data.UpdateBy, data.IsDel, data.LongUrl, data.Md5, data.ShortUrl, data.ExpireAt, data.ClickCount)
-	}, shortUrlMapIdKey, shortUrlMapMd5Key, shortUrlMapShortUrlKey)
+		query := fmt.Sprintf("insert into %s (%s) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", m.table, shortUrlMapRowsExpectAutoSet)
+		return conn.ExecCtx(ctx, query, data.CreateBy, data.UpdateBy, data.IsDel, data.LongUrl, data.Md5, data.ShortUrl, data.ExpireAt, data.ClickCount, data.Namespace, data.FinalUrl, data.CheckStatus, data.CheckCode, data.CheckedAt, data.FailCount, data.FallbackUrl, data.Tag, data.Utm)
+	}, shortUrlMapIdKey, shortUrlMapNamespaceMd5Key, shortUrlMapNamespaceShortUrlKey)
 	return ret, err
 }
 
@@ -157,12 +166,12 @@ func (m *defaultShortUrlMapModel) Update(ctx context.Context, newData *ShortUrlM
 	}
 
 	shortUrlMapIdKey := fmt.Sprintf("%s%v", cacheShortUrlMapIdPrefix, data.Id)
-	shortUrlMapMd5Key := fmt.Sprintf("%s%v", cacheShortUrlMapMd5Prefix, data.Md5)
-	shortUrlMapShortUrlKey := fmt.Sprintf("%s%v", cacheShortUrlMapShortUrlPrefix, data.ShortUrl)
+	shortUrlMapNamespaceMd5Key := fmt.Sprintf("%s%v:%v", cacheShortUrlMapNamespaceMd5Prefix, data.Namespace, data.Md5)
+	shortUrlMapNamespaceShortUrlKey := fmt.Sprintf("%s%v:%v", cacheShortUrlMapNamespaceShortUrlPrefix, data.Namespace, data.ShortUrl)
 	_, err = m.ExecCtx(ctx, func(ctx context.Context, conn sqlx.SqlConn) (result sql.Result, err error) {
 		query := fmt.Sprintf("update %s set %s where `id` = ?", m.table, shortUrlMapRowsWithPlaceHolder)
-		return conn.ExecCtx(ctx, query, newData.CreateBy, newData.UpdateBy, newData.IsDel, newData.LongUrl, newData.Md5, newData.ShortUrl, newData.ExpireAt, newData.ClickCount, newData.Id)
-	}, shortUrlMapIdKey, shortUrlMapMd5Key, shortUrlMapShortUrlKey)
+		return conn.ExecCtx(ctx, query, newData.CreateBy, newData.UpdateBy, newData.IsDel, newData.LongUrl, newData.Md5, newData.ShortUrl, newData.ExpireAt, newData.ClickCount, newData.Namespace, newData.FinalUrl, newData.CheckStatus, newData.CheckCode, newData.CheckedAt, newData.FailCount, newData.FallbackUrl, newData.Tag, newData.Utm, newData.Id)
+	}, shortUrlMapIdKey, shortUrlMapNamespaceMd5Key, shortUrlMapNamespaceShortUrlKey)
 	return err
 }
 
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/zeromicro/go-zero/core/stores/builder"
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+	"github.com/zeromicro/go-zero/core/stringx"
+	"strings"
+)
+
+var (
+	shortUrlMapPostgresFieldNames          = builder.RawFieldNames(&ShortUrlMap{}, true)
+	shortUrlMapPostgresRows                = strings.Join(shortUrlMapPostgresFieldNames, ",")
+	shortUrlMapPostgresRowsExpectAutoSet   = strings.Join(stringx.Remove(shortUrlMapPostgresFieldNames, "id", "create_at", "update_at"), ",")
+	shortUrlMapPostgresRowsWithPlaceHolder = builder.PostgreSqlJoin(stringx.Remove(shortUrlMapPostgresFieldNames, "id", "create_at", "update_at"))
+)
+
+var _ ShortUrlMapModel = (*postgresShortUrlMapModel)(nil)
+
+// NewShortUrlMapPostgresModel postgres的模型，表结构见internal/migrate/migrations/postgres，不使用Redis缓存
+func NewShortUrlMapPostgresModel(conn sqlx.SqlConn) ShortUrlMapModel {
+	return &postgresShortUrlMapModel{
+		conn:  conn,
+		table: `"short_url_map"`,
+	}
+}
+
+type postgresShortUrlMapModel struct {
+	conn  sqlx.SqlConn
+	table string
+}
+
+func (m *postgresShortUrlMapModel) Insert(ctx context.Context, data *ShortUrlMap) (sql.Result, error) {
+	query := fmt.Sprintf("insert into %s (%s) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)", m.table, shortUrlMapPostgresRowsExpectAutoSet)
+	return m.conn.ExecCtx(ctx, query, data.CreateBy, data.UpdateBy, data.IsDel, data.LongUrl, data.Md5, data.ShortUrl, data.ExpireAt, data.ClickCount, data.Namespace, data.FinalUrl, data.CheckStatus, data.CheckCode, data.CheckedAt, data.FailCount, data.FallbackUrl, data.Tag, data.Utm)
+}
+
+func (m *postgresShortUrlMapModel) FindOne(ctx context.Context, id uint64) (*ShortUrlMap, error) {
+	query := fmt.Sprintf("select %s from %s where id = $1 limit 1", shortUrlMapPostgresRows, m.table)
+	return m.findOne(ctx, query, id)
+}
+
+func (m *postgresShortUrlMapModel) FindOneByNamespaceMd5(ctx context.Context, namespace string, md5 string) (*ShortUrlMap, error) {
+	query := fmt.Sprintf("select %s from %s where namespace = $1 and md5 = $2 limit 1", shortUrlMapPostgresRows, m.table)
+	return m.findOne(ctx, query, namespace, md5)
+}
+
+func (m *postgresShortUrlMapModel) FindOneByNamespaceShortUrl(ctx context.Context, namespace string, shortUrl string) (*ShortUrlMap, error) {
+	query := fmt.Sprintf("select %s from %s where namespace = $1 and short_url = $2 limit 1", shortUrlMapPostgresRows, m.table)
+	return m.findOne(ctx, query, namespace, shortUrl)
+}
+
+func (m *postgresShortUrlMapModel) Update(ctx context.Context, data *ShortUrlMap) error {
+	query := fmt.Sprintf("update %s set %s where id = $1", m.table, shortUrlMapPostgresRowsWithPlaceHolder)
+	_, err := m.conn.ExecCtx(ctx, query, data.Id, data.CreateBy, data.UpdateBy, data.IsDel, data.LongUrl, data.Md5, data.ShortUrl, data.ExpireAt, data.ClickCount, data.Namespace, data.FinalUrl, data.CheckStatus, data.CheckCode, data.CheckedAt, data.FailCount, data.FallbackUrl, data.Tag, data.Utm)
+	return err
+}
+
+func (m *postgresShortUrlMapModel) UpdateCheckResult(ctx context.Context, data *ShortUrlMap) error {
+	query := fmt.Sprintf("update %s set final_url = $1, check_status = $2, check_code = $3, checked_at = $4, fail_count = $5 where id = $6", m.table)
+	_, err := m.conn.ExecCtx(ctx, query, data.FinalUrl, data.CheckStatus, data.CheckCode, data.CheckedAt, data.FailCount, data.Id)
+	return err
+}
+
+func (m *postgresShortUrlMapModel) Delete(ctx context.Context, id uint64) error {
+	query := fmt.Sprintf("delete from %s where id = $1", m.table)
+	_, err := m.conn.ExecCtx(ctx, query, id)
+	return err
+}
+
+func (m *postgresShortUrlMapModel) Remove(ctx context.Context, id uint64) (bool, error) {
+	query := fmt.Sprintf("delete from %s where id = $1", m.table)
+	return removed(m.conn.ExecCtx(ctx, query, id))
+}
+
+func (m *postgresShortUrlMapModel) FindShortUrlsAfter(ctx context.Context, id uint64, limit int) ([]*ShortUrlMap, error) {
+	var resp []*ShortUrlMap
+	query := fmt.Sprintf("select id, namespace, short_url from %s where id > $1 and is_del = 0 order by id limit $2", m.table)
+	if err := m.conn.QueryRowsPartialCtx(ctx, &resp, query, id, limit); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (m *postgresShortUrlMapModel) FindLinksAfter(ctx context.Context, id uint64, limit int) ([]*ShortUrlMap, error) {
+	var resp []*ShortUrlMap
+	query := fmt.Sprintf("select %s from %s where id > $1 and is_del = 0 order by id limit $2", shortUrlMapPostgresRows, m.table)
+	if err := m.conn.QueryRowsCtx(ctx, &resp, query, id, limit); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (m *postgresShortUrlMapModel) FindByCheckStatusAfter(ctx context.Context, namespace string, status uint64, id uint64, limit int) ([]*ShortUrlMap, error) {
+	var resp []*ShortUrlMap
+	query := fmt.Sprintf("select %s from %s where namespace = $1 and check_status = $2 and id > $3 and is_del = 0 order by id limit $4", shortUrlMapPostgresRows, m.table)
+	if err := m.conn.QueryRowsCtx(ctx, &resp, query, namespace, status, id, limit); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (m *postgresShortUrlMapModel) findOne(ctx context.Context, query string, args ...any) (*ShortUrlMap, error) {
+	var resp ShortUrlMap
+	if err := m.conn.QueryRowCtx(ctx, &resp, query, args...); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
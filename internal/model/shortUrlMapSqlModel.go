@@ -0,0 +1,101 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+)
+
+var _ ShortUrlMapModel = (*sqlShortUrlMapModel)(nil)
+
+// NewShortUrlMapSqlModel 不带Redis缓存的模型，用于sqlite以及不依赖外部缓存的单机部署
+func NewShortUrlMapSqlModel(conn sqlx.SqlConn) ShortUrlMapModel {
+	return &sqlShortUrlMapModel{
+		conn:  conn,
+		table: "`short_url_map`",
+	}
+}
+
+type sqlShortUrlMapModel struct {
+	conn  sqlx.SqlConn
+	table string
+}
+
+func (m *sqlShortUrlMapModel) Insert(ctx context.Context, data *ShortUrlMap) (sql.Result, error) {
+	query := fmt.Sprintf("insert into %s (%s) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", m.table, shortUrlMapRowsExpectAutoSet)
+	return m.conn.ExecCtx(ctx, query, data.CreateBy, data.UpdateBy, data.IsDel, data.LongUrl, data.Md5, data.ShortUrl, data.ExpireAt, data.ClickCount, data.Namespace, data.FinalUrl, data.CheckStatus, data.CheckCode, data.CheckedAt, data.FailCount, data.FallbackUrl, data.Tag, data.Utm)
+}
+
+func (m *sqlShortUrlMapModel) FindOne(ctx context.Context, id uint64) (*ShortUrlMap, error) {
+	query := fmt.Sprintf("select %s from %s where `id` = ? limit 1", shortUrlMapRows, m.table)
+	return m.findOne(ctx, query, id)
+}
+
+func (m *sqlShortUrlMapModel) FindOneByNamespaceMd5(ctx context.Context, namespace string, md5 string) (*ShortUrlMap, error) {
+	query := fmt.Sprintf("select %s from %s where `namespace` = ? and `md5` = ? limit 1", shortUrlMapRows, m.table)
+	return m.findOne(ctx, query, namespace, md5)
+}
+
+func (m *sqlShortUrlMapModel) FindOneByNamespaceShortUrl(ctx context.Context, namespace string, shortUrl string) (*ShortUrlMap, error) {
+	query := fmt.Sprintf("select %s from %s where `namespace` = ? and `short_url` = ? limit 1", shortUrlMapRows, m.table)
+	return m.findOne(ctx, query, namespace, shortUrl)
+}
+
+func (m *sqlShortUrlMapModel) Update(ctx context.Context, data *ShortUrlMap) error {
+	query := fmt.Sprintf("update %s set %s where `id` = ?", m.table, shortUrlMapRowsWithPlaceHolder)
+	_, err := m.conn.ExecCtx(ctx, query, data.CreateBy, data.UpdateBy, data.IsDel, data.LongUrl, data.Md5, data.ShortUrl, data.ExpireAt, data.ClickCount, data.Namespace, data.FinalUrl, data.CheckStatus, data.CheckCode, data.CheckedAt, data.FailCount, data.FallbackUrl, data.Tag, data.Utm, data.Id)
+	return err
+}
+
+func (m *sqlShortUrlMapModel) UpdateCheckResult(ctx context.Context, data *ShortUrlMap) error {
+	query := fmt.Sprintf("update %s set `final_url` = ?, `check_status` = ?, `check_code` = ?, `checked_at` = ?, `fail_count` = ? where `id` = ?", m.table)
+	_, err := m.conn.ExecCtx(ctx, query, data.FinalUrl, data.CheckStatus, data.CheckCode, data.CheckedAt, data.FailCount, data.Id)
+	return err
+}
+
+func (m *sqlShortUrlMapModel) Delete(ctx context.Context, id uint64) error {
+	query := fmt.Sprintf("delete from %s where `id` = ?", m.table)
+	_, err := m.conn.ExecCtx(ctx, query, id)
+	return err
+}
+
+func (m *sqlShortUrlMapModel) Remove(ctx context.Context, id uint64) (bool, error) {
+	query := fmt.Sprintf("delete from %s where `id` = ?", m.table)
+	return removed(m.conn.ExecCtx(ctx, query, id))
+}
+
+func (m *sqlShortUrlMapModel) FindShortUrlsAfter(ctx context.Context, id uint64, limit int) ([]*ShortUrlMap, error) {
+	var resp []*ShortUrlMap
+	query := fmt.Sprintf("select `id`, `namespace`, `short_url` from %s where `id` > ? and `is_del` = 0 order by `id` limit ?", m.table)
+	if err := m.conn.QueryRowsPartialCtx(ctx, &resp, query, id, limit); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (m *sqlShortUrlMapModel) FindLinksAfter(ctx context.Context, id uint64, limit int) ([]*ShortUrlMap, error) {
+	var resp []*ShortUrlMap
+	query := fmt.Sprintf("select %s from %s where `id` > ? and `is_del` = 0 order by `id` limit ?", shortUrlMapRows, m.table)
+	if err := m.conn.QueryRowsCtx(ctx, &resp, query, id, limit); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (m *sqlShortUrlMapModel) FindByCheckStatusAfter(ctx context.Context, namespace string, status uint64, id uint64, limit int) ([]*ShortUrlMap, error) {
+	var resp []*ShortUrlMap
+	query := fmt.Sprintf("select %s from %s where `namespace` = ? and `check_status` = ? and `id` > ? and `is_del` = 0 order by `id` limit ?", shortUrlMapRows, m.table)
+	if err := m.conn.QueryRowsCtx(ctx, &resp, query, namespace, status, id, limit); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (m *sqlShortUrlMapModel) findOne(ctx context.Context, query string, args ...any) (*ShortUrlMap, error) {
+	var resp ShortUrlMap
+	if err := m.conn.QueryRowCtx(ctx, &resp, query, args...); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
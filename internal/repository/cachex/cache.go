@@ -4,9 +4,19 @@ package cachex
 
 import "context"
 
+// SequenceCache 缓存预分配的序列ID，每个命名空间的ID相互独立
 type SequenceCache interface {
-	GetSingleID(ctx context.Context) (uint64, error)
-	FillIDs(ctx context.Context, ids []uint64) error
+	GetSingleID(ctx context.Context, namespace string) (uint64, error)
+	FillIDs(ctx context.Context, namespace string, ids []uint64) error
 	IsOK(ctx context.Context) bool
-	IsLessThanThreshold(ctx context.Context, threshold int) (bool, error)
+	IsLessThanThreshold(ctx context.Context, namespace string, threshold int) (bool, error)
+	// Len 获取命名空间内剩余的ID数量
+	Len(ctx context.Context, namespace string) (int, error)
+}
+
+// LocalCache 进程内的序列缓存，退出时取出剩余的ID归还到外部缓存
+type LocalCache interface {
+	SequenceCache
+	// Drain 取出命名空间内剩余的全部ID并清空缓存
+	Drain(ctx context.Context, namespace string) ([]uint64, error)
 }
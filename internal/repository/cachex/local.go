@@ -11,48 +11,51 @@ const (
 	defaultCap = 1000
 )
 
-// NewLocalSequenceCache 创建一个新的本地序列缓存
+// NewLocalSequenceCache 创建一个新的本地序列缓存，每个命名空间拥有独立的环形缓冲区
 func NewLocalSequenceCache(capacity int) *LocalSequenceCache {
 	if capacity <= 0 {
 		capacity = defaultCap
 	}
 	return &LocalSequenceCache{
-		head:  0,
-		tail:  0,
-		ids:   make([]uint64, capacity+1), // 环形缓冲区需要额外空间
 		cap:   capacity,
+		rings: make(map[string]*ring),
 		mutex: &sync.RWMutex{},
 	}
 }
 
 type LocalSequenceCache struct {
-	head  int           // 队列头指针
-	tail  int           // 队列尾指针
-	ids   []uint64      // 环形缓冲区
-	cap   int           // 容量
-	mutex *sync.RWMutex // 读写锁
+	cap   int              // 每个命名空间的容量
+	rings map[string]*ring // 命名空间 -> 环形缓冲区
+	mutex *sync.RWMutex    // 读写锁
+}
+
+type ring struct {
+	head int      // 队列头指针
+	tail int      // 队列尾指针
+	ids  []uint64 // 环形缓冲区
 }
 
 // GetSingleID 获取单个ID
-func (c *LocalSequenceCache) GetSingleID(ctx context.Context) (id uint64, err error) {
+func (c *LocalSequenceCache) GetSingleID(ctx context.Context, namespace string) (id uint64, err error) {
 	return id, ProcessTimeout(ctx, func() error {
 		c.mutex.Lock()
 		defer c.mutex.Unlock()
 
-		if c.head == c.tail {
+		r := c.ring(namespace)
+		if r.head == r.tail {
 			return errorx.New(errorx.CodeNotFound, "no id is available in the local cache")
 		}
 
 		// 获取ID并移动头指针
-		id = c.ids[c.head]
-		c.head = (c.head + 1) % (c.cap + 1)
+		id = r.ids[r.head]
+		r.head = (r.head + 1) % (c.cap + 1)
 
 		return nil
 	})
 }
 
 // FillIDs 填充ID到缓存
-func (c *LocalSequenceCache) FillIDs(ctx context.Context, ids []uint64) error {
+func (c *LocalSequenceCache) FillIDs(ctx context.Context, namespace string, ids []uint64) error {
 	if len(ids) == 0 {
 		return nil // 空列表直接返回成功
 	}
@@ -61,40 +64,74 @@ func (c *LocalSequenceCache) FillIDs(ctx context.Context, ids []uint64) error {
 		c.mutex.Lock()
 		defer c.mutex.Unlock()
 
+		r := c.ring(namespace)
+
 		// 计算可用空间
-		available := 0
-		if c.head <= c.tail {
-			available = c.cap - (c.tail - c.head)
-		} else {
-			available = c.head - c.tail - 1
-		}
+		available := c.cap - c.length(r)
 
 		// 确定能填充的ID数量
 		toLoad := len(ids)
 		if toLoad > available {
 			toLoad = available
-			logx.Infof("local cache capacity insufficient, only filling %v ids", toLoad)
+			logx.WithContext(ctx).Infof("local cache capacity insufficient, only filling %v ids", toLoad)
 		}
 
 		// 填充ID
 		for i := 0; i < toLoad; i++ {
-			c.ids[c.tail] = ids[i]
-			c.tail = (c.tail + 1) % (c.cap + 1)
+			r.ids[r.tail] = ids[i]
+			r.tail = (r.tail + 1) % (c.cap + 1)
+		}
+
+		logx.WithContext(ctx).Debugw("local cache filled",
+			logx.Field("namespace", namespace),
+			logx.Field("filled", toLoad),
+			logx.Field("dropped", len(ids)-toLoad))
+		return nil
+	})
+}
+
+// Drain 取出命名空间内全部剩余ID并清空缓存
+func (c *LocalSequenceCache) Drain(ctx context.Context, namespace string) (ids []uint64, err error) {
+	return ids, ProcessTimeout(ctx, func() error {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		r := c.ring(namespace)
+		ids = make([]uint64, 0, c.length(r))
+		for r.head != r.tail {
+			ids = append(ids, r.ids[r.head])
+			r.head = (r.head + 1) % (c.cap + 1)
 		}
 
-		logx.Infof("%v ids successfully filled, %v ids not filled", toLoad, len(ids)-toLoad)
 		return nil
 	})
 }
 
 // IsLessThanThreshold 检查当前缓存中的ID数量是否小于阈值
-func (c *LocalSequenceCache) IsLessThanThreshold(ctx context.Context, threshold int) (bool, error) {
+func (c *LocalSequenceCache) IsLessThanThreshold(ctx context.Context, namespace string, threshold int) (bool, error) {
 	var result bool
 	err := ProcessTimeout(ctx, func() error {
 		c.mutex.RLock() // 只读操作使用读锁
 		defer c.mutex.RUnlock()
 
-		result = c.length() < threshold
+		r, ok := c.rings[namespace]
+		result = !ok || c.length(r) < threshold
+		return nil
+	})
+
+	return result, err
+}
+
+// Len 获取命名空间内剩余的ID数量
+func (c *LocalSequenceCache) Len(ctx context.Context, namespace string) (int, error) {
+	var result int
+	err := ProcessTimeout(ctx, func() error {
+		c.mutex.RLock()
+		defer c.mutex.RUnlock()
+
+		if r, ok := c.rings[namespace]; ok {
+			result = c.length(r)
+		}
 		return nil
 	})
 
@@ -108,12 +145,22 @@ func (c *LocalSequenceCache) IsOK(ctx context.Context) bool {
 	}) == nil
 }
 
+// ring 获取命名空间对应的环形缓冲区，不存在时创建，调用方需持有写锁
+func (c *LocalSequenceCache) ring(namespace string) *ring {
+	r, ok := c.rings[namespace]
+	if !ok {
+		r = &ring{ids: make([]uint64, c.cap+1)} // 环形缓冲区需要额外空间
+		c.rings[namespace] = r
+	}
+	return r
+}
+
 // 计算缓存中的元素数量
-func (c *LocalSequenceCache) length() int {
-	if c.tail >= c.head {
-		return c.tail - c.head
+func (c *LocalSequenceCache) length(r *ring) int {
+	if r.tail >= r.head {
+		return r.tail - r.head
 	}
-	return (c.cap + 1) - (c.head - c.tail)
+	return (c.cap + 1) - (r.head - r.tail)
 }
 
 // ProcessTimeout 处理带超时的操作
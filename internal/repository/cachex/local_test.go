@@ -15,14 +15,14 @@ func TestNewLocalSequenceCache(t *testing.T) {
 	t.Run("默认容量", func(t *testing.T) {
 		cache := NewLocalSequenceCache(0)
 		assert.Equal(t, defaultCap, cache.cap)
-		assert.Equal(t, defaultCap+1, len(cache.ids))
+		assert.Equal(t, defaultCap+1, len(cache.ring("").ids))
 	})
 
 	t.Run("自定义容量", func(t *testing.T) {
 		capacity := 100
 		cache := NewLocalSequenceCache(capacity)
 		assert.Equal(t, capacity, cache.cap)
-		assert.Equal(t, capacity+1, len(cache.ids))
+		assert.Equal(t, capacity+1, len(cache.ring("").ids))
 	})
 }
 
@@ -31,7 +31,7 @@ func TestLocalSequenceCache_GetSingleID_Empty(t *testing.T) {
 	cache := NewLocalSequenceCache(10)
 	ctx := context.Background()
 
-	_, err := cache.GetSingleID(ctx)
+	_, err := cache.GetSingleID(ctx, "")
 	assert.Error(t, err)
 	var targetErr *errorx.ErrorX
 	assert.ErrorAs(t, err, &targetErr)
@@ -45,34 +45,34 @@ func TestLocalSequenceCache_FillAndGetID(t *testing.T) {
 
 	// 填充一些ID
 	ids := []uint64{1, 2, 3, 4, 5}
-	err := cache.FillIDs(ctx, ids)
+	err := cache.FillIDs(ctx, "", ids)
 	assert.NoError(t, err)
 
 	// 检查缓存长度
-	lessThanSix, err := cache.IsLessThanThreshold(ctx, 6)
+	lessThanSix, err := cache.IsLessThanThreshold(ctx, "", 6)
 	assert.NoError(t, err)
 	assert.True(t, lessThanSix)
 
 	// 逐个获取ID
 	for _, expected := range ids {
-		id, err := cache.GetSingleID(ctx)
+		id, err := cache.GetSingleID(ctx, "")
 		assert.NoError(t, err)
 		assert.Equal(t, expected, id)
 	}
 
 	// 再次从空缓存获取
-	_, err = cache.GetSingleID(ctx)
+	_, err = cache.GetSingleID(ctx, "")
 	assert.Error(t, err)
 	var targetErr *errorx.ErrorX
 	assert.ErrorAs(t, err, &targetErr)
 	assert.Equal(t, errorx.CodeNotFound, targetErr.Code)
 
 	ids = append(ids, 6, 7, 8, 9)
-	err = cache.FillIDs(ctx, ids)
+	err = cache.FillIDs(ctx, "", ids)
 	assert.NoError(t, err)
 
 	// 检查缓存长度
-	lessThanSix, err = cache.IsLessThanThreshold(ctx, 6)
+	lessThanSix, err = cache.IsLessThanThreshold(ctx, "", 6)
 	assert.NoError(t, err)
 	assert.False(t, lessThanSix)
 }
@@ -85,17 +85,17 @@ func TestLocalSequenceCache_FillIDs_ExceedCapacity(t *testing.T) {
 
 	// 填充超过容量的ID
 	ids := []uint64{1, 2, 3, 4, 5, 6, 7, 8}
-	err := cache.FillIDs(ctx, ids)
+	err := cache.FillIDs(ctx, "", ids)
 	assert.NoError(t, err)
 
 	// 验证只能获取容量个ID
 	for i := 0; i < capacity; i++ {
-		_, err := cache.GetSingleID(ctx)
+		_, err := cache.GetSingleID(ctx, "")
 		assert.NoError(t, err)
 	}
 
 	// 再次获取应返回错误
-	_, err = cache.GetSingleID(ctx)
+	_, err = cache.GetSingleID(ctx, "")
 	assert.Error(t, err)
 }
 
@@ -106,37 +106,37 @@ func TestLocalSequenceCache_CircularBuffer(t *testing.T) {
 	ctx := context.Background()
 
 	// 第一轮填充
-	err := cache.FillIDs(ctx, []uint64{1, 2, 3})
+	err := cache.FillIDs(ctx, "", []uint64{1, 2, 3})
 	assert.NoError(t, err)
 
 	// 获取两个ID
-	id1, err := cache.GetSingleID(ctx)
+	id1, err := cache.GetSingleID(ctx, "")
 	assert.NoError(t, err)
 	assert.Equal(t, uint64(1), id1)
 
-	id2, err := cache.GetSingleID(ctx)
+	id2, err := cache.GetSingleID(ctx, "")
 	assert.NoError(t, err)
 	assert.Equal(t, uint64(2), id2)
 
 	// 再填充两个ID
-	err = cache.FillIDs(ctx, []uint64{4, 5})
+	err = cache.FillIDs(ctx, "", []uint64{4, 5})
 	assert.NoError(t, err)
 
 	// 获取剩下的ID
-	id3, err := cache.GetSingleID(ctx)
+	id3, err := cache.GetSingleID(ctx, "")
 	assert.NoError(t, err)
 	assert.Equal(t, uint64(3), id3)
 
-	id4, err := cache.GetSingleID(ctx)
+	id4, err := cache.GetSingleID(ctx, "")
 	assert.NoError(t, err)
 	assert.Equal(t, uint64(4), id4)
 
-	id5, err := cache.GetSingleID(ctx)
+	id5, err := cache.GetSingleID(ctx, "")
 	assert.NoError(t, err)
 	assert.Equal(t, uint64(5), id5)
 
 	// 缓存为空
-	_, err = cache.GetSingleID(ctx)
+	_, err = cache.GetSingleID(ctx, "")
 	assert.Error(t, err)
 }
 
@@ -146,26 +146,26 @@ func TestLocalSequenceCache_IsLessThanThreshold(t *testing.T) {
 	ctx := context.Background()
 
 	// 空缓存
-	result, err := cache.IsLessThanThreshold(ctx, 1)
+	result, err := cache.IsLessThanThreshold(ctx, "", 1)
 	assert.NoError(t, err)
 	assert.True(t, result)
 
 	// 填充ID
-	err = cache.FillIDs(ctx, []uint64{1, 2, 3, 4, 5})
+	err = cache.FillIDs(ctx, "", []uint64{1, 2, 3, 4, 5})
 	assert.NoError(t, err)
 
 	// 测试阈值小于当前数量
-	result, err = cache.IsLessThanThreshold(ctx, 3)
+	result, err = cache.IsLessThanThreshold(ctx, "", 3)
 	assert.NoError(t, err)
 	assert.False(t, result)
 
 	// 测试阈值等于当前数量
-	result, err = cache.IsLessThanThreshold(ctx, 5)
+	result, err = cache.IsLessThanThreshold(ctx, "", 5)
 	assert.NoError(t, err)
 	assert.False(t, result)
 
 	// 测试阈值大于当前数量
-	result, err = cache.IsLessThanThreshold(ctx, 10)
+	result, err = cache.IsLessThanThreshold(ctx, "", 10)
 	assert.NoError(t, err)
 	assert.True(t, result)
 }
@@ -194,18 +194,18 @@ func TestLocalSequenceCache_Timeout(t *testing.T) {
 	defer cancel()
 	time.Sleep(5 * time.Millisecond)
 
-	_, err := cache.GetSingleID(ctx)
+	_, err := cache.GetSingleID(ctx, "")
 	assert.Error(t, err)
 	var targetErr *errorx.ErrorX
 	assert.ErrorAs(t, err, &targetErr)
 	assert.Equal(t, errorx.CodeTimeout, targetErr.Code)
 
-	err = cache.FillIDs(ctx, []uint64{1, 2, 3})
+	err = cache.FillIDs(ctx, "", []uint64{1, 2, 3})
 	assert.Error(t, err)
 	assert.ErrorAs(t, err, &targetErr)
 	assert.Equal(t, errorx.CodeTimeout, targetErr.Code)
 
-	_, err = cache.IsLessThanThreshold(ctx, 5)
+	_, err = cache.IsLessThanThreshold(ctx, "", 5)
 	assert.Error(t, err)
 	assert.ErrorAs(t, err, &targetErr)
 	assert.Equal(t, errorx.CodeTimeout, targetErr.Code)
@@ -221,7 +221,7 @@ func TestLocalSequenceCache_Concurrent(t *testing.T) {
 	for i := range ids {
 		ids[i] = uint64(i + 1)
 	}
-	err := cache.FillIDs(ctx, ids)
+	err := cache.FillIDs(ctx, "", ids)
 	assert.NoError(t, err)
 
 	// 并发获取ID
@@ -235,7 +235,7 @@ func TestLocalSequenceCache_Concurrent(t *testing.T) {
 		go func() {
 			defer wg.Done()
 			for j := 0; j < idsPerGoroutine; j++ {
-				id, err := cache.GetSingleID(ctx)
+				id, err := cache.GetSingleID(ctx, "")
 				if err != nil {
 					t.Errorf("获取ID错误: %v", err)
 					return
@@ -264,10 +264,64 @@ func TestLocalSequenceCache_FillIDs_Empty(t *testing.T) {
 	cache := NewLocalSequenceCache(10)
 	ctx := context.Background()
 
-	err := cache.FillIDs(ctx, []uint64{})
+	err := cache.FillIDs(ctx, "", []uint64{})
 	assert.NoError(t, err)
 
 	// 验证缓存仍为空
-	_, err = cache.GetSingleID(ctx)
+	_, err = cache.GetSingleID(ctx, "")
 	assert.Error(t, err)
 }
+
+// 测试取出全部剩余ID
+func TestLocalSequenceCache_Drain(t *testing.T) {
+	cache := NewLocalSequenceCache(5)
+	ctx := context.Background()
+
+	ids, err := cache.Drain(ctx, "")
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	// 制造环形缓冲区回绕
+	assert.NoError(t, cache.FillIDs(ctx, "", []uint64{1, 2, 3, 4}))
+	for i := 0; i < 3; i++ {
+		_, err = cache.GetSingleID(ctx, "")
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, cache.FillIDs(ctx, "", []uint64{5, 6, 7}))
+
+	ids, err = cache.Drain(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{4, 5, 6, 7}, ids)
+
+	_, err = cache.GetSingleID(ctx, "")
+	assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+}
+
+// 测试不同命名空间的缓冲区相互独立
+func TestLocalSequenceCache_Namespace(t *testing.T) {
+	cache := NewLocalSequenceCache(3)
+	ctx := context.Background()
+
+	assert.NoError(t, cache.FillIDs(ctx, "", []uint64{1, 2, 3}))
+	assert.NoError(t, cache.FillIDs(ctx, "brand", []uint64{10, 11}))
+
+	id, err := cache.GetSingleID(ctx, "brand")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), id)
+
+	id, err = cache.GetSingleID(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), id)
+
+	less, err := cache.IsLessThanThreshold(ctx, "other", 1)
+	assert.NoError(t, err)
+	assert.True(t, less)
+
+	ids, err := cache.Drain(ctx, "brand")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{11}, ids)
+
+	ids, err = cache.Drain(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{2, 3}, ids)
+}
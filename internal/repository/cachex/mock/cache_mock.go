@@ -41,47 +41,47 @@ func (m *MockSequenceCache) EXPECT() *MockSequenceCacheMockRecorder {
 }
 
 // FillIDs mocks base method.
-func (m *MockSequenceCache) FillIDs(ctx context.Context, ids []uint64) error {
+func (m *MockSequenceCache) FillIDs(ctx context.Context, namespace string, ids []uint64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FillIDs", ctx, ids)
+	ret := m.ctrl.Call(m, "FillIDs", ctx, namespace, ids)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // FillIDs indicates an expected call of FillIDs.
-func (mr *MockSequenceCacheMockRecorder) FillIDs(ctx, ids any) *gomock.Call {
+func (mr *MockSequenceCacheMockRecorder) FillIDs(ctx, namespace, ids any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FillIDs", reflect.TypeOf((*MockSequenceCache)(nil).FillIDs), ctx, ids)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FillIDs", reflect.TypeOf((*MockSequenceCache)(nil).FillIDs), ctx, namespace, ids)
 }
 
 // GetSingleID mocks base method.
-func (m *MockSequenceCache) GetSingleID(ctx context.Context) (uint64, error) {
+func (m *MockSequenceCache) GetSingleID(ctx context.Context, namespace string) (uint64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSingleID", ctx)
+	ret := m.ctrl.Call(m, "GetSingleID", ctx, namespace)
 	ret0, _ := ret[0].(uint64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetSingleID indicates an expected call of GetSingleID.
-func (mr *MockSequenceCacheMockRecorder) GetSingleID(ctx any) *gomock.Call {
+func (mr *MockSequenceCacheMockRecorder) GetSingleID(ctx, namespace any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSingleID", reflect.TypeOf((*MockSequenceCache)(nil).GetSingleID), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSingleID", reflect.TypeOf((*MockSequenceCache)(nil).GetSingleID), ctx, namespace)
 }
 
 // IsLessThanThreshold mocks base method.
-func (m *MockSequenceCache) IsLessThanThreshold(ctx context.Context, threshold int) (bool, error) {
+func (m *MockSequenceCache) IsLessThanThreshold(ctx context.Context, namespace string, threshold int) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "IsLessThanThreshold", ctx, threshold)
+	ret := m.ctrl.Call(m, "IsLessThanThreshold", ctx, namespace, threshold)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // IsLessThanThreshold indicates an expected call of IsLessThanThreshold.
-func (mr *MockSequenceCacheMockRecorder) IsLessThanThreshold(ctx, threshold any) *gomock.Call {
+func (mr *MockSequenceCacheMockRecorder) IsLessThanThreshold(ctx, namespace, threshold any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsLessThanThreshold", reflect.TypeOf((*MockSequenceCache)(nil).IsLessThanThreshold), ctx, threshold)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsLessThanThreshold", reflect.TypeOf((*MockSequenceCache)(nil).IsLessThanThreshold), ctx, namespace, threshold)
 }
 
 // IsOK mocks base method.
@@ -97,3 +97,130 @@ func (mr *MockSequenceCacheMockRecorder) IsOK(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsOK", reflect.TypeOf((*MockSequenceCache)(nil).IsOK), ctx)
 }
+
+// Len mocks base method.
+func (m *MockSequenceCache) Len(ctx context.Context, namespace string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Len", ctx, namespace)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Len indicates an expected call of Len.
+func (mr *MockSequenceCacheMockRecorder) Len(ctx, namespace any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Len", reflect.TypeOf((*MockSequenceCache)(nil).Len), ctx, namespace)
+}
+
+// MockLocalCache is a mock of LocalCache interface.
+type MockLocalCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockLocalCacheMockRecorder
+	isgomock struct{}
+}
+
+// MockLocalCacheMockRecorder is the mock recorder for MockLocalCache.
+type MockLocalCacheMockRecorder struct {
+	mock *MockLocalCache
+}
+
+// NewMockLocalCache creates a new mock instance.
+func NewMockLocalCache(ctrl *gomock.Controller) *MockLocalCache {
+	mock := &MockLocalCache{ctrl: ctrl}
+	mock.recorder = &MockLocalCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLocalCache) EXPECT() *MockLocalCacheMockRecorder {
+	return m.recorder
+}
+
+// Drain mocks base method.
+func (m *MockLocalCache) Drain(ctx context.Context, namespace string) ([]uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Drain", ctx, namespace)
+	ret0, _ := ret[0].([]uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Drain indicates an expected call of Drain.
+func (mr *MockLocalCacheMockRecorder) Drain(ctx, namespace any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Drain", reflect.TypeOf((*MockLocalCache)(nil).Drain), ctx, namespace)
+}
+
+// FillIDs mocks base method.
+func (m *MockLocalCache) FillIDs(ctx context.Context, namespace string, ids []uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FillIDs", ctx, namespace, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FillIDs indicates an expected call of FillIDs.
+func (mr *MockLocalCacheMockRecorder) FillIDs(ctx, namespace, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FillIDs", reflect.TypeOf((*MockLocalCache)(nil).FillIDs), ctx, namespace, ids)
+}
+
+// GetSingleID mocks base method.
+func (m *MockLocalCache) GetSingleID(ctx context.Context, namespace string) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSingleID", ctx, namespace)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSingleID indicates an expected call of GetSingleID.
+func (mr *MockLocalCacheMockRecorder) GetSingleID(ctx, namespace any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSingleID", reflect.TypeOf((*MockLocalCache)(nil).GetSingleID), ctx, namespace)
+}
+
+// IsLessThanThreshold mocks base method.
+func (m *MockLocalCache) IsLessThanThreshold(ctx context.Context, namespace string, threshold int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsLessThanThreshold", ctx, namespace, threshold)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsLessThanThreshold indicates an expected call of IsLessThanThreshold.
+func (mr *MockLocalCacheMockRecorder) IsLessThanThreshold(ctx, namespace, threshold any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsLessThanThreshold", reflect.TypeOf((*MockLocalCache)(nil).IsLessThanThreshold), ctx, namespace, threshold)
+}
+
+// IsOK mocks base method.
+func (m *MockLocalCache) IsOK(ctx context.Context) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsOK", ctx)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsOK indicates an expected call of IsOK.
+func (mr *MockLocalCacheMockRecorder) IsOK(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsOK", reflect.TypeOf((*MockLocalCache)(nil).IsOK), ctx)
+}
+
+// Len mocks base method.
+func (m *MockLocalCache) Len(ctx context.Context, namespace string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Len", ctx, namespace)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Len indicates an expected call of Len.
+func (mr *MockLocalCacheMockRecorder) Len(ctx, namespace any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Len", reflect.TypeOf((*MockLocalCache)(nil).Len), ctx, namespace)
+}
@@ -23,8 +23,16 @@ type redisSequenceCache struct {
 	keySequenceState string
 }
 
-func (c *redisSequenceCache) GetSingleID(ctx context.Context) (uint64, error) {
-	val, err := c.rdb.LpopCtx(ctx, c.keySequenceID)
+// idKey 获取命名空间对应的ID列表键，默认命名空间沿用原有键名
+func (c *redisSequenceCache) idKey(namespace string) string {
+	if namespace == "" {
+		return c.keySequenceID
+	}
+	return c.keySequenceID + ":" + namespace
+}
+
+func (c *redisSequenceCache) GetSingleID(ctx context.Context, namespace string) (uint64, error) {
+	val, err := c.rdb.LpopCtx(ctx, c.idKey(namespace))
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return 0, errorx.New(errorx.CodeNotFound, "no sequence found in redis")
@@ -40,15 +48,17 @@ func (c *redisSequenceCache) GetSingleID(ctx context.Context) (uint64, error) {
 	return id, nil
 }
 
-func (c *redisSequenceCache) FillIDs(ctx context.Context, ids []uint64) error {
+func (c *redisSequenceCache) FillIDs(ctx context.Context, namespace string, ids []uint64) error {
 	if len(ids) == 0 {
 		return nil
 	}
 
+	key := c.idKey(namespace)
+
 	// Use pipeline for batch writing
 	err := c.rdb.PipelinedCtx(ctx, func(pipe redis.Pipeliner) error {
 		for _, id := range ids {
-			if err := pipe.RPush(ctx, c.keySequenceID, strconv.FormatUint(id, 10)).Err(); err != nil {
+			if err := pipe.RPush(ctx, key, strconv.FormatUint(id, 10)).Err(); err != nil {
 				return errorx.NewWithCause(errorx.CodeCacheError, "failed to push sequence id", err)
 			}
 		}
@@ -71,12 +81,21 @@ func (c *redisSequenceCache) IsOK(ctx context.Context) bool {
 	return c.rdb.PingCtx(ctx)
 }
 
-func (c *redisSequenceCache) IsLessThanThreshold(ctx context.Context, threshold int) (bool, error) {
+func (c *redisSequenceCache) IsLessThanThreshold(ctx context.Context, namespace string, threshold int) (bool, error) {
 	// Get the length of the list
-	length, err := c.rdb.LlenCtx(ctx, c.keySequenceID)
+	length, err := c.rdb.LlenCtx(ctx, c.idKey(namespace))
 	if err != nil {
 		return false, errorx.NewWithCause(errorx.CodeCacheError, "failed to get sequence length from redis", err)
 	}
 
 	return length < threshold, nil
 }
+
+func (c *redisSequenceCache) Len(ctx context.Context, namespace string) (int, error) {
+	length, err := c.rdb.LlenCtx(ctx, c.idKey(namespace))
+	if err != nil {
+		return 0, errorx.NewWithCause(errorx.CodeCacheError, "failed to get sequence length from redis", err)
+	}
+
+	return length, nil
+}
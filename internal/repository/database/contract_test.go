@@ -0,0 +1,403 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+	_ "modernc.org/sqlite"
+	"shortener/internal/config"
+	"shortener/internal/migrate"
+)
+
+// fakeSequenceConn 在内存中模拟 sequence 表及 @current_id 用户变量，行以 namespace/stub 为键
+type fakeSequenceConn struct {
+	sqlx.SqlConn
+	mu   sync.Mutex
+	rows map[string]uint64
+}
+
+func newFakeSequenceConn(stubs ...string) *fakeSequenceConn {
+	rows := make(map[string]uint64, len(stubs))
+	for _, stub := range stubs {
+		rows["/"+stub] = 0
+	}
+	return &fakeSequenceConn{rows: rows}
+}
+
+func (c *fakeSequenceConn) TransactCtx(ctx context.Context, fn func(context.Context, sqlx.Session) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return fn(ctx, &fakeSequenceSession{conn: c})
+}
+
+type fakeSequenceSession struct {
+	sqlx.Session
+	conn    *fakeSequenceConn
+	current uint64
+}
+
+func (s *fakeSequenceSession) ExecCtx(_ context.Context, query string, args ...any) (sql.Result, error) {
+	if query == insertQuery {
+		key := args[0].(string) + "/" + args[1].(string)
+		if _, ok := s.conn.rows[key]; !ok {
+			var seed uint64
+			for rowKey, current := range s.conn.rows {
+				if strings.HasPrefix(rowKey, args[2].(string)+"/") {
+					seed = max(seed, current)
+				}
+			}
+			s.conn.rows[key] = seed
+		}
+		return driver.RowsAffected(1), nil
+	}
+
+	key := args[1].(string) + "/" + args[2].(string)
+	current, ok := s.conn.rows[key]
+	if !ok {
+		return driver.RowsAffected(0), nil
+	}
+	s.current = current
+	s.conn.rows[key] += args[0].(uint64)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeSequenceSession) QueryRowCtx(_ context.Context, v any, _ string, _ ...any) error {
+	*(v.(*uint64)) = s.current
+	return nil
+}
+
+// newSqliteConn 创建内存中的sqlite数据库，单个连接保证所有查询访问同一个数据库
+func newSqliteConn(t *testing.T) sqlx.SqlConn {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	db.SetMaxOpenConns(1)
+	conn := sqlx.NewSqlConnFromDB(db)
+
+	m, err := migrate.NewMigrator(conn, config.StorageDriverSqlite, migrate.TargetSequence)
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+
+	return conn
+}
+
+// 所有后端都必须满足的契约：并发获取的ID全局唯一且数量正确
+func TestSequenceDatabase_Contract(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.New(mr.Addr())
+
+	snow, err := NewSnowflakeSequenceDatabase(1)
+	require.NoError(t, err)
+
+	multi, err := NewMysqlMultiStubSequenceDatabase(newFakeSequenceConn("a", "b", "c"), []string{"a", "b", "c"})
+	require.NoError(t, err)
+
+	backends := map[string]SequenceDatabase{
+		"mysql":     NewMysqlSequenceDatabase(newFakeSequenceConn("a")),
+		"multistub": multi,
+		"redis":     NewRedisSequenceDatabase(rdb, "sequence:counter"),
+		"snowflake": snow,
+		"sqlite":    NewSqliteSequenceDatabase(newSqliteConn(t)),
+		"memory":    NewMemorySequenceDatabase(),
+	}
+
+	const (
+		goroutines = 16
+		rounds     = 50
+		batch      = 10
+	)
+
+	for name, db := range backends {
+		t.Run(name, func(t *testing.T) {
+			var (
+				mu   sync.Mutex
+				seen = make(map[uint64]struct{}, goroutines*rounds*batch)
+				wg   sync.WaitGroup
+			)
+
+			wg.Add(goroutines)
+			for i := 0; i < goroutines; i++ {
+				go func() {
+					defer wg.Done()
+					for j := 0; j < rounds; j++ {
+						ids, err := db.GetBatchIDs(context.Background(), "", batch)
+						assert.NoError(t, err)
+						assert.Len(t, ids, batch)
+
+						mu.Lock()
+						for _, id := range ids {
+							_, dup := seen[id]
+							assert.False(t, dup, "duplicate id %d", id)
+							seen[id] = struct{}{}
+						}
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			assert.Len(t, seen, goroutines*rounds*batch)
+		})
+	}
+}
+
+func TestSequenceDatabase_ZeroBatch(t *testing.T) {
+	snow, err := NewSnowflakeSequenceDatabase(0)
+	require.NoError(t, err)
+
+	ids, err := snow.GetBatchIDs(context.Background(), "", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestMysqlMultiStubSequenceDatabase(t *testing.T) {
+	t.Run("拒绝空stub列表", func(t *testing.T) {
+		_, err := NewMysqlMultiStubSequenceDatabase(newFakeSequenceConn(), nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("拒绝重复stub", func(t *testing.T) {
+		_, err := NewMysqlMultiStubSequenceDatabase(newFakeSequenceConn("a"), []string{"a", "a"})
+		assert.Error(t, err)
+	})
+
+	t.Run("轮询分配到不同stub行", func(t *testing.T) {
+		conn := newFakeSequenceConn("a", "b")
+		db, err := NewMysqlMultiStubSequenceDatabase(conn, []string{"a", "b"})
+		require.NoError(t, err)
+
+		first, err := db.GetBatchIDs(context.Background(), "", 3)
+		require.NoError(t, err)
+		second, err := db.GetBatchIDs(context.Background(), "", 3)
+		require.NoError(t, err)
+
+		assert.Equal(t, []uint64{0, 2, 4}, first)
+		assert.Equal(t, []uint64{1, 3, 5}, second)
+		assert.Equal(t, uint64(3), conn.rows["/a"])
+		assert.Equal(t, uint64(3), conn.rows["/b"])
+	})
+
+	t.Run("新增的stub行不与已发放的ID冲突", func(t *testing.T) {
+		// 单行计数器已发放0-99
+		conn := newFakeSequenceConn("a")
+		conn.rows["/a"] = 100
+		db, err := NewMysqlMultiStubSequenceDatabase(conn, []string{"a", "b"})
+		require.NoError(t, err)
+
+		first, err := db.GetBatchIDs(context.Background(), "", 2)
+		require.NoError(t, err)
+		second, err := db.GetBatchIDs(context.Background(), "", 2)
+		require.NoError(t, err)
+
+		assert.Equal(t, []uint64{200, 202}, first)
+		assert.Equal(t, []uint64{205, 207}, second)
+		assert.Equal(t, uint64(104), conn.rows["/b"])
+	})
+}
+
+func TestSequenceDatabase_Namespace(t *testing.T) {
+	t.Run("mysql按命名空间独立计数并自动建行", func(t *testing.T) {
+		conn := newFakeSequenceConn("a")
+		db := NewMysqlSequenceDatabase(conn)
+
+		ids, err := db.GetBatchIDs(context.Background(), "", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{0, 1}, ids)
+
+		ids, err = db.GetBatchIDs(context.Background(), "brand", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{0, 1}, ids)
+		assert.Equal(t, uint64(2), conn.rows["brand/a"])
+
+		ids, err = db.GetBatchIDs(context.Background(), "", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{2, 3}, ids)
+	})
+
+	t.Run("multistub为新命名空间自动建行", func(t *testing.T) {
+		conn := newFakeSequenceConn("a", "b")
+		db, err := NewMysqlMultiStubSequenceDatabase(conn, []string{"a", "b"})
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, err = db.GetBatchIDs(context.Background(), "brand", 1)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, uint64(1), conn.rows["brand/a"])
+		// b行创建时以a行的计数值为起点
+		assert.Equal(t, uint64(2), conn.rows["brand/b"])
+	})
+
+	t.Run("sqlite按命名空间独立计数并自动建行", func(t *testing.T) {
+		db := NewSqliteSequenceDatabase(newSqliteConn(t))
+
+		ids, err := db.GetBatchIDs(context.Background(), "", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{0, 1}, ids)
+
+		ids, err = db.GetBatchIDs(context.Background(), "brand", 3)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{0, 1, 2}, ids)
+
+		ids, err = db.GetBatchIDs(context.Background(), "", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{2, 3}, ids)
+	})
+
+	t.Run("memory按命名空间独立计数", func(t *testing.T) {
+		db := NewMemorySequenceDatabase()
+
+		ids, err := db.GetBatchIDs(context.Background(), "brand", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{0, 1}, ids)
+
+		ids, err = db.GetBatchIDs(context.Background(), "", 1)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{0}, ids)
+	})
+
+	t.Run("postgres缺少计数行时创建后重试", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(postgresUpdateQuery).WithArgs(uint64(3), "brand", defaultStub).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec(postgresInsertQuery).WithArgs("brand", defaultStub).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(postgresUpdateQuery).WithArgs(uint64(3), "brand", defaultStub).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectCommit()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(postgresUpdateQuery).WithArgs(uint64(2), "brand", defaultStub).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		mock.ExpectCommit()
+
+		seq := NewPostgresSequenceDatabase(sqlx.NewSqlConnFromDB(db))
+		ids, err := seq.GetBatchIDs(context.Background(), "brand", 3)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{0, 1, 2}, ids)
+
+		ids, err = seq.GetBatchIDs(context.Background(), "brand", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{3, 4}, ids)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("redis按命名空间使用独立计数器", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		db := NewRedisSequenceDatabase(redis.New(mr.Addr()), "counter")
+
+		ids, err := db.GetBatchIDs(context.Background(), "brand", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{1, 2}, ids)
+
+		ids, err = db.GetBatchIDs(context.Background(), "", 1)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{1}, ids)
+
+		val, err := mr.Get("counter:brand")
+		require.NoError(t, err)
+		assert.Equal(t, "2", val)
+	})
+}
+
+func TestSnowflakeSequenceDatabase(t *testing.T) {
+	t.Run("机器号越界", func(t *testing.T) {
+		_, err := NewSnowflakeSequenceDatabase(maxWorkerID + 1)
+		assert.Error(t, err)
+		_, err = NewSnowflakeSequenceDatabase(-1)
+		assert.Error(t, err)
+	})
+
+	t.Run("时钟回拨时拒绝生成", func(t *testing.T) {
+		db, err := NewSnowflakeSequenceDatabase(3)
+		require.NoError(t, err)
+
+		now := time.Now()
+		s := db.(*snowflake)
+		s.now = func() time.Time { return now }
+		_, err = db.GetBatchIDs(context.Background(), "", 1)
+		require.NoError(t, err)
+
+		s.now = func() time.Time { return now.Add(-time.Second) }
+		_, err = db.GetBatchIDs(context.Background(), "", 1)
+		assert.Error(t, err)
+	})
+
+	t.Run("ID包含机器号且单调递增", func(t *testing.T) {
+		db, err := NewSnowflakeSequenceDatabase(5)
+		require.NoError(t, err)
+
+		ids, err := db.GetBatchIDs(context.Background(), "", 5000)
+		require.NoError(t, err)
+		for i, id := range ids {
+			assert.Equal(t, uint64(5), (id>>workerIDShift)&maxWorkerID)
+			if i > 0 {
+				assert.Greater(t, id, ids[i-1])
+			}
+		}
+	})
+}
+
+func TestRedisSequenceDatabase(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := NewRedisSequenceDatabase(redis.New(mr.Addr()), "counter")
+
+	ids, err := db.GetBatchIDs(context.Background(), "", 3)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3}, ids)
+
+	ids, err = db.GetBatchIDs(context.Background(), "", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{4, 5}, ids)
+
+	mr.Close()
+	_, err = db.GetBatchIDs(context.Background(), "", 2)
+	assert.Error(t, err)
+}
+
+func TestSeedRedisSequence(t *testing.T) {
+	ctx := context.Background()
+	conn := newSqliteConn(t)
+	sqlite := NewSqliteSequenceDatabase(conn)
+
+	issued, err := sqlite.GetBatchIDs(ctx, "", 10)
+	require.NoError(t, err)
+	brand, err := sqlite.GetBatchIDs(ctx, "brand", 3)
+	require.NoError(t, err)
+
+	mr := miniredis.RunT(t)
+	rdb := redis.New(mr.Addr())
+	require.NoError(t, SeedRedisSequence(ctx, rdb, "counter", conn))
+
+	db := NewRedisSequenceDatabase(rdb, "counter")
+	ids, err := db.GetBatchIDs(ctx, "", 2)
+	require.NoError(t, err)
+	assert.Greater(t, ids[0], issued[len(issued)-1])
+
+	ids, err = db.GetBatchIDs(ctx, "brand", 2)
+	require.NoError(t, err)
+	assert.Greater(t, ids[0], brand[len(brand)-1])
+
+	// 再次初始化不会回退计数器
+	require.NoError(t, SeedRedisSequence(ctx, rdb, "counter", conn))
+	next, err := db.GetBatchIDs(ctx, "brand", 1)
+	require.NoError(t, err)
+	assert.Greater(t, next[0], ids[len(ids)-1])
+}
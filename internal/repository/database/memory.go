@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"sync"
+)
+
+// NewMemorySequenceDatabase 创建进程内的号段分配器，重启后从0开始，仅用于单机模式和测试
+func NewMemorySequenceDatabase() SequenceDatabase {
+	return &memorySequence{counters: make(map[string]uint64)}
+}
+
+type memorySequence struct {
+	mu       sync.Mutex
+	counters map[string]uint64
+}
+
+func (s *memorySequence) GetBatchIDs(_ context.Context, namespace string, batch uint64) ([]uint64, error) {
+	if batch == 0 {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	first := s.counters[namespace]
+	s.counters[namespace] = first + batch
+	s.mu.Unlock()
+
+	return generateIDList(first, batch), nil
+}
@@ -41,16 +41,16 @@ func (m *MockSequenceDatabase) EXPECT() *MockSequenceDatabaseMockRecorder {
 }
 
 // GetBatchIDs mocks base method.
-func (m *MockSequenceDatabase) GetBatchIDs(ctx context.Context, batch uint64) ([]uint64, error) {
+func (m *MockSequenceDatabase) GetBatchIDs(ctx context.Context, namespace string, batch uint64) ([]uint64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetBatchIDs", ctx, batch)
+	ret := m.ctrl.Call(m, "GetBatchIDs", ctx, namespace, batch)
 	ret0, _ := ret[0].([]uint64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetBatchIDs indicates an expected call of GetBatchIDs.
-func (mr *MockSequenceDatabaseMockRecorder) GetBatchIDs(ctx, batch any) *gomock.Call {
+func (mr *MockSequenceDatabaseMockRecorder) GetBatchIDs(ctx, namespace, batch any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBatchIDs", reflect.TypeOf((*MockSequenceDatabase)(nil).GetBatchIDs), ctx, batch)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBatchIDs", reflect.TypeOf((*MockSequenceDatabase)(nil).GetBatchIDs), ctx, namespace, batch)
 }
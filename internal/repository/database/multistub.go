@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+	"shortener/internal/types/errorx"
+	"sync/atomic"
+)
+
+// NewMysqlMultiStubSequenceDatabase 创建多行号段的MySQL序列生成器
+//
+// 每个stub行维护独立计数器，第i行的计数值c映射为ID: c*len(stubs)+i，
+// 不同行生成的ID互不重叠，请求轮询分散到各行以降低单行锁竞争。
+// 缺少的stub行在首次使用时以同一命名空间下已有行的最大计数值创建，计数值c的行发放过的ID都小于 c*len(stubs)，
+// 新行发放的ID不会与已发放的ID冲突；尚不存在的命名空间的各stub行从0开始。
+func NewMysqlMultiStubSequenceDatabase(conn sqlx.SqlConn, stubs []string) (SequenceDatabase, error) {
+	if len(stubs) == 0 {
+		return nil, errorx.New(errorx.CodeParamError, "multi-stub sequence requires at least one stub")
+	}
+
+	seen := make(map[string]struct{}, len(stubs))
+	for _, stub := range stubs {
+		if _, ok := seen[stub]; ok {
+			return nil, errorx.New(errorx.CodeParamError, "duplicate sequence stub").WithMeta("stub", stub)
+		}
+		seen[stub] = struct{}{}
+	}
+
+	return &multiStubSequence{
+		db:    conn,
+		stubs: stubs,
+	}, nil
+}
+
+type multiStubSequence struct {
+	db    sqlx.SqlConn
+	stubs []string
+	next  atomic.Uint64
+}
+
+func (s *multiStubSequence) GetBatchIDs(ctx context.Context, namespace string, batch uint64) ([]uint64, error) {
+	if batch == 0 {
+		return nil, nil
+	}
+
+	// 轮询选择stub行
+	index := (s.next.Add(1) - 1) % uint64(len(s.stubs))
+	stub := s.stubs[index]
+
+	var first uint64
+	err := s.db.TransactCtx(ctx, func(ctx context.Context, tx sqlx.Session) (err error) {
+		first, err = allocate(ctx, tx, namespace, stub, batch)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	n := uint64(len(s.stubs))
+	ids := make([]uint64, batch)
+	for i := uint64(0); i < batch; i++ {
+		ids[i] = (first+i)*n + index
+	}
+	return ids, nil
+}
@@ -9,12 +9,18 @@ import (
 )
 
 const (
-	updateQuery  = `UPDATE sequence SET id = (@current_id := id) + ? WHERE stub = 'a'`
+	defaultStub = "a"
+
+	updateQuery  = `UPDATE sequence SET id = (@current_id := id) + ? WHERE namespace = ? AND stub = ?`
 	currentQuery = `SELECT @current_id`
+	// insertQuery 以命名空间内已有行的最大计数值创建计数行，新增的stub行不会与已发放的ID冲突，
+	// 命名空间没有任何行时从0开始
+	insertQuery = `INSERT IGNORE INTO sequence(id, namespace, stub) SELECT COALESCE(MAX(id), 0), ?, ? FROM sequence WHERE namespace = ?`
 )
 
+// SequenceDatabase allocates batches of unique IDs, each namespace owns an independent code space
 type SequenceDatabase interface {
-	GetBatchIDs(ctx context.Context, batch uint64) ([]uint64, error)
+	GetBatchIDs(ctx context.Context, namespace string, batch uint64) ([]uint64, error)
 }
 
 func NewMysqlSequenceDatabase(conn sqlx.SqlConn) SequenceDatabase {
@@ -25,31 +31,54 @@ type sequence struct {
 	db sqlx.SqlConn
 }
 
-func (s *sequence) GetBatchIDs(ctx context.Context, batch uint64) ([]uint64, error) {
+func (s *sequence) GetBatchIDs(ctx context.Context, namespace string, batch uint64) ([]uint64, error) {
 	var first uint64
-	err := s.db.TransactCtx(ctx, func(ctx context.Context, tx sqlx.Session) error {
-		// Execute UPDATE and set @current_id
-		_, err := tx.ExecCtx(ctx, updateQuery, batch)
-		if err != nil {
-			return errorx.Wrap(err, errorx.CodeDatabaseError, "failed to update ID")
-		}
-
-		// Query the previous @current_id value
-		err = tx.QueryRowCtx(ctx, &first, currentQuery)
-		if err != nil {
-			return errorx.Wrap(err, errorx.CodeDatabaseError, "failed to get current ID")
-		}
-		return nil
+	err := s.db.TransactCtx(ctx, func(ctx context.Context, tx sqlx.Session) (err error) {
+		first, err = allocate(ctx, tx, namespace, defaultStub, batch)
+		return err
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return s.generateIDList(first, batch), nil // Return the next available ID
+	return generateIDList(first, batch), nil // Return the next available ID
+}
+
+// allocate advances the counter row of (namespace, stub) by batch and returns its previous value,
+// a missing row is created on first use, seeded from the largest counter of the namespace
+func allocate(ctx context.Context, tx sqlx.Session, namespace, stub string, batch uint64) (uint64, error) {
+	// Execute UPDATE and set @current_id
+	result, err := tx.ExecCtx(ctx, updateQuery, batch, namespace, stub)
+	if err != nil {
+		return 0, errorx.Wrap(err, errorx.CodeDatabaseError, "failed to update ID").
+			WithMeta("namespace", namespace).WithMeta("stub", stub)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		// Counter row does not exist yet, create it and retry
+		if _, err = tx.ExecCtx(ctx, insertQuery, namespace, stub, namespace); err != nil {
+			return 0, errorx.Wrap(err, errorx.CodeDatabaseError, "failed to create sequence row").
+				WithMeta("namespace", namespace).WithMeta("stub", stub)
+		}
+
+		if _, err = tx.ExecCtx(ctx, updateQuery, batch, namespace, stub); err != nil {
+			return 0, errorx.Wrap(err, errorx.CodeDatabaseError, "failed to update ID").
+				WithMeta("namespace", namespace).WithMeta("stub", stub)
+		}
+	}
+
+	// Query the previous @current_id value
+	var first uint64
+	err = tx.QueryRowCtx(ctx, &first, currentQuery)
+	if err != nil {
+		return 0, errorx.Wrap(err, errorx.CodeDatabaseError, "failed to get current ID").
+			WithMeta("namespace", namespace).WithMeta("stub", stub)
+	}
+	return first, nil
 }
 
 // generateIDList generates ID list
-func (s *sequence) generateIDList(startID uint64, count uint64) []uint64 {
+func generateIDList(startID uint64, count uint64) []uint64 {
 	if count <= 0 {
 		return nil
 	}
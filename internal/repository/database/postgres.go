@@ -0,0 +1,20 @@
+package database
+
+import (
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+)
+
+const (
+	postgresUpdateQuery = `UPDATE sequence SET id = id + $1 WHERE namespace = $2 AND stub = $3 RETURNING id`
+	postgresInsertQuery = `INSERT INTO sequence(id, namespace, stub) VALUES (0, $1, $2) ON CONFLICT DO NOTHING`
+)
+
+// NewPostgresSequenceDatabase 创建基于postgres的号段分配器，表结构见internal/migrate/migrations/postgres。
+// 每个命名空间需要独立计数，因此使用计数行而不是序列对象，UPDATE的行锁保证并发分配的号段不重叠。
+func NewPostgresSequenceDatabase(conn sqlx.SqlConn) SequenceDatabase {
+	return &returningSequence{
+		db:          conn,
+		updateQuery: postgresUpdateQuery,
+		insertQuery: postgresInsertQuery,
+	}
+}
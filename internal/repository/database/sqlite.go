@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+	"shortener/internal/types/errorx"
+)
+
+const (
+	sqliteUpdateQuery = `UPDATE sequence SET id = id + ? WHERE namespace = ? AND stub = ? RETURNING id`
+	sqliteInsertQuery = `INSERT INTO sequence(id, namespace, stub) VALUES (0, ?, ?) ON CONFLICT DO NOTHING`
+)
+
+// NewSqliteSequenceDatabase 创建基于sqlite的号段分配器，与mysql后端共用序号表结构
+func NewSqliteSequenceDatabase(conn sqlx.SqlConn) SequenceDatabase {
+	return &returningSequence{
+		db:          conn,
+		updateQuery: sqliteUpdateQuery,
+		insertQuery: sqliteInsertQuery,
+	}
+}
+
+// returningSequence 使用 UPDATE ... RETURNING 在一条语句内完成递增和读取，
+// 不需要mysql的用户变量，适用于sqlite和postgres
+type returningSequence struct {
+	db          sqlx.SqlConn
+	updateQuery string
+	insertQuery string
+}
+
+func (s *returningSequence) GetBatchIDs(ctx context.Context, namespace string, batch uint64) ([]uint64, error) {
+	if batch == 0 {
+		return nil, nil
+	}
+
+	var last uint64
+	err := s.db.TransactCtx(ctx, func(ctx context.Context, tx sqlx.Session) error {
+		err := tx.QueryRowCtx(ctx, &last, s.updateQuery, batch, namespace, defaultStub)
+		if !errors.Is(err, sqlx.ErrNotFound) {
+			return err
+		}
+
+		// 计数行不存在时创建后重试
+		if _, err = tx.ExecCtx(ctx, s.insertQuery, namespace, defaultStub); err != nil {
+			return err
+		}
+		return tx.QueryRowCtx(ctx, &last, s.updateQuery, batch, namespace, defaultStub)
+	})
+	if err != nil {
+		return nil, errorx.Wrap(err, errorx.CodeDatabaseError, "failed to update ID").
+			WithMeta("namespace", namespace)
+	}
+
+	// RETURNING 返回更新后的值，本号段从更新前的值开始
+	return generateIDList(last-batch, batch), nil
+}
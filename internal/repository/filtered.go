@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"shortener/pkg/filter"
+)
+
+// NewFilteredShortUrlMap 删除映射后从短码过滤器中移除该短码。
+// 计数布隆过滤器同一数据只能移除一次，仓库只在实际删除了行时返回成功，多个实例并发删除同一短码时只移除一次。
+func NewFilteredShortUrlMap(base ShortUrlMap, f filter.Filter) ShortUrlMap {
+	return &filteredShortUrlMap{
+		ShortUrlMap: base,
+		filter:      f,
+	}
+}
+
+type filteredShortUrlMap struct {
+	ShortUrlMap
+	filter filter.Filter
+}
+
+// Delete 删除成功后移除过滤器中的短码，已被删除的短码返回NotFound，不会重复移除
+func (s *filteredShortUrlMap) Delete(ctx context.Context, namespace, shortUrl string) error {
+	if err := s.ShortUrlMap.Delete(ctx, namespace, shortUrl); err != nil {
+		return err
+	}
+
+	// 映射已删除，过滤器中残留的短码只会让查询多走一次数据库
+	if err := s.filter.RemoveCtx(ctx, FilterKey(namespace, shortUrl)); err != nil {
+		logx.WithContext(ctx).Errorf("remove short code from filter failed,namespace:%v,shortUrl:%v,err:%v",
+			namespace, shortUrl, err)
+	}
+	return nil
+}
+
+// FilterKey 过滤器中的短码键，非默认命名空间加前缀以区分不同命名空间的相同短码
+func FilterKey(namespace, shortUrl string) []byte {
+	if len(namespace) == 0 {
+		return []byte(shortUrl)
+	}
+	return []byte(namespace + ":" + shortUrl)
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"shortener/internal/types/errorx"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	repositoryMock "shortener/internal/repository/mock"
+	filterMock "shortener/pkg/filter/mock"
+)
+
+func TestFilteredShortUrlMap_Delete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mockBase := repositoryMock.NewMockShortUrlMap(ctrl)
+	mockFilter := filterMock.NewMockFilter(ctrl)
+	repo := NewFilteredShortUrlMap(mockBase, mockFilter)
+
+	t.Run("删除后移除过滤器中的短码", func(t *testing.T) {
+		mockBase.EXPECT().Delete(gomock.Any(), "brand", "abc").Return(nil)
+		mockFilter.EXPECT().RemoveCtx(gomock.Any(), []byte("brand:abc")).Return(nil)
+
+		assert.NoError(t, repo.Delete(ctx, "brand", "abc"))
+	})
+
+	t.Run("移除失败不影响删除结果", func(t *testing.T) {
+		mockBase.EXPECT().Delete(gomock.Any(), "", "abc").Return(nil)
+		mockFilter.EXPECT().RemoveCtx(gomock.Any(), []byte("abc")).
+			Return(errorx.New(errorx.CodeCacheError, "redis error"))
+
+		assert.NoError(t, repo.Delete(ctx, "", "abc"))
+	})
+
+	t.Run("删除失败时不移除", func(t *testing.T) {
+		mockBase.EXPECT().Delete(gomock.Any(), "", "missing").
+			Return(errorx.New(errorx.CodeNotFound, "the data does not exist"))
+
+		err := repo.Delete(ctx, "", "missing")
+		assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+	})
+}
+
+func TestFilterKey(t *testing.T) {
+	assert.Equal(t, []byte("abc"), FilterKey("", "abc"))
+	assert.Equal(t, []byte("brand:abc"), FilterKey("brand", "abc"))
+}
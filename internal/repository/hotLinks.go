@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"hash/maphash"
+	"shortener/internal/config"
+	"shortener/internal/types/errorx"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// saveHotLinksScript 按距上次保存的时间衰减已有的次数，再累加本实例的访问次数，只保留前N个并设置过期时间。
+// 同一次发布中各实例的保存间隔很短，次数基本不衰减；之前发布累积的次数按半衰期逐渐失效
+// KEYS[1]: 热点短链有序集合; KEYS[2]: 上次保存的时间; ARGV[1]: 保留个数; ARGV[2]: 过期秒数;
+// ARGV[3]: 当前秒数; ARGV[4]: 半衰期秒数; ARGV[5...]: 次数与短链交替
+const saveHotLinksScript = `local now = tonumber(ARGV[3])
+local last = tonumber(redis.call('GET', KEYS[2])) or now
+if now > last then
+	local factor = 0.5 ^ ((now - last) / tonumber(ARGV[4]))
+	local items = redis.call('ZRANGE', KEYS[1], 0, -1, 'WITHSCORES')
+	for i = 1, #items, 2 do
+		redis.call('ZADD', KEYS[1], tonumber(items[i + 1]) * factor, items[i])
+	end
+end
+for i = 5, #ARGV, 2 do
+	redis.call('ZINCRBY', KEYS[1], ARGV[i], ARGV[i + 1])
+end
+redis.call('ZREMRANGEBYRANK', KEYS[1], 0, -tonumber(ARGV[1]) - 1)
+redis.call('EXPIRE', KEYS[1], ARGV[2])
+if now >= last then
+	redis.call('SET', KEYS[2], now, 'EX', ARGV[2])
+end
+return 1`
+
+const (
+	// hotLinksSavedAtSuffix 记录上次保存时间的键名后缀
+	hotLinksSavedAtSuffix = ":savedAt"
+	// hotLinksTrackFactor 统计个数为TopN的倍数，先到的短链占满后不再统计新的短链
+	hotLinksTrackFactor = 10
+	// hotLinksShards 访问计数的分片数，并发访问不同短链时不会竞争同一把锁
+	hotLinksShards = 32
+)
+
+// HotLinks 统计本实例的热点短链，退出时持久化到Redis，供新启动的实例预热
+type HotLinks interface {
+	// Touch 记录一次短链访问
+	Touch(namespace, shortUrl string)
+	// Save 将访问最多的短链合并到Redis
+	Save(ctx context.Context) error
+	// Load 读取上一批实例持久化的热点短链，按访问次数从高到低排列
+	Load(ctx context.Context) ([]Link, error)
+}
+
+// Link 短链在命名空间中的标识
+type Link struct {
+	Namespace string
+	ShortUrl  string
+}
+
+// NewHotLinks 创建热点短链统计，最多统计TopN的若干倍个短链以限制内存
+func NewHotLinks(rdb *redis.Redis, conf config.WarmUpConf) HotLinks {
+	h := &hotLinks{
+		rdb:      rdb,
+		key:      conf.Key,
+		topN:     conf.TopN,
+		ttl:      int(conf.TTL.Seconds()),
+		halfLife: max(int64(conf.HalfLife.Seconds()), 1),
+		seed:     maphash.MakeSeed(),
+		now:      time.Now,
+	}
+
+	// 每个分片的上限取整后不少于1，总数可能略多于TopN的若干倍
+	limit := (conf.TopN*hotLinksTrackFactor + hotLinksShards - 1) / hotLinksShards
+	for i := range h.shards {
+		h.shards[i] = hotLinkShard{limit: limit, counts: make(map[Link]uint64)}
+	}
+	return h
+}
+
+type hotLinks struct {
+	rdb      *redis.Redis
+	key      string
+	topN     int
+	ttl      int
+	halfLife int64
+	seed     maphash.Seed
+	now      func() time.Time
+
+	shards [hotLinksShards]hotLinkShard
+}
+
+// hotLinkShard 一个分片内的访问计数
+type hotLinkShard struct {
+	mu     sync.Mutex
+	limit  int
+	counts map[Link]uint64
+}
+
+func (h *hotLinks) Touch(namespace, shortUrl string) {
+	link := Link{Namespace: namespace, ShortUrl: shortUrl}
+	shard := &h.shards[h.shardOf(link)]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if _, ok := shard.counts[link]; !ok && len(shard.counts) >= shard.limit {
+		return
+	}
+	shard.counts[link]++
+}
+
+func (h *hotLinks) shardOf(link Link) uint64 {
+	return maphash.String(h.seed, linkKey(link.Namespace, link.ShortUrl)) % hotLinksShards
+}
+
+func (h *hotLinks) Save(ctx context.Context) error {
+	top := h.top()
+	if len(top) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, 4+len(top)*2)
+	args = append(args, h.topN, h.ttl, h.now().Unix(), h.halfLife)
+	for _, item := range top {
+		args = append(args, item.count, linkKey(item.link.Namespace, item.link.ShortUrl))
+	}
+
+	keys := []string{h.key, h.key + hotLinksSavedAtSuffix}
+	if _, err := h.rdb.EvalCtx(ctx, saveHotLinksScript, keys, args...); err != nil {
+		return errorx.NewWithCause(errorx.CodeCacheError, "save hot links failed", err).
+			WithMeta("key", h.key)
+	}
+	return nil
+}
+
+func (h *hotLinks) Load(ctx context.Context) ([]Link, error) {
+	members, err := h.rdb.ZrevrangeCtx(ctx, h.key, 0, int64(h.topN-1))
+	if err != nil {
+		return nil, errorx.NewWithCause(errorx.CodeCacheError, "load hot links failed", err).
+			WithMeta("key", h.key)
+	}
+
+	links := make([]Link, 0, len(members))
+	for _, member := range members {
+		namespace, shortUrl, ok := strings.Cut(member, ":")
+		if !ok {
+			continue
+		}
+		links = append(links, Link{Namespace: namespace, ShortUrl: shortUrl})
+	}
+	return links, nil
+}
+
+type hotLinkCount struct {
+	link  Link
+	count uint64
+}
+
+// top 获取访问次数最多的TopN个短链
+func (h *hotLinks) top() []hotLinkCount {
+	var items []hotLinkCount
+	for i := range h.shards {
+		shard := &h.shards[i]
+		shard.mu.Lock()
+		for link, count := range shard.counts {
+			items = append(items, hotLinkCount{link: link, count: count})
+		}
+		shard.mu.Unlock()
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].count != items[j].count {
+			return items[i].count > items[j].count
+		}
+		return linkKey(items[i].link.Namespace, items[i].link.ShortUrl) < linkKey(items[j].link.Namespace, items[j].link.ShortUrl)
+	})
+
+	if len(items) > h.topN {
+		items = items[:h.topN]
+	}
+	return items
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"shortener/internal/config"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+)
+
+func TestHotLinks(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.New(mr.Addr())
+	conf := config.WarmUpConf{TopN: 2, Key: "hot", TTL: time.Hour}
+
+	// 第一个实例
+	first := NewHotLinks(rdb, conf)
+	for i := 0; i < 3; i++ {
+		first.Touch("", "a")
+	}
+	first.Touch("", "b")
+	first.Touch("brand", "c")
+	first.Touch("brand", "c")
+	assert.NoError(t, first.Save(ctx))
+
+	// 第二个实例的次数与第一个合并
+	second := NewHotLinks(rdb, conf)
+	for i := 0; i < 5; i++ {
+		second.Touch("brand", "c")
+	}
+	assert.NoError(t, second.Save(ctx))
+
+	links, err := NewHotLinks(rdb, conf).Load(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []Link{{Namespace: "brand", ShortUrl: "c"}, {ShortUrl: "a"}}, links)
+
+	members, err := mr.ZMembers("hot")
+	assert.NoError(t, err)
+	assert.Len(t, members, 2, "只保留前TopN个")
+	assert.Equal(t, time.Hour, mr.TTL("hot"))
+}
+
+func TestHotLinks_Decay(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.New(mr.Addr())
+	conf := config.WarmUpConf{TopN: 2, Key: "hot", TTL: 24 * time.Hour, HalfLife: time.Hour}
+	now := time.Now()
+
+	// 上一次发布的热点
+	previous := NewHotLinks(rdb, conf).(*hotLinks)
+	previous.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		previous.Touch("", "old")
+	}
+	assert.NoError(t, previous.Save(ctx))
+
+	// 两个半衰期后保存，旧的次数衰减为四分之一
+	current := NewHotLinks(rdb, conf).(*hotLinks)
+	current.now = func() time.Time { return now.Add(2 * time.Hour) }
+	current.Touch("", "new")
+	assert.NoError(t, current.Save(ctx))
+
+	links, err := current.Load(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []Link{{ShortUrl: "new"}, {ShortUrl: "old"}}, links)
+
+	score, err := mr.ZScore("hot", ":old")
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.75, score, 0.001)
+}
+
+func TestHotLinks_Bounded(t *testing.T) {
+	h := NewHotLinks(nil, config.WarmUpConf{TopN: 100}).(*hotLinks)
+
+	for i := 0; i < 100*hotLinksTrackFactor*4; i++ {
+		h.Touch("", strconv.Itoa(i))
+	}
+
+	// 每个分片统计的个数不超过上限
+	total := 0
+	for i := range h.shards {
+		assert.LessOrEqual(t, len(h.shards[i].counts), h.shards[i].limit)
+		total += len(h.shards[i].counts)
+	}
+	assert.LessOrEqual(t, total, 100*hotLinksTrackFactor+hotLinksShards)
+
+	// 已统计的短链继续累加
+	h.Touch("", "0")
+	shard := &h.shards[h.shardOf(Link{ShortUrl: "0"})]
+	assert.Equal(t, uint64(2), shard.counts[Link{ShortUrl: "0"}])
+}
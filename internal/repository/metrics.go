@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zeromicro/go-zero/core/logx"
+	"time"
+)
+
+const (
+	tierRedis = "redis"
+	tierLocal = "local"
+	tierDB    = "db"
+
+	// collectTimeout 采集缓存水位时单次查询的超时时间
+	collectTimeout = 200 * time.Millisecond
+)
+
+// sequenceMetrics contains all prometheus metrics of the sequence generator.
+var sequenceMetrics = struct {
+	// idsServed tracks the number of IDs handed out, labeled by the tier that served them.
+	idsServed *prometheus.CounterVec
+
+	// refills tracks batch allocations from the database, labeled by the cache tier being refilled
+	// and the result ("success" or "failure").
+	refills *prometheus.CounterVec
+
+	// refillDuration tracks the latency of batch allocations from the database.
+	refillDuration *prometheus.HistogramVec
+
+	// batchSize tracks the number of IDs returned by each batch allocation.
+	batchSize *prometheus.HistogramVec
+
+	// fallbacks tracks transitions from one tier to the next when a tier fails.
+	fallbacks *prometheus.CounterVec
+
+	// localBufferIDs reports the number of IDs currently held in the local ring buffer.
+	localBufferIDs *prometheus.Desc
+
+	// redisRemainingIDs reports the number of IDs remaining in the redis list.
+	redisRemainingIDs *prometheus.Desc
+}{
+	idsServed: prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "shortener",
+			Subsystem: "sequence",
+			Name:      "ids_served_total",
+			Help:      "Number of sequence IDs served by tier (redis/local/db)",
+		},
+		[]string{"tier"},
+	),
+	refills: prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "shortener",
+			Subsystem: "sequence",
+			Name:      "refills_total",
+			Help:      "Number of batch allocations from the database by refilled tier and result",
+		},
+		[]string{"tier", "result"},
+	),
+	refillDuration: prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "shortener",
+			Subsystem: "sequence",
+			Name:      "refill_duration_seconds",
+			Help:      "Latency of batch allocations from the database by refilled tier",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"tier"},
+	),
+	batchSize: prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "shortener",
+			Subsystem: "sequence",
+			Name:      "batch_size",
+			Help:      "Number of IDs returned by each batch allocation by refilled tier",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+		},
+		[]string{"tier"},
+	),
+	fallbacks: prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "shortener",
+			Subsystem: "sequence",
+			Name:      "fallback_transitions_total",
+			Help:      "Number of fallback transitions between tiers",
+		},
+		[]string{"from", "to"},
+	),
+	localBufferIDs: prometheus.NewDesc(
+		"shortener_sequence_local_buffer_ids",
+		"Number of IDs held in the local ring buffer by namespace",
+		[]string{"namespace"}, nil,
+	),
+	redisRemainingIDs: prometheus.NewDesc(
+		"shortener_sequence_redis_remaining_ids",
+		"Number of IDs remaining in the redis list by namespace",
+		[]string{"namespace"}, nil,
+	),
+}
+
+// negativeCacheMetrics contains all prometheus metrics of the short url negative cache.
+var negativeCacheMetrics = struct {
+	// lookups tracks negative cache lookups labeled by result ("hit" or "miss"),
+	// the negative hit ratio is hit / (hit + miss).
+	lookups *prometheus.CounterVec
+}{
+	lookups: prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "shortener",
+			Subsystem: "negative_cache",
+			Name:      "lookups_total",
+			Help:      "Number of short url negative cache lookups by result (hit/miss)",
+		},
+		[]string{"result"},
+	),
+}
+
+// hotCacheMetrics contains all prometheus metrics of the in-process hot link cache.
+var hotCacheMetrics = struct {
+	// lookups tracks hot link cache lookups labeled by result ("hit" or "miss").
+	lookups *prometheus.CounterVec
+}{
+	lookups: prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "shortener",
+			Subsystem: "hot_cache",
+			Name:      "lookups_total",
+			Help:      "Number of in-process hot link cache lookups by result (hit/miss)",
+		},
+		[]string{"result"},
+	),
+}
+
+// RegisterMetrics registers the repository metrics with the provided prometheus registerer.
+// The sequence buffer level gauges are collected from seq on every scrape.
+//
+// Example:
+//
+//	repository.RegisterMetrics(prometheus.DefaultRegisterer, seq)
+func RegisterMetrics(reg prometheus.Registerer, seq Sequence) {
+	reg.MustRegister(
+		sequenceMetrics.idsServed,
+		sequenceMetrics.refills,
+		sequenceMetrics.refillDuration,
+		sequenceMetrics.batchSize,
+		sequenceMetrics.fallbacks,
+		negativeCacheMetrics.lookups,
+		hotCacheMetrics.lookups,
+	)
+
+	if s, ok := seq.(*sequence); ok {
+		reg.MustRegister(&sequenceCollector{seq: s})
+	}
+}
+
+// sequenceCollector 在采集时读取各命名空间的缓存水位
+type sequenceCollector struct {
+	seq *sequence
+}
+
+func (c *sequenceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sequenceMetrics.localBufferIDs
+	ch <- sequenceMetrics.redisRemainingIDs
+}
+
+func (c *sequenceCollector) Collect(ch chan<- prometheus.Metric) {
+	c.seq.namespaces.Range(func(key, _ any) bool {
+		namespace := key.(string)
+
+		ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+		defer cancel()
+
+		if n, err := c.seq.localCache.Len(ctx, namespace); err == nil {
+			ch <- prometheus.MustNewConstMetric(sequenceMetrics.localBufferIDs, prometheus.GaugeValue, float64(n), namespace)
+		}
+
+		if !c.seq.externalCacheAvailable.Load() {
+			return true
+		}
+
+		n, err := c.seq.externalCache.Len(ctx, namespace)
+		if err != nil {
+			logx.Debugw("collect redis remaining ids failed", logx.Field("namespace", namespace), logx.Field("err", err))
+			return true
+		}
+		ch <- prometheus.MustNewConstMetric(sequenceMetrics.redisRemainingIDs, prometheus.GaugeValue, float64(n), namespace)
+
+		return true
+	})
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"shortener/internal/repository/cachex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	cachexMock "shortener/internal/repository/cachex/mock"
+	databaseMock "shortener/internal/repository/database/mock"
+)
+
+func TestSequenceMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := databaseMock.NewMockSequenceDatabase(ctrl)
+	mockExternalCache := cachexMock.NewMockSequenceCache(ctrl)
+	localCache := cachex.NewLocalSequenceCache(1000)
+
+	seq := &sequence{
+		database:      mockDB,
+		externalCache: mockExternalCache,
+		localCache:    localCache,
+		retryBackoff:  50 * time.Millisecond,
+		externPatch:   1000,
+		localPatch:    3,
+	}
+
+	servedDB := testutil.ToFloat64(sequenceMetrics.idsServed.WithLabelValues(tierDB))
+	servedLocal := testutil.ToFloat64(sequenceMetrics.idsServed.WithLabelValues(tierLocal))
+	refilled := testutil.ToFloat64(sequenceMetrics.refills.WithLabelValues(tierLocal, "success"))
+
+	// 外部缓存不可用，直接走本地缓存：第一次从数据库补充，后续命中本地缓存
+	mockDB.EXPECT().GetBatchIDs(gomock.Any(), "", uint64(3)).Return([]uint64{1, 2, 3}, nil)
+	for i := 0; i < 3; i++ {
+		_, err := seq.NextID(context.Background(), "")
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, servedDB+1, testutil.ToFloat64(sequenceMetrics.idsServed.WithLabelValues(tierDB)))
+	assert.Equal(t, servedLocal+2, testutil.ToFloat64(sequenceMetrics.idsServed.WithLabelValues(tierLocal)))
+	assert.Equal(t, refilled+1, testutil.ToFloat64(sequenceMetrics.refills.WithLabelValues(tierLocal, "success")))
+
+	t.Run("外部缓存降级只计一次", func(t *testing.T) {
+		fallbacks := testutil.ToFloat64(sequenceMetrics.fallbacks.WithLabelValues(tierRedis, tierLocal))
+
+		seq.externalCacheAvailable.Store(true)
+		seq.disableExternalCache()
+		seq.disableExternalCache()
+
+		assert.Equal(t, fallbacks+1, testutil.ToFloat64(sequenceMetrics.fallbacks.WithLabelValues(tierRedis, tierLocal)))
+	})
+
+	t.Run("采集缓存水位", func(t *testing.T) {
+		seq.externalCacheAvailable.Store(true)
+		mockExternalCache.EXPECT().Len(gomock.Any(), "").Return(42, nil)
+		assert.NoError(t, localCache.FillIDs(context.Background(), "", []uint64{7, 8}))
+
+		reg := prometheus.NewPedanticRegistry()
+		reg.MustRegister(&sequenceCollector{seq: seq})
+
+		expected := `
+# HELP shortener_sequence_local_buffer_ids Number of IDs held in the local ring buffer by namespace
+# TYPE shortener_sequence_local_buffer_ids gauge
+shortener_sequence_local_buffer_ids{namespace=""} 2
+# HELP shortener_sequence_redis_remaining_ids Number of IDs remaining in the redis list by namespace
+# TYPE shortener_sequence_redis_remaining_ids gauge
+shortener_sequence_redis_remaining_ids{namespace=""} 42
+`
+		assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected)))
+	})
+}
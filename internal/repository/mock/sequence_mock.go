@@ -41,16 +41,30 @@ func (m *MockSequence) EXPECT() *MockSequenceMockRecorder {
 }
 
 // NextID mocks base method.
-func (m *MockSequence) NextID(ctx context.Context) (uint64, error) {
+func (m *MockSequence) NextID(ctx context.Context, namespace string) (uint64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "NextID", ctx)
+	ret := m.ctrl.Call(m, "NextID", ctx, namespace)
 	ret0, _ := ret[0].(uint64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // NextID indicates an expected call of NextID.
-func (mr *MockSequenceMockRecorder) NextID(ctx any) *gomock.Call {
+func (mr *MockSequenceMockRecorder) NextID(ctx, namespace any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NextID", reflect.TypeOf((*MockSequence)(nil).NextID), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NextID", reflect.TypeOf((*MockSequence)(nil).NextID), ctx, namespace)
+}
+
+// Release mocks base method.
+func (m *MockSequence) Release(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockSequenceMockRecorder) Release(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockSequence)(nil).Release), ctx)
 }
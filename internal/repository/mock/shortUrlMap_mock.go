@@ -41,34 +41,48 @@ func (m *MockShortUrlMap) EXPECT() *MockShortUrlMapMockRecorder {
 	return m.recorder
 }
 
+// Delete mocks base method.
+func (m *MockShortUrlMap) Delete(ctx context.Context, namespace, shortUrl string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, namespace, shortUrl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockShortUrlMapMockRecorder) Delete(ctx, namespace, shortUrl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockShortUrlMap)(nil).Delete), ctx, namespace, shortUrl)
+}
+
 // FindOneByMd5 mocks base method.
-func (m *MockShortUrlMap) FindOneByMd5(ctx context.Context, md5 string) (*model.ShortUrlMap, error) {
+func (m *MockShortUrlMap) FindOneByMd5(ctx context.Context, namespace, md5 string) (*model.ShortUrlMap, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindOneByMd5", ctx, md5)
+	ret := m.ctrl.Call(m, "FindOneByMd5", ctx, namespace, md5)
 	ret0, _ := ret[0].(*model.ShortUrlMap)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // FindOneByMd5 indicates an expected call of FindOneByMd5.
-func (mr *MockShortUrlMapMockRecorder) FindOneByMd5(ctx, md5 any) *gomock.Call {
+func (mr *MockShortUrlMapMockRecorder) FindOneByMd5(ctx, namespace, md5 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOneByMd5", reflect.TypeOf((*MockShortUrlMap)(nil).FindOneByMd5), ctx, md5)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOneByMd5", reflect.TypeOf((*MockShortUrlMap)(nil).FindOneByMd5), ctx, namespace, md5)
 }
 
 // FindOneByShortUrl mocks base method.
-func (m *MockShortUrlMap) FindOneByShortUrl(ctx context.Context, shortUrl string) (*model.ShortUrlMap, error) {
+func (m *MockShortUrlMap) FindOneByShortUrl(ctx context.Context, namespace, shortUrl string) (*model.ShortUrlMap, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindOneByShortUrl", ctx, shortUrl)
+	ret := m.ctrl.Call(m, "FindOneByShortUrl", ctx, namespace, shortUrl)
 	ret0, _ := ret[0].(*model.ShortUrlMap)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // FindOneByShortUrl indicates an expected call of FindOneByShortUrl.
-func (mr *MockShortUrlMapMockRecorder) FindOneByShortUrl(ctx, shortUrl any) *gomock.Call {
+func (mr *MockShortUrlMapMockRecorder) FindOneByShortUrl(ctx, namespace, shortUrl any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOneByShortUrl", reflect.TypeOf((*MockShortUrlMap)(nil).FindOneByShortUrl), ctx, shortUrl)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOneByShortUrl", reflect.TypeOf((*MockShortUrlMap)(nil).FindOneByShortUrl), ctx, namespace, shortUrl)
 }
 
 // Insert mocks base method.
@@ -84,3 +98,74 @@ func (mr *MockShortUrlMapMockRecorder) Insert(ctx, data any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockShortUrlMap)(nil).Insert), ctx, data)
 }
+
+// ListByCheckStatus mocks base method.
+func (m *MockShortUrlMap) ListByCheckStatus(ctx context.Context, namespace string, status, cursor uint64, limit int) ([]*model.ShortUrlMap, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByCheckStatus", ctx, namespace, status, cursor, limit)
+	ret0, _ := ret[0].([]*model.ShortUrlMap)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByCheckStatus indicates an expected call of ListByCheckStatus.
+func (mr *MockShortUrlMapMockRecorder) ListByCheckStatus(ctx, namespace, status, cursor, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByCheckStatus", reflect.TypeOf((*MockShortUrlMap)(nil).ListByCheckStatus), ctx, namespace, status, cursor, limit)
+}
+
+// RangeLinks mocks base method.
+func (m *MockShortUrlMap) RangeLinks(ctx context.Context, batch int, fn func([]*model.ShortUrlMap) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RangeLinks", ctx, batch, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RangeLinks indicates an expected call of RangeLinks.
+func (mr *MockShortUrlMapMockRecorder) RangeLinks(ctx, batch, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RangeLinks", reflect.TypeOf((*MockShortUrlMap)(nil).RangeLinks), ctx, batch, fn)
+}
+
+// RangeShortUrls mocks base method.
+func (m *MockShortUrlMap) RangeShortUrls(ctx context.Context, batch int, fn func([]*model.ShortUrlMap) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RangeShortUrls", ctx, batch, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RangeShortUrls indicates an expected call of RangeShortUrls.
+func (mr *MockShortUrlMapMockRecorder) RangeShortUrls(ctx, batch, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RangeShortUrls", reflect.TypeOf((*MockShortUrlMap)(nil).RangeShortUrls), ctx, batch, fn)
+}
+
+// Update mocks base method.
+func (m *MockShortUrlMap) Update(ctx context.Context, data *model.ShortUrlMap) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockShortUrlMapMockRecorder) Update(ctx, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockShortUrlMap)(nil).Update), ctx, data)
+}
+
+// UpdateCheckResult mocks base method.
+func (m *MockShortUrlMap) UpdateCheckResult(ctx context.Context, data *model.ShortUrlMap) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCheckResult", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCheckResult indicates an expected call of UpdateCheckResult.
+func (mr *MockShortUrlMapMockRecorder) UpdateCheckResult(ctx, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCheckResult", reflect.TypeOf((*MockShortUrlMap)(nil).UpdateCheckResult), ctx, data)
+}
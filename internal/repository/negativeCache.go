@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/collection"
+	"github.com/zeromicro/go-zero/core/logx"
+	"shortener/internal/config"
+	"shortener/internal/model"
+	"shortener/internal/types/errorx"
+	"shortener/pkg/pubsub"
+)
+
+// NewNegativeCacheShortUrlMap 为短码查询增加进程内的负缓存：
+// 数据库中不存在的短码在TTL内直接返回NotFound，避免过滤器误判或已删除的短码反复查询MySQL。
+// 新建短码时删除本地缓存并通过发布订阅通知其他实例。
+func NewNegativeCacheShortUrlMap(base ShortUrlMap, conf config.NegativeCacheConf, ps pubsub.PubSub) ShortUrlMap {
+	c, err := collection.NewCache(conf.TTL, collection.WithLimit(conf.Limit), collection.WithName("negative-short-url"))
+	if err != nil {
+		logx.Severef("init negative cache failed,err:%v", err)
+		return base
+	}
+
+	n := &negativeCacheShortUrlMap{
+		ShortUrlMap: base,
+		cache:       c,
+		ps:          ps,
+		channel:     conf.Channel,
+	}
+	ps.Subscribe(context.Background(), conf.Channel, c.Del)
+
+	return n
+}
+
+type negativeCacheShortUrlMap struct {
+	ShortUrlMap
+	cache   *collection.Cache
+	ps      pubsub.PubSub
+	channel string
+}
+
+// Insert 写入成功后使该短码的负缓存失效
+func (n *negativeCacheShortUrlMap) Insert(ctx context.Context, data *model.ShortUrlMap) error {
+	if err := n.ShortUrlMap.Insert(ctx, data); err != nil {
+		return err
+	}
+
+	key := linkKey(data.Namespace, data.ShortUrl)
+	n.cache.Del(key)
+	if err := n.ps.Publish(ctx, n.channel, key); err != nil {
+		logx.WithContext(ctx).Errorf("publish negative cache invalidation failed,key:%v,err:%v", key, err)
+	}
+	return nil
+}
+
+func (n *negativeCacheShortUrlMap) FindOneByShortUrl(ctx context.Context, namespace, shortUrl string) (*model.ShortUrlMap, error) {
+	key := linkKey(namespace, shortUrl)
+	if _, ok := n.cache.Get(key); ok {
+		negativeCacheMetrics.lookups.WithLabelValues("hit").Inc()
+		return nil, errorx.New(errorx.CodeNotFound, "the data does not exist").
+			WithMeta("namespace", namespace).WithMeta("shortUrl", shortUrl)
+	}
+	negativeCacheMetrics.lookups.WithLabelValues("miss").Inc()
+
+	data, err := n.ShortUrlMap.FindOneByShortUrl(ctx, namespace, shortUrl)
+	if errorx.Is(err, errorx.CodeNotFound) {
+		n.cache.Set(key, struct{}{})
+	}
+	return data, err
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"shortener/internal/config"
+	"shortener/internal/model"
+	"shortener/internal/types/errorx"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	repositoryMock "shortener/internal/repository/mock"
+	pubsubMock "shortener/pkg/pubsub/mock"
+)
+
+func TestNegativeCacheShortUrlMap(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mockBase := repositoryMock.NewMockShortUrlMap(ctrl)
+	mockPubSub := pubsubMock.NewMockPubSub(ctrl)
+
+	conf := config.NegativeCacheConf{TTL: time.Minute, Limit: 10, Channel: "created"}
+
+	// 记录订阅的处理函数，模拟其他实例发布的失效消息
+	var invalidate func(message string)
+	mockPubSub.EXPECT().Subscribe(gomock.Any(), "created", gomock.Any()).
+		Do(func(_ context.Context, _ string, handler func(message string)) {
+			invalidate = handler
+		})
+
+	repo := NewNegativeCacheShortUrlMap(mockBase, conf, mockPubSub)
+
+	hits := testutil.ToFloat64(negativeCacheMetrics.lookups.WithLabelValues("hit"))
+	misses := testutil.ToFloat64(negativeCacheMetrics.lookups.WithLabelValues("miss"))
+
+	t.Run("不存在的短码只查询一次数据库", func(t *testing.T) {
+		mockBase.EXPECT().FindOneByShortUrl(gomock.Any(), "", "abc").
+			Return(nil, errorx.New(errorx.CodeNotFound, "the data does not exist")).Times(1)
+
+		for i := 0; i < 3; i++ {
+			_, err := repo.FindOneByShortUrl(ctx, "", "abc")
+			assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+		}
+
+		assert.Equal(t, hits+2, testutil.ToFloat64(negativeCacheMetrics.lookups.WithLabelValues("hit")))
+		assert.Equal(t, misses+1, testutil.ToFloat64(negativeCacheMetrics.lookups.WithLabelValues("miss")))
+	})
+
+	t.Run("其他错误不缓存", func(t *testing.T) {
+		mockBase.EXPECT().FindOneByShortUrl(gomock.Any(), "", "err").
+			Return(nil, errorx.New(errorx.CodeDatabaseError, "database error")).Times(2)
+
+		for i := 0; i < 2; i++ {
+			_, err := repo.FindOneByShortUrl(ctx, "", "err")
+			assert.True(t, errorx.Is(err, errorx.CodeDatabaseError))
+		}
+	})
+
+	t.Run("新建短码后失效并通知其他实例", func(t *testing.T) {
+		data := &model.ShortUrlMap{ShortUrl: "abc"}
+		mockBase.EXPECT().Insert(gomock.Any(), data).Return(nil)
+		mockPubSub.EXPECT().Publish(gomock.Any(), "created", ":abc").Return(nil)
+		assert.NoError(t, repo.Insert(ctx, data))
+
+		mockBase.EXPECT().FindOneByShortUrl(gomock.Any(), "", "abc").Return(data, nil)
+		got, err := repo.FindOneByShortUrl(ctx, "", "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, data, got)
+	})
+
+	t.Run("收到其他实例的失效消息", func(t *testing.T) {
+		mockBase.EXPECT().FindOneByShortUrl(gomock.Any(), "brand", "xyz").
+			Return(nil, errorx.New(errorx.CodeNotFound, "the data does not exist")).Times(2)
+
+		_, err := repo.FindOneByShortUrl(ctx, "brand", "xyz")
+		assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+
+		invalidate("brand:xyz")
+
+		_, err = repo.FindOneByShortUrl(ctx, "brand", "xyz")
+		assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+	})
+}
@@ -7,14 +7,19 @@ import (
 	"shortener/internal/repository/cachex"
 	"shortener/internal/repository/database"
 	"shortener/internal/types/errorx"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
 // Sequence defines the sequence generator interface
 type Sequence interface {
-	// NextID returns the next unique sequence ID
-	NextID(ctx context.Context) (uint64, error)
+	// NextID returns the next unique sequence ID within the namespace
+	NextID(ctx context.Context, namespace string) (uint64, error)
+	// Release returns the unused locally cached IDs of all namespaces to the external cache
+	Release(ctx context.Context) error
 }
 
 type SequenceOptions struct {
@@ -55,7 +60,7 @@ func (opt SequenceOptions) WithDefault() SequenceOptions {
 func NewSequence(
 	db database.SequenceDatabase,
 	externalCache cachex.SequenceCache,
-	localCache cachex.SequenceCache,
+	localCache cachex.LocalCache,
 	opts SequenceOptions,
 ) Sequence {
 	opts = opts.WithDefault()
@@ -87,7 +92,7 @@ func NewSequence(
 type sequence struct {
 	database      database.SequenceDatabase
 	externalCache cachex.SequenceCache
-	localCache    cachex.SequenceCache
+	localCache    cachex.LocalCache
 
 	externPatch    uint64
 	cacheThreshold int
@@ -97,20 +102,24 @@ type sequence struct {
 	externalCacheAvailable atomic.Bool
 	retryBackoff           time.Duration
 	maxRetries             int
+
+	// 已使用过的命名空间，退出时据此归还本地ID
+	namespaces sync.Map
 }
 
 // NextID generates and returns the next unique ID
-func (s *sequence) NextID(ctx context.Context) (uint64, error) {
+func (s *sequence) NextID(ctx context.Context, namespace string) (uint64, error) {
+	s.namespaces.LoadOrStore(namespace, struct{}{})
+
 	// 只有当外部缓存被标记为可用时才尝试从外部缓存获取ID
 	if s.externalCacheAvailable.Load() {
-		logx.Info("Getting ID from externalCache")
-		id, err := s.externalCache.GetSingleID(ctx)
+		id, err := s.externalCache.GetSingleID(ctx, namespace)
 		if err == nil {
-			return id, nil
+			return s.served(ctx, tierRedis, namespace, id), nil
 		}
 
 		if errorx.Is(err, errorx.CodeNotFound) {
-			ids, err := s.database.GetBatchIDs(ctx, s.externPatch)
+			ids, err := s.fetchBatch(ctx, tierRedis, namespace, s.externPatch)
 			if err == nil {
 				if len(ids) == 0 {
 					return 0, errorx.New(errorx.CodeNotFound, "database returned empty ID list")
@@ -124,31 +133,31 @@ func (s *sequence) NextID(ctx context.Context) (uint64, error) {
 					remainingIDs = []uint64{} // 明确使用空切片而非nil
 				}
 
-				err = s.externalCache.FillIDs(ctx, remainingIDs)
+				err = s.externalCache.FillIDs(ctx, namespace, remainingIDs)
 				if err != nil {
 					logx.Errorf("external cahce fill ids failed,err:%v,batch first id:%v,batch size:%v", err, ids[0], s.externPatch)
-					s.externalCacheAvailable.Store(false)
+					s.disableExternalCache()
 				}
 
-				return ids[0], nil
+				return s.served(ctx, tierDB, namespace, ids[0]), nil
 			}
 
 			return 0, errorx.Wrap(err, errorx.CodeDatabaseError, "get ids from database failed")
 		}
 
-		s.externalCacheAvailable.Store(false)
+		s.disableExternalCache()
 
 		logx.Errorf("external cache is unavailable,err:%v,try to fix it", errorx.Wrap(err, errorx.CodeCacheError, "get id from cache failed"))
 	}
 
 	//使用本地缓存
-	id, err := s.localCache.GetSingleID(ctx)
+	id, err := s.localCache.GetSingleID(ctx, namespace)
 	if err == nil {
-		return id, nil
+		return s.served(ctx, tierLocal, namespace, id), nil
 	}
 
 	if errorx.Is(err, errorx.CodeNotFound) {
-		ids, err := s.database.GetBatchIDs(ctx, s.localPatch)
+		ids, err := s.fetchBatch(ctx, tierLocal, namespace, s.localPatch)
 		if err == nil {
 			if len(ids) == 0 {
 				return 0, errorx.New(errorx.CodeNotFound, "database returned empty ID list")
@@ -160,20 +169,21 @@ func (s *sequence) NextID(ctx context.Context) (uint64, error) {
 				remainingIDs = ids[1:]
 			}
 
-			err = s.localCache.FillIDs(ctx, remainingIDs)
+			err = s.localCache.FillIDs(ctx, namespace, remainingIDs)
 			if err != nil {
 				logx.Errorf("local cache fill ids failed,err:%v", err)
 			}
 
-			return ids[0], nil
+			return s.served(ctx, tierDB, namespace, ids[0]), nil
 		}
 
 		return 0, errorx.Wrap(err, errorx.CodeDatabaseError, "get id from database failed")
 	}
 
 	logx.Errorf("get id from local cache failed,err:%v", errorx.Wrap(err, errorx.CodeCacheError, "get single id from local cache failed"))
+	sequenceMetrics.fallbacks.WithLabelValues(tierLocal, tierDB).Inc()
 
-	ids, err := s.database.GetBatchIDs(ctx, 1)
+	ids, err := s.fetchBatch(ctx, tierDB, namespace, 1)
 	if err != nil {
 		return 0, errorx.Wrap(err, errorx.CodeDatabaseError, "get id from database failed")
 	}
@@ -182,5 +192,107 @@ func (s *sequence) NextID(ctx context.Context) (uint64, error) {
 		return 0, errorx.New(errorx.CodeNotFound, "database returned empty ID list")
 	}
 
-	return ids[0], nil
+	return s.served(ctx, tierDB, namespace, ids[0]), nil
+}
+
+// fetchBatch 从数据库批量获取ID并记录耗时与批量大小
+func (s *sequence) fetchBatch(ctx context.Context, tier, namespace string, batch uint64) ([]uint64, error) {
+	start := time.Now()
+	ids, err := s.database.GetBatchIDs(ctx, namespace, batch)
+	sequenceMetrics.refillDuration.WithLabelValues(tier).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		sequenceMetrics.refills.WithLabelValues(tier, "failure").Inc()
+		return nil, err
+	}
+
+	sequenceMetrics.refills.WithLabelValues(tier, "success").Inc()
+	sequenceMetrics.batchSize.WithLabelValues(tier).Observe(float64(len(ids)))
+	logx.WithContext(ctx).Debugw("sequence batch fetched",
+		logx.Field("tier", tier),
+		logx.Field("namespace", namespace),
+		logx.Field("size", len(ids)),
+		logx.Field("duration", time.Since(start)))
+
+	return ids, nil
+}
+
+// served 记录ID的来源层级
+func (s *sequence) served(ctx context.Context, tier, namespace string, id uint64) uint64 {
+	sequenceMetrics.idsServed.WithLabelValues(tier).Inc()
+	logx.WithContext(ctx).Debugw("sequence id served",
+		logx.Field("tier", tier),
+		logx.Field("namespace", namespace),
+		logx.Field("id", id))
+	return id
+}
+
+// disableExternalCache 将外部缓存标记为不可用，后续请求降级到本地缓存
+func (s *sequence) disableExternalCache() {
+	if s.externalCacheAvailable.CompareAndSwap(true, false) {
+		sequenceMetrics.fallbacks.WithLabelValues(tierRedis, tierLocal).Inc()
+	}
+}
+
+// Release 将本地缓存中未使用的ID归还到外部缓存，避免重启时浪费号段
+func (s *sequence) Release(ctx context.Context) error {
+	var lastErr error
+	s.namespaces.Range(func(key, _ any) bool {
+		if err := s.releaseNamespace(ctx, key.(string)); err != nil {
+			lastErr = err
+		}
+		return true
+	})
+
+	return lastErr
+}
+
+// releaseNamespace 归还单个命名空间的本地ID
+func (s *sequence) releaseNamespace(ctx context.Context, namespace string) error {
+	ids, err := s.localCache.Drain(ctx, namespace)
+	if err != nil {
+		return errorx.Wrap(err, errorx.CodeCacheError, "drain local cache failed").
+			WithMeta("namespace", namespace)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if !s.externalCacheAvailable.Load() || !s.externalCache.IsOK(ctx) {
+		logx.Errorf("external cache is unavailable, %v unused ids of namespace %q are discarded,ranges:%v", len(ids), namespace, formatIDRanges(ids))
+		return errorx.New(errorx.CodeCacheError, "external cache is unavailable").
+			WithMeta("namespace", namespace).WithMeta("discarded", len(ids))
+	}
+
+	if err = s.externalCache.FillIDs(ctx, namespace, ids); err != nil {
+		logx.Errorf("return unused ids of namespace %q to external cache failed,err:%v,ranges:%v", namespace, err, formatIDRanges(ids))
+		return errorx.Wrap(err, errorx.CodeCacheError, "return unused ids to external cache failed").
+			WithMeta("namespace", namespace)
+	}
+
+	logx.Infof("%v unused ids of namespace %q returned to external cache", len(ids), namespace)
+	return nil
+}
+
+// formatIDRanges 将ID列表压缩为区间表示，便于日志记录
+func formatIDRanges(ids []uint64) string {
+	var b strings.Builder
+	for i := 0; i < len(ids); {
+		j := i
+		for j+1 < len(ids) && ids[j+1] == ids[j]+1 {
+			j++
+		}
+
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		if i == j {
+			b.WriteString(strconv.FormatUint(ids[i], 10))
+		} else {
+			b.WriteString(strconv.FormatUint(ids[i], 10) + "-" + strconv.FormatUint(ids[j], 10))
+		}
+		i = j + 1
+	}
+	return b.String()
 }
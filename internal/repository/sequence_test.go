@@ -23,7 +23,7 @@ func TestSequence_NextID(t *testing.T) {
 	// 创建mock对象
 	mockDB := databaseMock.NewMockSequenceDatabase(ctrl)
 	mockExternalCache := cachexMock.NewMockSequenceCache(ctrl)
-	mockLocalCache := cachexMock.NewMockSequenceCache(ctrl)
+	mockLocalCache := cachexMock.NewMockLocalCache(ctrl)
 
 	// 测试配置
 	opts := SequenceOptions{
@@ -46,7 +46,7 @@ func TestSequence_NextID(t *testing.T) {
 		{
 			name: "从外部缓存获取ID成功",
 			setupMocks: func() {
-				mockExternalCache.EXPECT().GetSingleID(gomock.Any()).Return(uint64(123), nil)
+				mockExternalCache.EXPECT().GetSingleID(gomock.Any(), "").Return(uint64(123), nil)
 			},
 			expectedID:         123,
 			expectedErr:        nil,
@@ -55,9 +55,9 @@ func TestSequence_NextID(t *testing.T) {
 		{
 			name: "外部缓存为空，从数据库批量获取并填充缓存",
 			setupMocks: func() {
-				mockExternalCache.EXPECT().GetSingleID(gomock.Any()).Return(uint64(0), errorx.New(errorx.CodeNotFound, "无可用ID"))
-				mockDB.EXPECT().GetBatchIDs(gomock.Any(), uint64(1000)).Return([]uint64{100, 101, 102}, nil)
-				mockExternalCache.EXPECT().FillIDs(gomock.Any(), []uint64{101, 102}).Return(nil)
+				mockExternalCache.EXPECT().GetSingleID(gomock.Any(), "").Return(uint64(0), errorx.New(errorx.CodeNotFound, "无可用ID"))
+				mockDB.EXPECT().GetBatchIDs(gomock.Any(), "", uint64(1000)).Return([]uint64{100, 101, 102}, nil)
+				mockExternalCache.EXPECT().FillIDs(gomock.Any(), "", []uint64{101, 102}).Return(nil)
 			},
 			expectedID:         100,
 			expectedErr:        nil,
@@ -67,7 +67,7 @@ func TestSequence_NextID(t *testing.T) {
 			name: "外部缓存失效，使用本地缓存",
 			setupMocks: func() {
 				// 预填充本地缓存
-				mockLocalCache.EXPECT().GetSingleID(gomock.Any()).Return(uint64(200), nil)
+				mockLocalCache.EXPECT().GetSingleID(gomock.Any(), "").Return(uint64(200), nil)
 			},
 			expectedID:         200,
 			expectedErr:        nil,
@@ -79,9 +79,9 @@ func TestSequence_NextID(t *testing.T) {
 				// 当外部缓存被标记为不可用时，确保不会调用GetSingleID
 
 				// 本地缓存失败
-				mockLocalCache.EXPECT().GetSingleID(gomock.Any()).Return(uint64(0), errorx.New(errorx.CodeSystemError, "本地缓存错误"))
+				mockLocalCache.EXPECT().GetSingleID(gomock.Any(), "").Return(uint64(0), errorx.New(errorx.CodeSystemError, "本地缓存错误"))
 				// 直接从数据库获取ID - 应返回999
-				mockDB.EXPECT().GetBatchIDs(gomock.Any(), uint64(1)).Return([]uint64{999}, nil)
+				mockDB.EXPECT().GetBatchIDs(gomock.Any(), "", uint64(1)).Return([]uint64{999}, nil)
 			},
 			expectedID:         999,
 			expectedErr:        nil,
@@ -91,9 +91,9 @@ func TestSequence_NextID(t *testing.T) {
 			name: "所有途径获取ID都失败",
 			setupMocks: func() {
 				// 本地缓存返回错误
-				mockLocalCache.EXPECT().GetSingleID(gomock.Any()).Return(uint64(0), errorx.New(errorx.CodeSystemError, "本地缓存错误"))
+				mockLocalCache.EXPECT().GetSingleID(gomock.Any(), "").Return(uint64(0), errorx.New(errorx.CodeSystemError, "本地缓存错误"))
 				// 模拟数据库返回错误
-				mockDB.EXPECT().GetBatchIDs(gomock.Any(), uint64(1)).Return(nil, errorx.New(errorx.CodeDatabaseError, "数据库错误"))
+				mockDB.EXPECT().GetBatchIDs(gomock.Any(), "", uint64(1)).Return(nil, errorx.New(errorx.CodeDatabaseError, "数据库错误"))
 			},
 			expectedID:         0,
 			expectedErr:        errorx.New(errorx.CodeDatabaseError, "数据库错误"),
@@ -124,7 +124,7 @@ func TestSequence_NextID(t *testing.T) {
 			tt.setupMocks()
 
 			// 执行测试
-			id, err := seq.NextID(context.Background())
+			id, err := seq.NextID(context.Background(), "")
 
 			// 验证结果
 			if tt.expectedErr == nil {
@@ -164,11 +164,11 @@ func TestSequence_ExternalCacheFillFailure(t *testing.T) {
 
 	seq.externalCacheAvailable.Store(true)
 
-	mockExternalCache.EXPECT().GetSingleID(gomock.Any()).Return(uint64(0), errorx.New(errorx.CodeNotFound, "缓存为空"))
-	mockDB.EXPECT().GetBatchIDs(gomock.Any(), uint64(1000)).Return([]uint64{100, 101, 102}, nil)
-	mockExternalCache.EXPECT().FillIDs(gomock.Any(), []uint64{101, 102}).Return(errorx.New(errorx.CodeCacheError, "填充缓存失败"))
+	mockExternalCache.EXPECT().GetSingleID(gomock.Any(), "").Return(uint64(0), errorx.New(errorx.CodeNotFound, "缓存为空"))
+	mockDB.EXPECT().GetBatchIDs(gomock.Any(), "", uint64(1000)).Return([]uint64{100, 101, 102}, nil)
+	mockExternalCache.EXPECT().FillIDs(gomock.Any(), "", []uint64{101, 102}).Return(errorx.New(errorx.CodeCacheError, "填充缓存失败"))
 
-	id, err := seq.NextID(context.Background())
+	id, err := seq.NextID(context.Background(), "")
 
 	assert.NoError(t, err)
 	assert.Equal(t, uint64(100), id)
@@ -182,7 +182,7 @@ func TestSequence_LocalCacheNonNotFoundError(t *testing.T) {
 
 	mockDB := databaseMock.NewMockSequenceDatabase(ctrl)
 	mockExternalCache := cachexMock.NewMockSequenceCache(ctrl)
-	mockLocalCache := cachexMock.NewMockSequenceCache(ctrl)
+	mockLocalCache := cachexMock.NewMockLocalCache(ctrl)
 
 	seq := &sequence{
 		database:       mockDB,
@@ -199,10 +199,10 @@ func TestSequence_LocalCacheNonNotFoundError(t *testing.T) {
 	seq.externalCacheAvailable.Store(false)
 
 	// 本地缓存返回系统错误(非NotFound)
-	mockLocalCache.EXPECT().GetSingleID(gomock.Any()).Return(uint64(0), errorx.New(errorx.CodeSystemError, "系统错误"))
-	mockDB.EXPECT().GetBatchIDs(gomock.Any(), uint64(1)).Return([]uint64{300}, nil)
+	mockLocalCache.EXPECT().GetSingleID(gomock.Any(), "").Return(uint64(0), errorx.New(errorx.CodeSystemError, "系统错误"))
+	mockDB.EXPECT().GetBatchIDs(gomock.Any(), "", uint64(1)).Return([]uint64{300}, nil)
 
-	id, err := seq.NextID(context.Background())
+	id, err := seq.NextID(context.Background(), "")
 
 	assert.NoError(t, err)
 	assert.Equal(t, uint64(300), id)
@@ -232,19 +232,19 @@ func TestSequence_LocalCacheContinuousGet(t *testing.T) {
 	seq.externalCacheAvailable.Store(false)
 
 	// 首次调用，本地缓存为空
-	mockDB.EXPECT().GetBatchIDs(gomock.Any(), uint64(500)).Return([]uint64{200, 201, 202, 203}, nil)
+	mockDB.EXPECT().GetBatchIDs(gomock.Any(), "", uint64(500)).Return([]uint64{200, 201, 202, 203}, nil)
 
 	// 第一次请求
-	id1, err1 := seq.NextID(context.Background())
+	id1, err1 := seq.NextID(context.Background(), "")
 	assert.NoError(t, err1)
 	assert.Equal(t, uint64(200), id1)
 
 	// 后续请求应直接从本地缓存获取
-	id2, err2 := seq.NextID(context.Background())
+	id2, err2 := seq.NextID(context.Background(), "")
 	assert.NoError(t, err2)
 	assert.Equal(t, uint64(201), id2)
 
-	id3, err3 := seq.NextID(context.Background())
+	id3, err3 := seq.NextID(context.Background(), "")
 	assert.NoError(t, err3)
 	assert.Equal(t, uint64(202), id3)
 }
@@ -256,7 +256,7 @@ func TestSequence_SingleIDFromDatabase(t *testing.T) {
 
 	mockDB := databaseMock.NewMockSequenceDatabase(ctrl)
 	mockExternalCache := cachexMock.NewMockSequenceCache(ctrl)
-	mockLocalCache := cachexMock.NewMockSequenceCache(ctrl) // 修改为mock对象
+	mockLocalCache := cachexMock.NewMockLocalCache(ctrl) // 修改为mock对象
 
 	seq := &sequence{
 		database:       mockDB,
@@ -272,12 +272,12 @@ func TestSequence_SingleIDFromDatabase(t *testing.T) {
 
 	seq.externalCacheAvailable.Store(true)
 
-	mockExternalCache.EXPECT().GetSingleID(gomock.Any()).Return(uint64(0), errorx.New(errorx.CodeNotFound, "缓存为空"))
-	mockDB.EXPECT().GetBatchIDs(gomock.Any(), uint64(1000)).Return([]uint64{400}, nil)
+	mockExternalCache.EXPECT().GetSingleID(gomock.Any(), "").Return(uint64(0), errorx.New(errorx.CodeNotFound, "缓存为空"))
+	mockDB.EXPECT().GetBatchIDs(gomock.Any(), "", uint64(1000)).Return([]uint64{400}, nil)
 	// 使用gomock.Any()代替明确指定空切片，这样可以匹配任何切片包括nil和空切片
-	mockExternalCache.EXPECT().FillIDs(gomock.Any(), gomock.Any()).Return(nil)
+	mockExternalCache.EXPECT().FillIDs(gomock.Any(), "", gomock.Any()).Return(nil)
 
-	id, err := seq.NextID(context.Background())
+	id, err := seq.NextID(context.Background(), "")
 
 	assert.NoError(t, err)
 	assert.Equal(t, uint64(400), id)
@@ -376,10 +376,10 @@ func TestSequence_ExternalCacheRecovery(t *testing.T) {
 
 	// 阶段1：外部缓存仍不可用，使用本地缓存
 	t.Run("外部缓存不可用时使用本地缓存", func(t *testing.T) {
-		err := localCache.FillIDs(context.Background(), []uint64{100})
+		err := localCache.FillIDs(context.Background(), "", []uint64{100})
 		assert.NoError(t, err)
 
-		id, err := seq.NextID(context.Background())
+		id, err := seq.NextID(context.Background(), "")
 
 		assert.NoError(t, err)
 		assert.Equal(t, uint64(100), id)
@@ -389,13 +389,13 @@ func TestSequence_ExternalCacheRecovery(t *testing.T) {
 	// 阶段2：外部缓存恢复，但本地缓存已用完
 	t.Run("外部缓存恢复后使用外部缓存", func(t *testing.T) {
 		// 模拟外部缓存恢复
-		mockExternalCache.EXPECT().GetSingleID(gomock.Any()).Return(uint64(200), nil).Times(1)
+		mockExternalCache.EXPECT().GetSingleID(gomock.Any(), "").Return(uint64(200), nil).Times(1)
 
 		// 手动调用检查外部缓存是否可用的方法
 		// 假设有一个内部方法会定期检查外部缓存状态
 		seq.externalCacheAvailable.Store(true)
 
-		id, err := seq.NextID(context.Background())
+		id, err := seq.NextID(context.Background(), "")
 
 		assert.NoError(t, err)
 		assert.Equal(t, uint64(200), id)
@@ -430,11 +430,11 @@ func TestSequence_BatchGetIDs(t *testing.T) {
 	// 批量获取ID并填充缓存
 	t.Run("首次批量获取ID并填充缓存", func(t *testing.T) {
 		// 外部缓存为空，从数据库获取ID批次
-		mockExternalCache.EXPECT().GetSingleID(gomock.Any()).Return(uint64(0), errorx.New(errorx.CodeNotFound, "缓存为空"))
-		mockDB.EXPECT().GetBatchIDs(gomock.Any(), uint64(10)).Return([]uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, nil)
-		mockExternalCache.EXPECT().FillIDs(gomock.Any(), []uint64{2, 3, 4, 5, 6, 7, 8, 9, 10}).Return(nil)
+		mockExternalCache.EXPECT().GetSingleID(gomock.Any(), "").Return(uint64(0), errorx.New(errorx.CodeNotFound, "缓存为空"))
+		mockDB.EXPECT().GetBatchIDs(gomock.Any(), "", uint64(10)).Return([]uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, nil)
+		mockExternalCache.EXPECT().FillIDs(gomock.Any(), "", []uint64{2, 3, 4, 5, 6, 7, 8, 9, 10}).Return(nil)
 
-		id, err := seq.NextID(context.Background())
+		id, err := seq.NextID(context.Background(), "")
 
 		assert.NoError(t, err)
 		assert.Equal(t, uint64(1), id)
@@ -442,9 +442,9 @@ func TestSequence_BatchGetIDs(t *testing.T) {
 
 	// 测试从已填充的外部缓存获取ID
 	t.Run("从已填充的外部缓存获取ID", func(t *testing.T) {
-		mockExternalCache.EXPECT().GetSingleID(gomock.Any()).Return(uint64(2), nil)
+		mockExternalCache.EXPECT().GetSingleID(gomock.Any(), "").Return(uint64(2), nil)
 
-		id, err := seq.NextID(context.Background())
+		id, err := seq.NextID(context.Background(), "")
 
 		assert.NoError(t, err)
 		assert.Equal(t, uint64(2), id)
@@ -471,7 +471,7 @@ func TestSequence_ConcurrentGetIDs(t *testing.T) {
 
 	// 设置外部缓存行为
 	mockExternalCache.EXPECT().IsOK(gomock.Any()).Return(true).AnyTimes()
-	mockExternalCache.EXPECT().GetSingleID(gomock.Any()).DoAndReturn(func(_ context.Context) (uint64, error) {
+	mockExternalCache.EXPECT().GetSingleID(gomock.Any(), "").DoAndReturn(func(_ context.Context, _ string) (uint64, error) {
 		return atomic.AddUint64(&counter, 1), nil
 	}).AnyTimes()
 
@@ -486,7 +486,7 @@ func TestSequence_ConcurrentGetIDs(t *testing.T) {
 			go func() {
 				defer wg.Done()
 				for j := 0; j < 10; j++ {
-					id, err := seq.NextID(context.Background())
+					id, err := seq.NextID(context.Background(), "")
 					assert.NoError(t, err)
 					assert.NotZero(t, id)
 				}
@@ -521,13 +521,122 @@ func TestSequence_EmptyIDsFromDatabase(t *testing.T) {
 	seq.externalCacheAvailable.Store(true)
 
 	t.Run("数据库返回空ID列表", func(t *testing.T) {
-		mockExternalCache.EXPECT().GetSingleID(gomock.Any()).Return(uint64(0), errorx.New(errorx.CodeNotFound, "缓存为空"))
-		mockDB.EXPECT().GetBatchIDs(gomock.Any(), uint64(10)).Return([]uint64{}, nil)
+		mockExternalCache.EXPECT().GetSingleID(gomock.Any(), "").Return(uint64(0), errorx.New(errorx.CodeNotFound, "缓存为空"))
+		mockDB.EXPECT().GetBatchIDs(gomock.Any(), "", uint64(10)).Return([]uint64{}, nil)
 
-		id, err := seq.NextID(context.Background())
+		id, err := seq.NextID(context.Background(), "")
 
 		assert.Error(t, err)
 		assert.Zero(t, id)
 		assert.True(t, errorx.Is(err, errorx.CodeNotFound))
 	})
 }
+
+// 测试优雅退出时归还本地ID
+func TestSequence_Release(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	newSeq := func(external cachex.SequenceCache, local cachex.LocalCache, available bool) *sequence {
+		seq := &sequence{
+			database:      databaseMock.NewMockSequenceDatabase(ctrl),
+			externalCache: external,
+			localCache:    local,
+		}
+		seq.externalCacheAvailable.Store(available)
+		seq.namespaces.Store("", struct{}{})
+		return seq
+	}
+
+	t.Run("本地缓存为空时无需归还", func(t *testing.T) {
+		mockExternalCache := cachexMock.NewMockSequenceCache(ctrl)
+		seq := newSeq(mockExternalCache, cachex.NewLocalSequenceCache(10), true)
+
+		assert.NoError(t, seq.Release(context.Background()))
+	})
+
+	t.Run("归还剩余ID到外部缓存", func(t *testing.T) {
+		mockExternalCache := cachexMock.NewMockSequenceCache(ctrl)
+		localCache := cachex.NewLocalSequenceCache(10)
+		assert.NoError(t, localCache.FillIDs(context.Background(), "", []uint64{7, 8, 9}))
+
+		mockExternalCache.EXPECT().IsOK(gomock.Any()).Return(true)
+		mockExternalCache.EXPECT().FillIDs(gomock.Any(), "", []uint64{7, 8, 9}).Return(nil)
+
+		seq := newSeq(mockExternalCache, localCache, true)
+		assert.NoError(t, seq.Release(context.Background()))
+
+		ids, err := localCache.Drain(context.Background(), "")
+		assert.NoError(t, err)
+		assert.Empty(t, ids)
+	})
+
+	t.Run("外部缓存不可用", func(t *testing.T) {
+		mockExternalCache := cachexMock.NewMockSequenceCache(ctrl)
+		localCache := cachex.NewLocalSequenceCache(10)
+		assert.NoError(t, localCache.FillIDs(context.Background(), "", []uint64{7, 8, 9}))
+
+		seq := newSeq(mockExternalCache, localCache, false)
+		err := seq.Release(context.Background())
+		assert.True(t, errorx.Is(err, errorx.CodeCacheError))
+	})
+
+	t.Run("填充外部缓存失败", func(t *testing.T) {
+		mockExternalCache := cachexMock.NewMockSequenceCache(ctrl)
+		localCache := cachex.NewLocalSequenceCache(10)
+		assert.NoError(t, localCache.FillIDs(context.Background(), "", []uint64{7}))
+
+		mockExternalCache.EXPECT().IsOK(gomock.Any()).Return(true)
+		mockExternalCache.EXPECT().FillIDs(gomock.Any(), "", []uint64{7}).Return(errorx.New(errorx.CodeCacheError, "填充失败"))
+
+		seq := newSeq(mockExternalCache, localCache, true)
+		assert.Error(t, seq.Release(context.Background()))
+	})
+}
+
+func TestFormatIDRanges(t *testing.T) {
+	assert.Equal(t, "", formatIDRanges(nil))
+	assert.Equal(t, "5", formatIDRanges([]uint64{5}))
+	assert.Equal(t, "1-3,7,9-10", formatIDRanges([]uint64{1, 2, 3, 7, 9, 10}))
+}
+
+// 测试不同命名空间使用独立的号段
+func TestSequence_Namespace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDB := databaseMock.NewMockSequenceDatabase(ctrl)
+	mockExternalCache := cachexMock.NewMockSequenceCache(ctrl)
+	localCache := cachex.NewLocalSequenceCache(100)
+
+	seq := &sequence{
+		database:      mockDB,
+		externalCache: mockExternalCache,
+		localCache:    localCache,
+		localPatch:    3,
+	}
+	seq.externalCacheAvailable.Store(false)
+
+	mockDB.EXPECT().GetBatchIDs(gomock.Any(), "brand", uint64(3)).Return([]uint64{0, 1, 2}, nil)
+	mockDB.EXPECT().GetBatchIDs(gomock.Any(), "", uint64(3)).Return([]uint64{500, 501, 502}, nil)
+
+	id, err := seq.NextID(context.Background(), "brand")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), id)
+
+	id, err = seq.NextID(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(500), id)
+
+	id, err = seq.NextID(context.Background(), "brand")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), id)
+
+	// 退出时两个命名空间的剩余ID分别归还
+	seq.externalCacheAvailable.Store(true)
+	mockExternalCache.EXPECT().IsOK(gomock.Any()).Return(true).Times(2)
+	mockExternalCache.EXPECT().FillIDs(gomock.Any(), "brand", []uint64{2}).Return(nil)
+	mockExternalCache.EXPECT().FillIDs(gomock.Any(), "", []uint64{501, 502}).Return(nil)
+
+	assert.NoError(t, seq.Release(context.Background()))
+}
@@ -4,33 +4,94 @@ package repository
 import (
 	"context"
 	"errors"
+	"github.com/zeromicro/go-zero/core/collection"
+	"github.com/zeromicro/go-zero/core/logx"
 	"github.com/zeromicro/go-zero/core/stores/cache"
 	"github.com/zeromicro/go-zero/core/stores/sqlx"
 	"shortener/internal/config"
 	"shortener/internal/model"
 	"shortener/internal/types/errorx"
+	"shortener/pkg/pubsub"
+	"time"
 )
 
 // ShortUrlMap 定义短URL映射接口
 type ShortUrlMap interface {
 	// Insert 添加一个新的URL映射
 	Insert(ctx context.Context, data *model.ShortUrlMap) error
-	// FindOneByMd5 根据MD5哈希查找URL映射
-	FindOneByMd5(ctx context.Context, md5 string) (*model.ShortUrlMap, error)
-	// FindOneByShortUrl 根据shortURL查找映射
-	FindOneByShortUrl(ctx context.Context, shortUrl string) (*model.ShortUrlMap, error)
+	// FindOneByMd5 根据命名空间和MD5哈希查找URL映射
+	FindOneByMd5(ctx context.Context, namespace, md5 string) (*model.ShortUrlMap, error)
+	// FindOneByShortUrl 根据命名空间和shortURL查找映射
+	FindOneByShortUrl(ctx context.Context, namespace, shortUrl string) (*model.ShortUrlMap, error)
+	// Update 更新URL映射
+	Update(ctx context.Context, data *model.ShortUrlMap) error
+	// UpdateCheckResult 根据data的命名空间和shortURL更新连通性检查结果和最终地址
+	UpdateCheckResult(ctx context.Context, data *model.ShortUrlMap) error
+	// Delete 根据命名空间和shortURL删除映射，只有实际删除了行时返回nil
+	Delete(ctx context.Context, namespace, shortUrl string) error
+	// RangeShortUrls 按主键顺序分批遍历全部未删除的映射，fn返回错误时终止遍历
+	RangeShortUrls(ctx context.Context, batch int, fn func(data []*model.ShortUrlMap) error) error
+	// RangeLinks 与RangeShortUrls相同，但每行包含长链接和检查结果等全部字段
+	RangeLinks(ctx context.Context, batch int, fn func(data []*model.ShortUrlMap) error) error
+	// ListByCheckStatus 按主键顺序列出命名空间内主键大于cursor且检查结果为status的映射
+	ListByCheckStatus(ctx context.Context, namespace string, status uint64, cursor uint64, limit int) ([]*model.ShortUrlMap, error)
 }
 
-// NewShortUrlMap 创建短URL映射仓库的新实例
-func NewShortUrlMap(conf config.ShortUrlConf, cacheConf cache.CacheConf) ShortUrlMap {
+// NewShortUrlMap 创建短URL映射仓库的新实例，ps用于在实例之间同步热点缓存的失效
+func NewShortUrlMap(conf config.ShortUrlConf, cacheConf cache.CacheConf, ps pubsub.PubSub) ShortUrlMap {
 	conn := sqlx.NewMysql(conf.Mysql.DSN())
-	return &shortUrlMap{
-		model: model.NewShortUrlMapModel(conn, cacheConf),
+	return newShortUrlMap(model.NewShortUrlMapModel(conn, cacheConf), conf, ps)
+}
+
+// NewSqlShortUrlMap 创建不使用Redis缓存的短URL映射仓库，conn可以是sqlite或mysql连接
+func NewSqlShortUrlMap(conn sqlx.SqlConn, conf config.ShortUrlConf, ps pubsub.PubSub) ShortUrlMap {
+	return newShortUrlMap(model.NewShortUrlMapSqlModel(conn), conf, ps)
+}
+
+// NewPostgresShortUrlMap 创建基于postgres的短URL映射仓库，不使用Redis缓存
+func NewPostgresShortUrlMap(conn sqlx.SqlConn, conf config.ShortUrlConf, ps pubsub.PubSub) ShortUrlMap {
+	return newShortUrlMap(model.NewShortUrlMapPostgresModel(conn), conf, ps)
+}
+
+// NewMemoryShortUrlMap 创建进程内的短URL映射仓库，重启后数据丢失
+func NewMemoryShortUrlMap(conf config.ShortUrlConf, ps pubsub.PubSub) ShortUrlMap {
+	return newShortUrlMap(model.NewShortUrlMapMemoryModel(), conf, ps)
+}
+
+func newShortUrlMap(m model.ShortUrlMapModel, conf config.ShortUrlConf, ps pubsub.PubSub) ShortUrlMap {
+	s := &shortUrlMap{
+		model: m,
+	}
+
+	if conf.HotCache.Enabled {
+		s.enableHotCache(conf.HotCache, ps)
 	}
+	return s
 }
 
 type shortUrlMap struct {
 	model model.ShortUrlMapModel
+
+	// 进程内的热点短链缓存，为nil时不启用
+	hot     *collection.Cache
+	hotTTL  time.Duration
+	ps      pubsub.PubSub
+	channel string
+}
+
+// enableHotCache 启用热点短链缓存，并订阅其他实例的失效通知
+func (s *shortUrlMap) enableHotCache(conf config.HotCacheConf, ps pubsub.PubSub) {
+	hot, err := collection.NewCache(conf.TTL, collection.WithLimit(conf.Limit), collection.WithName("hot-short-url"))
+	if err != nil {
+		logx.Severef("init hot link cache failed,err:%v", err)
+		return
+	}
+
+	s.hot = hot
+	s.hotTTL = conf.TTL
+	s.ps = ps
+	s.channel = conf.Channel
+	ps.Subscribe(context.Background(), conf.Channel, hot.Del)
 }
 
 // Insert 实现添加URL映射的功能
@@ -44,15 +105,175 @@ func (s *shortUrlMap) Insert(ctx context.Context, data *model.ShortUrlMap) error
 }
 
 // FindOneByMd5 实现通过MD5查找URL映射的功能
-func (s *shortUrlMap) FindOneByMd5(ctx context.Context, md5 string) (*model.ShortUrlMap, error) {
-	data, err := s.model.FindOneByMd5(ctx, md5)
+func (s *shortUrlMap) FindOneByMd5(ctx context.Context, namespace, md5 string) (*model.ShortUrlMap, error) {
+	data, err := s.model.FindOneByNamespaceMd5(ctx, namespace, md5)
 	return s.handleFindResult(ctx, data, err, "find shortUrlMap by md5 failed")
 }
 
-// FindOneByShortUrl 实现通过短URL查找映射的功能
-func (s *shortUrlMap) FindOneByShortUrl(ctx context.Context, shortUrl string) (*model.ShortUrlMap, error) {
-	data, err := s.model.FindOneByShortUrl(ctx, shortUrl)
-	return s.handleFindResult(ctx, data, err, "find shortUrlMap by shortUrl failed")
+// FindOneByShortUrl 实现通过短URL查找映射的功能，启用热点缓存时优先读取进程内缓存
+func (s *shortUrlMap) FindOneByShortUrl(ctx context.Context, namespace, shortUrl string) (*model.ShortUrlMap, error) {
+	if s.hot == nil {
+		data, err := s.model.FindOneByNamespaceShortUrl(ctx, namespace, shortUrl)
+		return s.handleFindResult(ctx, data, err, "find shortUrlMap by shortUrl failed")
+	}
+
+	key := linkKey(namespace, shortUrl)
+	if val, ok := s.hot.Get(key); ok {
+		hotCacheMetrics.lookups.WithLabelValues("hit").Inc()
+		data := *val.(*model.ShortUrlMap)
+		return &data, nil
+	}
+	hotCacheMetrics.lookups.WithLabelValues("miss").Inc()
+
+	data, err := s.model.FindOneByNamespaceShortUrl(ctx, namespace, shortUrl)
+	data, err = s.handleFindResult(ctx, data, err, "find shortUrlMap by shortUrl failed")
+	if err != nil {
+		return nil, err
+	}
+
+	s.cacheHot(key, data)
+	return data, nil
+}
+
+// Update 实现更新URL映射的功能
+func (s *shortUrlMap) Update(ctx context.Context, data *model.ShortUrlMap) error {
+	if err := s.model.Update(ctx, data); err != nil {
+		return errorx.NewWithCause(errorx.CodeDatabaseError, "update shortUrlMap failed", err).
+			WithContext(ctx).WithMeta("data", data)
+	}
+
+	s.invalidateHot(ctx, data.Namespace, data.ShortUrl)
+	return nil
+}
+
+// UpdateCheckResult 只更新检查结果相关的字段，避免覆盖并发修改的其他字段
+func (s *shortUrlMap) UpdateCheckResult(ctx context.Context, data *model.ShortUrlMap) error {
+	found, err := s.model.FindOneByNamespaceShortUrl(ctx, data.Namespace, data.ShortUrl)
+	found, err = s.handleFindResult(ctx, found, err, "find shortUrlMap by shortUrl failed")
+	if err != nil {
+		return err
+	}
+
+	result := *data
+	result.Id = found.Id
+	if err = s.model.UpdateCheckResult(ctx, &result); err != nil {
+		return errorx.NewWithCause(errorx.CodeDatabaseError, "update shortUrlMap check result failed", err).
+			WithContext(ctx).WithMeta("namespace", data.Namespace).WithMeta("shortUrl", data.ShortUrl)
+	}
+
+	s.invalidateHot(ctx, data.Namespace, data.ShortUrl)
+	return nil
+}
+
+// Delete 实现删除URL映射的功能，映射已被其他实例删除时返回NotFound
+func (s *shortUrlMap) Delete(ctx context.Context, namespace, shortUrl string) error {
+	data, err := s.model.FindOneByNamespaceShortUrl(ctx, namespace, shortUrl)
+	data, err = s.handleFindResult(ctx, data, err, "find shortUrlMap by shortUrl failed")
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.model.Remove(ctx, data.Id)
+	if err != nil {
+		return errorx.NewWithCause(errorx.CodeDatabaseError, "delete shortUrlMap failed", err).
+			WithContext(ctx).WithMeta("namespace", namespace).WithMeta("shortUrl", shortUrl)
+	}
+
+	s.invalidateHot(ctx, namespace, shortUrl)
+	if !removed {
+		return errorx.New(errorx.CodeNotFound, "the data does not exist").
+			WithMeta("namespace", namespace).WithMeta("shortUrl", shortUrl)
+	}
+	return nil
+}
+
+// RangeShortUrls 使用主键游标分页，避免大偏移量的深分页
+func (s *shortUrlMap) RangeShortUrls(ctx context.Context, batch int, fn func(data []*model.ShortUrlMap) error) error {
+	return s.rangeAfter(ctx, batch, s.model.FindShortUrlsAfter, fn)
+}
+
+// RangeLinks 使用主键游标分页读取完整的行
+func (s *shortUrlMap) RangeLinks(ctx context.Context, batch int, fn func(data []*model.ShortUrlMap) error) error {
+	return s.rangeAfter(ctx, batch, s.model.FindLinksAfter, fn)
+}
+
+// ListByCheckStatus 使用主键作为游标，cursor为上一页最后一行的主键
+func (s *shortUrlMap) ListByCheckStatus(
+	ctx context.Context,
+	namespace string,
+	status uint64,
+	cursor uint64,
+	limit int,
+) ([]*model.ShortUrlMap, error) {
+	data, err := s.model.FindByCheckStatusAfter(ctx, namespace, status, cursor, limit)
+	if err != nil {
+		return nil, errorx.NewWithCause(errorx.CodeDatabaseError, "list shortUrlMap by check status failed", err).
+			WithContext(ctx).WithMeta("status", status).WithMeta("cursor", cursor)
+	}
+	return data, nil
+}
+
+// rangeAfter 从主键0开始分批调用find，直到返回的行数少于batch
+func (s *shortUrlMap) rangeAfter(
+	ctx context.Context,
+	batch int,
+	find func(ctx context.Context, id uint64, limit int) ([]*model.ShortUrlMap, error),
+	fn func(data []*model.ShortUrlMap) error,
+) error {
+	var lastID uint64
+	for {
+		data, err := find(ctx, lastID, batch)
+		if err != nil {
+			return errorx.NewWithCause(errorx.CodeDatabaseError, "range shortUrlMap failed", err).
+				WithContext(ctx).WithMeta("lastID", lastID)
+		}
+
+		if len(data) == 0 {
+			return nil
+		}
+
+		if err = fn(data); err != nil {
+			return err
+		}
+
+		if len(data) < batch {
+			return nil
+		}
+		lastID = data[len(data)-1].Id
+	}
+}
+
+// cacheHot 写入热点缓存，缓存时间不超过短链的过期时间
+func (s *shortUrlMap) cacheHot(key string, data *model.ShortUrlMap) {
+	ttl := s.hotTTL
+	if data.ExpireAt.Valid {
+		remaining := time.Until(data.ExpireAt.Time)
+		if remaining <= 0 {
+			return
+		}
+		ttl = min(ttl, remaining)
+	}
+
+	cached := *data
+	s.hot.SetWithExpire(key, &cached, ttl)
+}
+
+// invalidateHot 删除本地热点缓存并通知其他实例
+func (s *shortUrlMap) invalidateHot(ctx context.Context, namespace, shortUrl string) {
+	if s.hot == nil {
+		return
+	}
+
+	key := linkKey(namespace, shortUrl)
+	s.hot.Del(key)
+	if err := s.ps.Publish(ctx, s.channel, key); err != nil {
+		logx.WithContext(ctx).Errorf("publish hot link invalidation failed,key:%v,err:%v", key, err)
+	}
+}
+
+// linkKey 进程内缓存的键，与命名空间一起区分相同短码
+func linkKey(namespace, shortUrl string) string {
+	return namespace + ":" + shortUrl
 }
 
 // handleFindResult 处理查询结果和错误
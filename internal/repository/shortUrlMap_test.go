@@ -0,0 +1,292 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"shortener/internal/config"
+	"shortener/internal/model"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+	"go.uber.org/mock/gomock"
+	_ "modernc.org/sqlite"
+
+	"shortener/internal/migrate"
+	"shortener/internal/types/errorx"
+	"shortener/pkg/pubsub"
+	pubsubMock "shortener/pkg/pubsub/mock"
+)
+
+func TestShortUrlMap_HotCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mr := miniredis.RunT(t)
+	cacheConf := cache.CacheConf{{RedisConf: redis.RedisConf{Host: mr.Addr(), Type: redis.NodeType}, Weight: 100}}
+
+	mockPubSub := pubsubMock.NewMockPubSub(ctrl)
+	var invalidate func(message string)
+	mockPubSub.EXPECT().Subscribe(gomock.Any(), "invalidate", gomock.Any()).
+		Do(func(_ context.Context, _ string, handler func(message string)) {
+			invalidate = handler
+		})
+
+	s := &shortUrlMap{model: model.NewShortUrlMapModel(sqlx.NewSqlConnFromDB(db), cacheConf)}
+	s.enableHotCache(config.HotCacheConf{TTL: time.Minute, Limit: 10, Channel: "invalidate"}, mockPubSub)
+
+	query := regexp.QuoteMeta("where `namespace` = ? and `short_url` = ? limit 1")
+	expectRow := func(shortUrl string, expireAt sql.NullTime) {
+		rows := sqlmock.NewRows([]string{"id", "create_at", "create_by", "update_at", "update_by", "is_del",
+			"long_url", "md5", "short_url", "expire_at", "click_count", "namespace", "final_url", "check_status", "check_code", "checked_at", "fail_count", "fallback_url", "tag", "utm"}).
+			AddRow(1, time.Now(), "op", time.Now(), "op", 0, "https://example.com", "md5", shortUrl, expireAt, 0, "", "", 0, 0, nil, 0, "", "", "")
+		mock.ExpectQuery(query).WithArgs("", shortUrl).WillReturnRows(rows)
+	}
+
+	hits := testutil.ToFloat64(hotCacheMetrics.lookups.WithLabelValues("hit"))
+
+	t.Run("热点短链命中进程内缓存", func(t *testing.T) {
+		expectRow("abc", sql.NullTime{})
+
+		data, err := s.FindOneByShortUrl(ctx, "", "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", data.LongUrl)
+
+		// 关闭Redis后仍可从进程内缓存读取
+		mr.Close()
+		data, err = s.FindOneByShortUrl(ctx, "", "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", data.LongUrl)
+
+		// 修改返回值不影响缓存
+		data.LongUrl = "changed"
+		data, err = s.FindOneByShortUrl(ctx, "", "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", data.LongUrl)
+
+		assert.Equal(t, hits+2, testutil.ToFloat64(hotCacheMetrics.lookups.WithLabelValues("hit")))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("收到失效通知后删除缓存", func(t *testing.T) {
+		invalidate(linkKey("", "abc"))
+
+		_, ok := s.hot.Get(linkKey("", "abc"))
+		assert.False(t, ok)
+	})
+
+	t.Run("本地失效并通知其他实例", func(t *testing.T) {
+		s.cacheHot(linkKey("", "xyz"), &model.ShortUrlMap{ShortUrl: "xyz"})
+		mockPubSub.EXPECT().Publish(gomock.Any(), "invalidate", ":xyz").Return(nil)
+
+		s.invalidateHot(ctx, "", "xyz")
+
+		_, ok := s.hot.Get(linkKey("", "xyz"))
+		assert.False(t, ok)
+	})
+
+	t.Run("已过期的短链不缓存", func(t *testing.T) {
+		expired := &model.ShortUrlMap{ExpireAt: sql.NullTime{Time: time.Now().Add(-time.Second), Valid: true}}
+		s.cacheHot(linkKey("", "old"), expired)
+
+		_, ok := s.hot.Get(linkKey("", "old"))
+		assert.False(t, ok)
+	})
+}
+
+// 所有存储后端的短链映射仓库行为一致
+func TestShortUrlMap_Backends(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	assert.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	m, err := migrate.NewMigrator(sqlx.NewSqlConnFromDB(db), config.StorageDriverSqlite, migrate.TargetShortUrlMap)
+	assert.NoError(t, err)
+	_, err = m.Up(ctx)
+	assert.NoError(t, err)
+
+	backends := map[string]ShortUrlMap{
+		"sqlite": NewSqlShortUrlMap(sqlx.NewSqlConnFromDB(db), config.ShortUrlConf{}, pubsub.NewMemoryPubSub()),
+		"memory": NewMemoryShortUrlMap(config.ShortUrlConf{}, pubsub.NewMemoryPubSub()),
+	}
+
+	for name, s := range backends {
+		t.Run(name, func(t *testing.T) {
+			expireAt := sql.NullTime{Time: time.Now().Add(time.Hour).Truncate(time.Second).UTC(), Valid: true}
+			for i, code := range []string{"a", "b", "c"} {
+				err := s.Insert(ctx, &model.ShortUrlMap{
+					LongUrl:  "https://example.com/" + code,
+					Md5:      "md5-" + code,
+					ShortUrl: code,
+					ExpireAt: expireAt,
+					CreateBy: "op",
+					UpdateBy: "op",
+					FinalUrl: "https://www.example.com/" + code,
+				})
+				assert.NoError(t, err, i)
+			}
+
+			// 命名空间内短链唯一
+			err := s.Insert(ctx, &model.ShortUrlMap{LongUrl: "https://example.com/x", Md5: "md5-x", ShortUrl: "a"})
+			assert.True(t, errorx.Is(err, errorx.CodeDatabaseError))
+
+			// 不同命名空间可以使用相同短链
+			err = s.Insert(ctx, &model.ShortUrlMap{Namespace: "brand", LongUrl: "https://example.com/a", Md5: "md5-a", ShortUrl: "a"})
+			assert.NoError(t, err)
+
+			data, err := s.FindOneByShortUrl(ctx, "", "b")
+			assert.NoError(t, err)
+			assert.Equal(t, "https://example.com/b", data.LongUrl)
+			assert.Equal(t, "https://www.example.com/b", data.FinalUrl)
+			assert.True(t, data.ExpireAt.Valid)
+			assert.True(t, expireAt.Time.Equal(data.ExpireAt.Time))
+
+			data, err = s.FindOneByMd5(ctx, "brand", "md5-a")
+			assert.NoError(t, err)
+			assert.Equal(t, "brand", data.Namespace)
+
+			_, err = s.FindOneByMd5(ctx, "brand", "md5-b")
+			assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+
+			data, err = s.FindOneByShortUrl(ctx, "", "c")
+			assert.NoError(t, err)
+			data.LongUrl = "https://example.com/updated"
+			assert.NoError(t, s.Update(ctx, data))
+
+			data, err = s.FindOneByShortUrl(ctx, "", "c")
+			assert.NoError(t, err)
+			assert.Equal(t, "https://example.com/updated", data.LongUrl)
+
+			// 只更新检查结果，其他字段保持不变
+			checkedAt := sql.NullTime{Time: time.Now().Truncate(time.Second).UTC(), Valid: true}
+			err = s.UpdateCheckResult(ctx, &model.ShortUrlMap{ShortUrl: "c", LongUrl: "ignored", FinalUrl: "https://www.example.com/final",
+				CheckStatus: model.CheckStatusUnreachable, CheckCode: 503, CheckedAt: checkedAt, FailCount: 2, FallbackUrl: "ignored"})
+			assert.NoError(t, err)
+
+			data, err = s.FindOneByShortUrl(ctx, "", "c")
+			assert.NoError(t, err)
+			assert.Equal(t, "https://example.com/updated", data.LongUrl)
+			assert.Equal(t, "https://www.example.com/final", data.FinalUrl)
+			assert.Equal(t, model.CheckStatusUnreachable, data.CheckStatus)
+			assert.Equal(t, int64(503), data.CheckCode)
+			assert.True(t, checkedAt.Time.Equal(data.CheckedAt.Time))
+			assert.Equal(t, uint64(2), data.FailCount)
+			assert.Empty(t, data.FallbackUrl)
+
+			links, err := s.ListByCheckStatus(ctx, "", model.CheckStatusUnreachable, 0, 10)
+			assert.NoError(t, err)
+			if assert.Len(t, links, 1) {
+				assert.Equal(t, "c", links[0].ShortUrl)
+				assert.Equal(t, "https://example.com/updated", links[0].LongUrl)
+
+				links, err = s.ListByCheckStatus(ctx, "", model.CheckStatusUnreachable, links[0].Id, 10)
+				assert.NoError(t, err)
+				assert.Empty(t, links)
+			}
+			links, err = s.ListByCheckStatus(ctx, "brand", model.CheckStatusUnreachable, 0, 10)
+			assert.NoError(t, err)
+			assert.Empty(t, links)
+
+			err = s.UpdateCheckResult(ctx, &model.ShortUrlMap{ShortUrl: "missing"})
+			assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+
+			assert.NoError(t, s.Delete(ctx, "", "b"))
+			_, err = s.FindOneByShortUrl(ctx, "", "b")
+			assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+
+			var codes []string
+			err = s.RangeShortUrls(ctx, 2, func(data []*model.ShortUrlMap) error {
+				for _, d := range data {
+					codes = append(codes, d.Namespace+"/"+d.ShortUrl)
+				}
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"/a", "/c", "brand/a"}, codes)
+
+			var longUrls []string
+			err = s.RangeLinks(ctx, 2, func(data []*model.ShortUrlMap) error {
+				for _, d := range data {
+					longUrls = append(longUrls, d.LongUrl)
+				}
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"https://example.com/a", "https://example.com/updated", "https://example.com/a"}, longUrls)
+		})
+	}
+}
+
+func TestShortUrlMap_Postgres(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := NewPostgresShortUrlMap(sqlx.NewSqlConnFromDB(db), config.ShortUrlConf{}, pubsub.NewMemoryPubSub())
+
+	columns := []string{"id", "create_at", "create_by", "update_at", "update_by", "is_del",
+		"long_url", "md5", "short_url", "expire_at", "click_count", "namespace", "final_url", "check_status", "check_code", "checked_at", "fail_count", "fallback_url", "tag", "utm"}
+
+	t.Run("使用postgres占位符查询", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`from "short_url_map" where namespace = $1 and short_url = $2 limit 1`)).
+			WithArgs("brand", "abc").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(7, time.Now(), "op", time.Now(), "op", 0, "https://example.com", "md5", "abc", nil, 0, "brand", "", 0, 0, nil, 0, "", "", ""))
+
+		data, err := s.FindOneByShortUrl(ctx, "brand", "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(7), data.Id)
+		assert.Equal(t, "https://example.com", data.LongUrl)
+	})
+
+	t.Run("不存在时返回NotFound", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`where namespace = $1 and md5 = $2 limit 1`)).
+			WithArgs("", "missing").
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := s.FindOneByMd5(ctx, "", "missing")
+		assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+	})
+
+	t.Run("更新时主键为第一个参数", func(t *testing.T) {
+		data := &model.ShortUrlMap{Id: 7, CreateBy: "op", UpdateBy: "op", LongUrl: "https://example.com/new",
+			Md5: "md5", ShortUrl: "abc", Namespace: "brand", FinalUrl: "https://www.example.com/new"}
+		mock.ExpectExec(regexp.QuoteMeta(`update "short_url_map" set create_by = $2, update_by = $3, is_del = $4, long_url = $5, md5 = $6, short_url = $7, expire_at = $8, click_count = $9, namespace = $10, final_url = $11, check_status = $12, check_code = $13, checked_at = $14, fail_count = $15, fallback_url = $16, tag = $17, utm = $18 where id = $1`)).
+			WithArgs(uint64(7), "op", "op", uint64(0), "https://example.com/new", "md5", "abc", sql.NullTime{}, uint64(0), "brand", "https://www.example.com/new", uint64(0), int64(0), sql.NullTime{}, uint64(0), "", "", "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, s.Update(ctx, data))
+	})
+
+	t.Run("其他实例已删除时返回NotFound", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`where namespace = $1 and short_url = $2 limit 1`)).
+			WithArgs("brand", "abc").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(7, time.Now(), "op", time.Now(), "op", 0, "https://example.com", "md5", "abc", nil, 0, "brand", "", 0, 0, nil, 0, "", "", ""))
+		mock.ExpectExec(regexp.QuoteMeta(`delete from "short_url_map" where id = $1`)).
+			WithArgs(uint64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := s.Delete(ctx, "brand", "abc")
+		assert.True(t, errorx.Is(err, errorx.CodeNotFound))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
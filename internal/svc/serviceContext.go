@@ -1,25 +1,37 @@
 package svc
 
 import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/zeromicro/go-zero/core/limit"
 	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/proc"
+	"github.com/zeromicro/go-zero/core/stores/cache"
 	"github.com/zeromicro/go-zero/core/stores/redis"
-	"github.com/zeromicro/go-zero/core/stores/sqlx"
 	"github.com/zeromicro/go-zero/rest"
 	"shortener/internal/config"
 	"shortener/internal/middleware"
 	"shortener/internal/repository"
 	"shortener/internal/repository/cachex"
-	"shortener/internal/repository/database"
 	"shortener/internal/types/errorx"
+	"shortener/pkg/domainRule"
 	"shortener/pkg/filter"
+	shortenerlimit "shortener/pkg/limit"
+	"shortener/pkg/pubsub"
 	"shortener/pkg/sensitive"
+	"shortener/pkg/threat"
+	"time"
 )
 
 const (
 	sensitiveWordsPath = "assets/sensitiveWords.txt"
 	similarCharsPath   = "assets/similarChars.txt"
 	replaceRulesPath   = "assets/replaceRules.txt"
+
+	// 优雅退出时归还本地ID的超时时间，需小于go-zero强制退出等待时间
+	sequenceReleaseTimeout = 3 * time.Second
+	// 优雅退出时持久化热点短链的超时时间
+	hotLinksSaveTimeout = time.Second
 )
 
 type ServiceContext struct {
@@ -28,26 +40,41 @@ type ServiceContext struct {
 	ShortUrlMapRepository repository.ShortUrlMap
 	ShortCodeFilter       filter.Filter
 	SensitiveFilter       sensitive.Filter
+	HotLinks              repository.HotLinks
+	DomainRules           domainRule.Rules
+	ThreatMatcher         threat.Matcher // 未开启时为nil
 
 	Limit rest.Middleware
 }
 
 func NewServiceContext(c config.Config) *ServiceContext {
-	// 创建MySQL连接
-	sequenceDB := sqlx.NewMysql(c.Sequence.Mysql.DSN())
+	// 创建数据库连接
+	shortUrlMapDB, sequenceDB := newStorageConns(c)
+	// 单机模式没有单独的部署步骤，总是在启动时迁移
+	if c.Storage.AutoMigrate || c.Standalone {
+		autoMigrate(c, shortUrlMapDB, sequenceDB)
+	}
 
-	// 创建Redis连接
-	sequenceRedis := newRedis(c.Sequence.Redis)
+	// 创建Redis连接，单机模式不连接Redis
+	var sequenceRedis *redis.Redis
+	if !c.Standalone {
+		sequenceRedis = newRedis(c.Sequence.Redis)
+	}
 
 	// 创建数据库访问层
-	sequenceDatabase := database.NewMysqlSequenceDatabase(sequenceDB)
+	sequenceDatabase := newSequenceDatabase(c, sequenceDB, sequenceRedis)
 
-	// 创建缓存层
-	redisCache := cachex.NewRedisSequenceCache(
-		sequenceRedis,
-		c.Sequence.KeySequenceID,
-		c.Sequence.KeySequenceState,
-	)
+	// 创建缓存层，单机模式的外部缓存也在进程内，容量与批量大小一致
+	var externalCache cachex.SequenceCache
+	if c.Standalone {
+		externalCache = cachex.NewLocalSequenceCache(int(c.Sequence.CachePatch))
+	} else {
+		externalCache = cachex.NewRedisSequenceCache(
+			sequenceRedis,
+			c.Sequence.KeySequenceID,
+			c.Sequence.KeySequenceState,
+		)
+	}
 	localCache := cachex.NewLocalSequenceCache(c.Sequence.LocalCapacity)
 
 	// 创建序列生成器
@@ -60,9 +87,36 @@ func NewServiceContext(c config.Config) *ServiceContext {
 		LocalPatch:     c.Sequence.LocalPatch,
 	}
 
-	// 初始化限流器
-	limitRedis := newRedis(c.Limit.Redis)
-	tokenLimiter := limit.NewTokenLimiter(c.Limit.Rate, c.Limit.Burst, limitRedis, c.Limit.Key)
+	sequenceRepository := repository.NewSequence(
+		sequenceDatabase,
+		externalCache,
+		localCache,
+		sequenceOpts,
+	)
+
+	// 注册序列生成器监控指标
+	repository.RegisterMetrics(prometheus.DefaultRegisterer, sequenceRepository)
+
+	// 优雅退出时将本地未使用的ID归还到Redis，单机模式的外部缓存随进程退出，无需归还
+	if !c.Standalone {
+		proc.AddShutdownListener(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), sequenceReleaseTimeout)
+			defer cancel()
+
+			if err := sequenceRepository.Release(ctx); err != nil {
+				logx.Errorf("release unused sequence ids failed,err:%v", err)
+			}
+		})
+	}
+
+	// 初始化限流器，单机模式只在进程内限流
+	var limiter shortenerlimit.Limit
+	if c.Standalone {
+		limiter = shortenerlimit.NewLocalLimit(c.Limit.Rate, c.Limit.Burst)
+	} else {
+		limitRedis := newRedis(c.Limit.Redis)
+		limiter = limit.NewTokenLimiter(c.Limit.Rate, c.Limit.Burst, limitRedis, c.Limit.Key)
+	}
 
 	//初始化敏感词过滤器
 	f, err := sensitive.NewFilter(sensitiveWordsPath, similarCharsPath, replaceRulesPath)
@@ -71,19 +125,108 @@ func NewServiceContext(c config.Config) *ServiceContext {
 		logx.Severef("get sensitive words filter failed,err:%v", err)
 	}
 
+	// 创建短链映射仓库，进程内缓存通过CacheRedis的发布订阅在实例之间失效，单机模式只需通知当前进程
+	var cachePubSub pubsub.PubSub
+	if c.Standalone {
+		cachePubSub = pubsub.NewMemoryPubSub()
+	} else {
+		cachePubSub = pubsub.NewRedisPubSub(newCacheRedisConf(c.CacheRedis))
+	}
+	// 删除映射时同步移除过滤器中的短码
+	shortCodeFilter := newShortCodeFilter(c)
+	shortUrlMapRepository := repository.NewFilteredShortUrlMap(
+		newShortUrlMapRepository(c, shortUrlMapDB, cachePubSub),
+		shortCodeFilter,
+	)
+	if c.ShortUrlMap.NegativeCache.Enabled {
+		shortUrlMapRepository = repository.NewNegativeCacheShortUrlMap(
+			shortUrlMapRepository,
+			c.ShortUrlMap.NegativeCache,
+			cachePubSub,
+		)
+	}
+
+	// 加载目标域名规则，规则保存在CacheRedis中，管理接口修改后通过发布订阅通知其他实例重新加载，
+	// 单机模式使用规则文件
+	var domainRules domainRule.Rules
+	if c.Standalone {
+		domainRules, err = domainRule.NewRules(c.DomainRule)
+	} else {
+		domainRules, err = domainRule.NewRedisRules(c.DomainRule, newRedis(newCacheRedisConf(c.CacheRedis)))
+	}
+	if err != nil {
+		logx.Severef("load domain rules failed,err:%v", err)
+	}
+	domainRules = domainRule.NewSyncedRules(domainRules, cachePubSub, c.DomainRule.Channel)
+
+	// 加载恶意链接列表，列表文件由外部任务同步，修改后定期重新加载
+	var threatMatcher threat.Matcher
+	if c.Threat.Enabled {
+		threatMatcher, err = threat.NewMatcher(c.Threat)
+		if err != nil {
+			logx.Severef("load threat lists failed,err:%v", err)
+		}
+	}
+
+	// 统计热点短链，退出时持久化供下一批实例预热，单机模式没有持久化的位置
+	var hotLinks repository.HotLinks
+	if c.WarmUp.Enabled && !c.Standalone {
+		hotLinks = repository.NewHotLinks(newRedis(newCacheRedisConf(c.CacheRedis)), c.WarmUp)
+		proc.AddShutdownListener(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), hotLinksSaveTimeout)
+			defer cancel()
+
+			if err := hotLinks.Save(ctx); err != nil {
+				logx.Errorf("save hot links failed,err:%v", err)
+			}
+		})
+	}
+
 	return &ServiceContext{
 		Config:                c,
-		ShortUrlMapRepository: repository.NewShortUrlMap(c.ShortUrlMap, c.CacheRedis),
-		SequenceRepository: repository.NewSequence(
-			sequenceDatabase,
-			redisCache,
-			localCache,
-			sequenceOpts,
-		),
-		ShortCodeFilter: filter.NewBloomFilter(c.ShortUrlFilter),
-		SensitiveFilter: f,
-
-		Limit: middleware.NewLimitMiddleware(tokenLimiter).Handle,
+		ShortUrlMapRepository: shortUrlMapRepository,
+		SequenceRepository:    sequenceRepository,
+		ShortCodeFilter:       shortCodeFilter,
+		SensitiveFilter:       f,
+		HotLinks:              hotLinks,
+		DomainRules:           domainRules,
+		ThreatMatcher:         threatMatcher,
+
+		Limit: middleware.NewLimitMiddleware(limiter).Handle,
+	}
+}
+
+// newShortCodeFilter 创建短码过滤器，单机模式使用进程内的位图，启动时从存储重建
+func newShortCodeFilter(c config.Config) filter.Filter {
+	conf := c.ShortUrlFilter
+	if c.Standalone {
+		return filter.NewMemoryFilter(conf)
+	}
+
+	f := filter.NewBloomFilter(conf)
+	if conf.Replica {
+		f = filter.NewReplicaFilter(f, conf, pubsub.NewRedisPubSub(conf.Redis))
+	}
+
+	if !conf.FailOpen {
+		return f
+	}
+
+	filter.RegisterMetrics(prometheus.DefaultRegisterer)
+	return filter.NewFailOpenFilter(f, "short-code-filter")
+}
+
+// newCacheRedisConf 使用CacheRedis的第一个节点承载发布订阅和热点短链列表
+func newCacheRedisConf(conf cache.CacheConf) config.RedisConf {
+	if len(conf) == 0 {
+		logx.Severef("cache redis is not configured")
+		return config.RedisConf{}
+	}
+
+	return config.RedisConf{
+		Addr:     conf[0].Host,
+		Password: conf[0].Pass,
+		Type:     conf[0].Type,
 	}
 }
 
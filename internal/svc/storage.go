@@ -0,0 +1,168 @@
+package svc
+
+import (
+	"context"
+	"database/sql"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/stores/postgres"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+	_ "modernc.org/sqlite"
+	"shortener/internal/config"
+	"shortener/internal/migrate"
+	"shortener/internal/repository"
+	"shortener/internal/repository/database"
+	"shortener/internal/types/errorx"
+	"shortener/pkg/pubsub"
+)
+
+const (
+	// defaultSqliteDSN 未配置Storage.DSN时使用的sqlite数据库文件
+	defaultSqliteDSN = "shortener.db"
+	sqliteDriverName = "sqlite"
+)
+
+// sqlitePragmas 打开sqlite数据库后执行的语句
+var sqlitePragmas = []string{
+	`PRAGMA journal_mode = WAL`,
+	`PRAGMA busy_timeout = 5000`,
+}
+
+// newStorageConns 根据存储后端创建短链映射和序号使用的数据库连接，
+// postgres和sqlite的两者共用同一个数据库，memory不需要连接
+func newStorageConns(c config.Config) (shortUrlMap, sequence sqlx.SqlConn) {
+	switch c.Storage.DriverOf() {
+	case config.StorageDriverPostgres:
+		conn := postgres.New(c.Storage.DSN)
+		return conn, conn
+	case config.StorageDriverSqlite:
+		conn := newSqliteConn(c.Storage.DSN)
+		return conn, conn
+	case config.StorageDriverMemory:
+		return nil, nil
+	}
+
+	return sqlx.NewMysql(c.ShortUrlMap.Mysql.DSN()), sqlx.NewMysql(c.Sequence.Mysql.DSN())
+}
+
+// newSqliteConn 打开sqlite数据库，打开失败时退出
+func newSqliteConn(dsn string) sqlx.SqlConn {
+	if len(dsn) == 0 {
+		dsn = defaultSqliteDSN
+	}
+
+	db, err := sql.Open(sqliteDriverName, dsn)
+	if err != nil {
+		logx.Must(errorx.NewWithCause(errorx.CodeDatabaseError, "open sqlite database failed", err).
+			WithMeta("dsn", dsn))
+	}
+
+	// sqlite同一时间只允许一个写事务，使用单个连接避免SQLITE_BUSY，:memory:数据库也只在单个连接内可见
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range sqlitePragmas {
+		if _, err = db.Exec(stmt); err != nil {
+			err = errorx.NewWithCause(errorx.CodeDatabaseError, "init sqlite database failed", err)
+			logx.Severef("init sqlite failed,dsn:%v,err:%v", dsn, err)
+		}
+	}
+
+	return sqlx.NewSqlConnFromDB(db)
+}
+
+// NewMigrators 创建存储后端各数据库的迁移器，mysql的短链映射和序号可能位于不同的数据库，memory没有需要迁移的结构
+func NewMigrators(c config.Config) ([]migrate.Migrator, error) {
+	shortUrlMapDB, sequenceDB := newStorageConns(c)
+	return newMigrators(c, shortUrlMapDB, sequenceDB)
+}
+
+func newMigrators(c config.Config, shortUrlMapDB, sequenceDB sqlx.SqlConn) ([]migrate.Migrator, error) {
+	driver := c.Storage.DriverOf()
+	switch driver {
+	case config.StorageDriverMemory:
+		return nil, nil
+	case config.StorageDriverMysql:
+		shortUrlMap, err := migrate.NewMigrator(shortUrlMapDB, driver, migrate.TargetShortUrlMap)
+		if err != nil {
+			return nil, err
+		}
+		sequence, err := migrate.NewMigrator(sequenceDB, driver, migrate.TargetSequence)
+		if err != nil {
+			return nil, err
+		}
+		return []migrate.Migrator{sequence, shortUrlMap}, nil
+	}
+
+	m, err := migrate.NewMigrator(shortUrlMapDB, driver, migrate.TargetSequence, migrate.TargetShortUrlMap)
+	if err != nil {
+		return nil, err
+	}
+	return []migrate.Migrator{m}, nil
+}
+
+// autoMigrate 启动时执行未执行的结构迁移，失败时退出
+func autoMigrate(c config.Config, shortUrlMapDB, sequenceDB sqlx.SqlConn) {
+	migrators, err := newMigrators(c, shortUrlMapDB, sequenceDB)
+	logx.Must(err)
+
+	for _, m := range migrators {
+		_, err = m.Up(context.Background())
+		logx.Must(err)
+	}
+}
+
+// newShortUrlMapRepository 根据存储后端创建短链映射仓库，只有mysql使用go-zero的缓存模型，单机模式没有CacheRedis也不使用
+func newShortUrlMapRepository(c config.Config, conn sqlx.SqlConn, ps pubsub.PubSub) repository.ShortUrlMap {
+	switch c.Storage.DriverOf() {
+	case config.StorageDriverPostgres:
+		return repository.NewPostgresShortUrlMap(conn, c.ShortUrlMap, ps)
+	case config.StorageDriverSqlite:
+		return repository.NewSqlShortUrlMap(conn, c.ShortUrlMap, ps)
+	case config.StorageDriverMemory:
+		return repository.NewMemoryShortUrlMap(c.ShortUrlMap, ps)
+	}
+
+	if c.Standalone {
+		return repository.NewSqlShortUrlMap(conn, c.ShortUrlMap, ps)
+	}
+	return repository.NewShortUrlMap(c.ShortUrlMap, c.CacheRedis, ps)
+}
+
+// newSequenceDatabase 根据配置的ID分配后端创建序号数据库，后端配置无效时退出，
+// 不回退到存储后端，避免多个实例使用不同的ID空间分配出重复的短码
+func newSequenceDatabase(c config.Config, conn sqlx.SqlConn, rdb *redis.Redis) database.SequenceDatabase {
+	conf := c.Sequence
+	switch conf.Backend {
+	case config.SequenceBackendMultiStub:
+		if c.Storage.DriverOf() != config.StorageDriverMysql {
+			logx.Must(errorx.New(errorx.CodeParamError, "multi-stub sequence backend requires mysql storage").
+				WithMeta("driver", c.Storage.DriverOf()))
+		}
+		db, err := database.NewMysqlMultiStubSequenceDatabase(conn, conf.Stubs)
+		logx.Must(err)
+		return db
+	case config.SequenceBackendRedis:
+		if rdb == nil {
+			logx.Must(errorx.New(errorx.CodeParamError, "redis sequence backend is unavailable in standalone mode"))
+		}
+		// 计数器从sequence表已发放的ID之后开始，memory存储没有sequence表
+		if conn != nil {
+			logx.Must(database.SeedRedisSequence(context.Background(), rdb, conf.KeyCounter, conn))
+		}
+		return database.NewRedisSequenceDatabase(rdb, conf.KeyCounter)
+	case config.SequenceBackendSnowflake:
+		db, err := database.NewSnowflakeSequenceDatabase(conf.WorkerID)
+		logx.Must(err)
+		return db
+	}
+
+	switch c.Storage.DriverOf() {
+	case config.StorageDriverPostgres:
+		return database.NewPostgresSequenceDatabase(conn)
+	case config.StorageDriverSqlite:
+		return database.NewSqliteSequenceDatabase(conn)
+	case config.StorageDriverMemory:
+		return database.NewMemorySequenceDatabase()
+	}
+	return database.NewMysqlSequenceDatabase(conn)
+}
@@ -3,8 +3,38 @@
 
 package types
 
+type BrokenLink struct {
+	ShortCode   string `json:"short_code"`
+	LongUrl     string `json:"long_url"`
+	FallbackUrl string `json:"fallback_url,optional"`
+	FailCount   uint64 `json:"fail_count"`
+	StatusCode  int64  `json:"status_code"`
+	CheckedAt   string `json:"checked_at,optional"`
+}
+
+type DomainRulesRequest struct {
+	Rules []string `json:"rules" validate:"required,min=1,max=1000,dive,required,max=255"`
+}
+
+type DomainRulesResponse struct {
+	Mode  string   `json:"mode"`
+	Rules []string `json:"rules"`
+}
+
+type ListBrokenRequest struct {
+	Domain string `form:"domain,optional"`
+	Cursor uint64 `form:"cursor,optional"`
+	Limit  int    `form:"limit,default=20" validate:"min=1,max=100"`
+}
+
+type ListBrokenResponse struct {
+	Links      []BrokenLink `json:"links"`
+	NextCursor uint64       `json:"next_cursor,optional"`
+}
+
 type ResolveRequest struct {
 	ShortCode string `path:"short_code" validate:"required,validShortUrl"`
+	Domain    string `header:"X-Forwarded-Host,optional"`
 }
 
 type ResolveResponse struct {
@@ -13,9 +43,16 @@ type ResolveResponse struct {
 }
 
 type ShortenRequest struct {
-	LongUrl string `json:"long_url" validate:"required,max=2048,validLongUrl"`
+	LongUrl     string `json:"long_url" validate:"required,max=2048,validLongUrl"`
+	Domain      string `json:"domain,optional"`
+	FallbackUrl string `json:"fallback_url,optional" validate:"omitempty,max=2048,validLongUrl"`
+	Tag         string `json:"tag,optional" validate:"max=64"`
+	UtmSource   string `json:"utm_source,optional" validate:"max=128"`
+	UtmMedium   string `json:"utm_medium,optional" validate:"max=128"`
+	UtmCampaign string `json:"utm_campaign,optional" validate:"max=128"`
 }
 
 type ShortenResponse struct {
 	ShortCode string `json:"short_code"`
+	FinalUrl  string `json:"final_url,optional"`
 }
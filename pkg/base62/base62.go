@@ -2,10 +2,16 @@ package base62
 
 import (
 	"github.com/zeromicro/go-zero/core/logx"
+	"math"
 	"os"
+	"shortener/internal/types/errorx"
+	"strings"
 	"sync"
 )
 
+// MaxCodeLen 短码（包含校验字符）的最大长度，与short_url列的长度一致
+const MaxCodeLen = 11
+
 const defaultBase62Str = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 const base62EnvKey = "BASE62STR"
 
@@ -35,6 +41,33 @@ func Convert(number uint64) string {
 	return string(result)
 }
 
+// Decode 将Base62字符串还原为数字
+func Decode(s string) (uint64, error) {
+	once.Do(initBase62Str)
+
+	if len(s) == 0 {
+		return 0, errorx.New(errorx.CodeParamError, "base62 string is empty")
+	}
+
+	var number uint64
+	for i := 0; i < len(s); i++ {
+		index := strings.IndexByte(base62Str, s[i])
+		if index < 0 {
+			return 0, errorx.New(errorx.CodeParamError, "invalid base62 character").
+				WithMeta("char", string(s[i]))
+		}
+
+		// 防止溢出
+		if number > (math.MaxUint64-uint64(index))/62 {
+			return 0, errorx.New(errorx.CodeParamError, "base62 string overflows uint64").
+				WithMeta("str", s)
+		}
+		number = number*62 + uint64(index)
+	}
+
+	return number, nil
+}
+
 func initBase62Str() {
 	base62Str = os.Getenv(base62EnvKey)
 	if base62Str == "" {
@@ -103,3 +103,69 @@ func TestInitBase62StrDuplicateChars(t *testing.T) {
 func TestHasDuplicateChars(t *testing.T) {
 	// ... 保持原有测试用例不变 ...
 }
+
+func TestDecode(t *testing.T) {
+	os.Unsetenv(base62EnvKey)
+	once = sync.Once{}
+
+	for _, n := range []uint64{0, 1, 61, 62, 12345, 1<<63 + 7} {
+		got, err := Decode(Convert(n))
+		assert.NoError(t, err)
+		assert.Equal(t, n, got)
+	}
+
+	_, err := Decode("")
+	assert.Error(t, err)
+
+	_, err = Decode("ab-c")
+	assert.Error(t, err)
+
+	_, err = Decode("zzzzzzzzzzzzzz")
+	assert.Error(t, err)
+}
+
+func TestCheckCode(t *testing.T) {
+	os.Unsetenv(base62EnvKey)
+	once = sync.Once{}
+
+	t.Run("生成的校验码可以通过校验", func(t *testing.T) {
+		for _, n := range []uint64{0, 1, 62, 12345, 987654321} {
+			code := AppendCheck(Convert(n))
+			assert.True(t, ValidCheck(code), code)
+			assert.Equal(t, Convert(n), StripCheck(code))
+		}
+	})
+
+	t.Run("单字符错误被拒绝", func(t *testing.T) {
+		code := AppendCheck(Convert(987654321))
+		for i := 0; i < len(code); i++ {
+			for j := 0; j < len(base62Str); j++ {
+				if base62Str[j] == code[i] {
+					continue
+				}
+				typo := code[:i] + string(base62Str[j]) + code[i+1:]
+				assert.False(t, ValidCheck(typo), typo)
+			}
+		}
+	})
+
+	t.Run("相邻字符换位被拒绝", func(t *testing.T) {
+		code := AppendCheck(Convert(987654321))
+		for i := 0; i < len(code)-1; i++ {
+			if code[i] == code[i+1] {
+				continue
+			}
+			swapped := code[:i] + string(code[i+1]) + string(code[i]) + code[i+2:]
+			if swapped == code {
+				continue
+			}
+			assert.False(t, ValidCheck(swapped), swapped)
+		}
+	})
+
+	t.Run("过短或非法字符", func(t *testing.T) {
+		assert.False(t, ValidCheck(""))
+		assert.False(t, ValidCheck("a"))
+		assert.False(t, ValidCheck("a-b"))
+	})
+}
@@ -0,0 +1,66 @@
+package base62
+
+import "strings"
+
+// 校验位采用 Luhn mod N 算法（N=62），字符集与 Convert 使用的 Base62 字符集保持一致。
+// 该算法能够检测出所有单字符输入错误以及绝大多数相邻字符换位错误。
+
+// AppendCheck 在短码末尾追加一位校验字符
+func AppendCheck(code string) string {
+	once.Do(initBase62Str)
+
+	return code + string(base62Str[checkIndex(code)])
+}
+
+// ValidCheck 校验短码末尾的校验字符是否正确
+func ValidCheck(code string) bool {
+	once.Do(initBase62Str)
+
+	if len(code) < 2 {
+		return false
+	}
+
+	body, check := code[:len(code)-1], code[len(code)-1]
+	return strings.IndexByte(base62Str, check) == checkIndex(body) && isBase62(body)
+}
+
+// StripCheck 去除短码末尾的校验字符
+func StripCheck(code string) string {
+	if len(code) == 0 {
+		return code
+	}
+	return code[:len(code)-1]
+}
+
+// checkIndex 计算校验字符在字符集中的位置
+func checkIndex(code string) int {
+	const n = 62
+
+	factor := 2
+	sum := 0
+
+	// 从右向左遍历，交替乘以2和1
+	for i := len(code) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(base62Str, code[i])
+		addend = addend/n + addend%n
+		sum += addend
+
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+	}
+
+	return (n - sum%n) % n
+}
+
+// isBase62 判断字符串是否只包含字符集内的字符
+func isBase62(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(base62Str, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: rules.go
+//
+// Generated by this command:
+//
+//	mockgen -source=rules.go -destination=./mock/rules_mock.go -package=domainRule
+//
+
+// Package domainRule is a generated GoMock package.
+package domainRule
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRules is a mock of Rules interface.
+type MockRules struct {
+	ctrl     *gomock.Controller
+	recorder *MockRulesMockRecorder
+	isgomock struct{}
+}
+
+// MockRulesMockRecorder is the mock recorder for MockRules.
+type MockRulesMockRecorder struct {
+	mock *MockRules
+}
+
+// NewMockRules creates a new mock instance.
+func NewMockRules(ctrl *gomock.Controller) *MockRules {
+	mock := &MockRules{ctrl: ctrl}
+	mock.recorder = &MockRulesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRules) EXPECT() *MockRulesMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockRules) Add(ctx context.Context, rules []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", ctx, rules)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockRulesMockRecorder) Add(ctx, rules any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockRules)(nil).Add), ctx, rules)
+}
+
+// Allowed mocks base method.
+func (m *MockRules) Allowed(host string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Allowed", host)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Allowed indicates an expected call of Allowed.
+func (mr *MockRulesMockRecorder) Allowed(host any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Allowed", reflect.TypeOf((*MockRules)(nil).Allowed), host)
+}
+
+// List mocks base method.
+func (m *MockRules) List() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// List indicates an expected call of List.
+func (mr *MockRulesMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockRules)(nil).List))
+}
+
+// Reload mocks base method.
+func (m *MockRules) Reload(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reload", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reload indicates an expected call of Reload.
+func (mr *MockRulesMockRecorder) Reload(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reload", reflect.TypeOf((*MockRules)(nil).Reload), ctx)
+}
+
+// Remove mocks base method.
+func (m *MockRules) Remove(ctx context.Context, rules []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Remove", ctx, rules)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Remove indicates an expected call of Remove.
+func (mr *MockRulesMockRecorder) Remove(ctx, rules any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockRules)(nil).Remove), ctx, rules)
+}
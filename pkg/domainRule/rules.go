@@ -0,0 +1,253 @@
+// Package domainRule 按域名规则判断目标地址是否允许缩短和跳转
+//
+//go:generate mockgen -source=$GOFILE -destination=./mock/rules_mock.go -package=domainRule
+package domainRule
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"github.com/zeromicro/go-zero/core/threading"
+	"golang.org/x/net/idna"
+	"shortener/internal/config"
+	"shortener/internal/types/errorx"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// wildcardPrefix 通配规则的前缀，*.example.com 匹配 example.com 的全部子域名，不含 example.com 本身
+	wildcardPrefix = "*."
+	// suffixPrefix 后缀规则的前缀，.example.com 匹配 example.com 及其全部子域名
+	suffixPrefix = "."
+)
+
+// Rules 目标域名规则，规则有三种写法：
+//   - example.com 只匹配该域名
+//   - *.example.com 匹配全部子域名
+//   - .example.com 匹配该域名及其全部子域名
+type Rules interface {
+	// Allowed 判断主机是否允许作为目标地址
+	Allowed(host string) bool
+	// List 返回当前的全部规则，按字典序排列
+	List() []string
+	// Add 添加规则，任一规则不合法时不做修改
+	Add(ctx context.Context, rules []string) error
+	// Remove 删除规则，不存在的规则忽略
+	Remove(ctx context.Context, rules []string) error
+	// Reload 从存储重新加载规则，读取失败时保留当前规则
+	Reload(ctx context.Context) error
+}
+
+// profile 按IDNA查找规则将国际化域名转换为punycode
+var profile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.VerifyDNSLength(true))
+
+// NewRules 创建以规则文件为存储的域名规则，用于单机模式。文件每行一条规则，#开头的行为注释。
+// 文件不存在时规则为空；不合法的规则被跳过，返回最后一个错误
+func NewRules(conf config.DomainRuleConf) (Rules, error) {
+	return newRules(conf, fileStore{path: conf.Path})
+}
+
+// NewRedisRules 创建以Redis集合为存储的域名规则，多实例共享同一份规则，每隔Refresh重新加载。
+// 集合第一次使用时导入规则文件，之后规则文件不再生效
+func NewRedisRules(conf config.DomainRuleConf, rdb *redis.Redis) (Rules, error) {
+	s, err := newRedisStore(rdb, conf.Key, conf.Path)
+	if err != nil {
+		logx.Errorf("seed domain rules failed,key:%v,err:%v", conf.Key, err)
+	}
+	return newRules(conf, s)
+}
+
+func newRules(conf config.DomainRuleConf, s store) (Rules, error) {
+	r := &rules{mode: conf.Mode, store: s}
+	empty := make(map[string]struct{})
+	r.set.Store(&empty)
+
+	err := r.Reload(context.Background())
+	r.start(context.Background(), conf.Refresh)
+	return r, err
+}
+
+type rules struct {
+	mode  string
+	store store
+
+	// mu 串行化修改和重新加载，读取不加锁
+	mu  sync.Mutex
+	set atomic.Pointer[map[string]struct{}]
+}
+
+func (r *rules) Allowed(host string) bool {
+	switch r.mode {
+	case config.DomainRuleModeAllow:
+		return r.match(host)
+	case config.DomainRuleModeBlock:
+		return !r.match(host)
+	default:
+		return true
+	}
+}
+
+// match 依次检查主机本身和每一级父域名是否命中规则
+func (r *rules) match(host string) bool {
+	set := *r.set.Load()
+	if len(set) == 0 {
+		return false
+	}
+
+	host = normalizeHost(host)
+	if _, ok := set[host]; ok {
+		return true
+	}
+	if _, ok := set[suffixPrefix+host]; ok {
+		return true
+	}
+
+	for i := strings.IndexByte(host, '.'); i >= 0; i = strings.IndexByte(host, '.') {
+		host = host[i+1:]
+		if _, ok := set[wildcardPrefix+host]; ok {
+			return true
+		}
+		if _, ok := set[suffixPrefix+host]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rules) List() []string {
+	return sortedRules(*r.set.Load())
+}
+
+func (r *rules) Add(ctx context.Context, rules []string) error {
+	normalized, err := normalizeRules(rules)
+	if err != nil {
+		return err
+	}
+
+	return r.update(func(set map[string]struct{}) error {
+		for _, rule := range normalized {
+			set[rule] = struct{}{}
+		}
+		return r.store.add(ctx, normalized, set)
+	})
+}
+
+func (r *rules) Remove(ctx context.Context, rules []string) error {
+	normalized, err := normalizeRules(rules)
+	if err != nil {
+		return err
+	}
+
+	return r.update(func(set map[string]struct{}) error {
+		for _, rule := range normalized {
+			delete(set, rule)
+		}
+		return r.store.remove(ctx, normalized, set)
+	})
+}
+
+// update 在规则的副本上修改，写入存储成功后替换当前规则
+func (r *rules) update(fn func(set map[string]struct{}) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := *r.set.Load()
+	set := make(map[string]struct{}, len(current))
+	for rule := range current {
+		set[rule] = struct{}{}
+	}
+
+	if err := fn(set); err != nil {
+		return err
+	}
+	r.set.Store(&set)
+	return nil
+}
+
+func (r *rules) Reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	set, err := r.store.load(ctx)
+	if set != nil {
+		r.set.Store(&set)
+	}
+	return err
+}
+
+// start 每隔refresh重新加载规则，兜底其他实例修改后未收到的通知
+func (r *rules) start(ctx context.Context, refresh time.Duration) {
+	if refresh <= 0 {
+		return
+	}
+
+	threading.GoSafe(func() {
+		ticker := time.NewTicker(refresh)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Reload(ctx); err != nil {
+					logx.Errorf("reload domain rules failed,err:%v", err)
+				}
+			}
+		}
+	})
+}
+
+// ParseRule 校验规则并转换为规范形式：小写，国际化域名转换为punycode
+func ParseRule(raw string) (string, error) {
+	rule := strings.TrimSpace(raw)
+	prefix := ""
+	switch {
+	case strings.HasPrefix(rule, wildcardPrefix):
+		prefix = wildcardPrefix
+	case strings.HasPrefix(rule, suffixPrefix):
+		prefix = suffixPrefix
+	}
+
+	domain := strings.TrimSuffix(rule[len(prefix):], ".")
+	ascii, err := profile.ToASCII(domain)
+	if err != nil || len(ascii) == 0 {
+		return "", errorx.NewWithCause(errorx.CodeParamError, "invalid domain rule", err).
+			WithMeta("rule", raw)
+	}
+	return prefix + ascii, nil
+}
+
+func normalizeRules(rules []string) ([]string, error) {
+	normalized := make([]string, 0, len(rules))
+	for _, raw := range rules {
+		rule, err := ParseRule(raw)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, rule)
+	}
+	return normalized, nil
+}
+
+// normalizeHost 主机转换为与规则相同的形式，无法转换时（如IPv6地址）只转为小写
+func normalizeHost(host string) string {
+	host = strings.TrimSuffix(host, ".")
+	if ascii, err := profile.ToASCII(host); err == nil {
+		return ascii
+	}
+	return strings.ToLower(host)
+}
+
+func sortedRules(set map[string]struct{}) []string {
+	list := make([]string, 0, len(set))
+	for rule := range set {
+		list = append(list, rule)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,184 @@
+package domainRule
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"os"
+	"path/filepath"
+	"shortener/internal/config"
+	"shortener/internal/types/errorx"
+	"shortener/pkg/pubsub"
+	"sync"
+	"testing"
+)
+
+func newTestRules(t *testing.T, mode, content string) (Rules, string) {
+	path := filepath.Join(t.TempDir(), "domainRules.txt")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	r, err := NewRules(config.DomainRuleConf{Mode: mode, Path: path})
+	require.NoError(t, err)
+	return r, path
+}
+
+func TestRules_Allowed(t *testing.T) {
+	r, _ := newTestRules(t, config.DomainRuleModeBlock, "# 示例\nexact.example\n*.wild.example\n.suffix.example\n例子.测试\n")
+
+	tests := []struct {
+		name   string
+		host   string
+		expect bool
+	}{
+		{name: "精确匹配", host: "exact.example", expect: false},
+		{name: "精确规则不匹配子域名", host: "a.exact.example", expect: true},
+		{name: "通配规则匹配子域名", host: "a.wild.example", expect: false},
+		{name: "通配规则匹配多级子域名", host: "a.b.wild.example", expect: false},
+		{name: "通配规则不匹配域名本身", host: "wild.example", expect: true},
+		{name: "后缀规则匹配域名本身", host: "suffix.example", expect: false},
+		{name: "后缀规则匹配子域名", host: "a.suffix.example", expect: false},
+		{name: "后缀规则不匹配相似域名", host: "notsuffix.example", expect: true},
+		{name: "不区分大小写", host: "EXACT.Example.", expect: false},
+		{name: "国际化域名", host: "xn--fsqu00a.xn--0zwm56d", expect: false},
+		{name: "未命中规则", host: "other.example", expect: true},
+		{name: "IPv6地址", host: "2001:db8::1", expect: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, r.Allowed(tt.host))
+		})
+	}
+}
+
+func TestRules_Mode(t *testing.T) {
+	allow, _ := newTestRules(t, config.DomainRuleModeAllow, ".example.com\n")
+	assert.True(t, allow.Allowed("www.example.com"))
+	assert.False(t, allow.Allowed("example.org"))
+
+	off, _ := newTestRules(t, config.DomainRuleModeOff, ".example.com\n")
+	assert.True(t, off.Allowed("www.example.com"))
+
+	// 允许模式下没有规则时全部拒绝
+	empty, err := NewRules(config.DomainRuleConf{Mode: config.DomainRuleModeAllow, Path: filepath.Join(t.TempDir(), "missing.txt")})
+	require.NoError(t, err)
+	assert.False(t, empty.Allowed("example.com"))
+}
+
+func TestRules_InvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domainRules.txt")
+	require.NoError(t, os.WriteFile(path, []byte("bad_domain.example\ngood.example\n"), 0644))
+
+	r, err := NewRules(config.DomainRuleConf{Mode: config.DomainRuleModeBlock, Path: path})
+
+	assert.Error(t, err)
+	assert.Equal(t, []string{"good.example"}, r.List())
+}
+
+func TestRules_Edit(t *testing.T) {
+	ctx := context.Background()
+	r, path := newTestRules(t, config.DomainRuleModeBlock, "a.example\n")
+
+	require.NoError(t, r.Add(ctx, []string{"*.B.example", ".例子.测试"}))
+	assert.Equal(t, []string{"*.b.example", ".xn--fsqu00a.xn--0zwm56d", "a.example"}, r.List())
+	assert.False(t, r.Allowed("x.b.example"))
+
+	// 修改写回规则文件，重新加载后保持一致
+	reloaded, err := NewRules(config.DomainRuleConf{Mode: config.DomainRuleModeBlock, Path: path})
+	require.NoError(t, err)
+	assert.Equal(t, r.List(), reloaded.List())
+
+	require.NoError(t, r.Remove(ctx, []string{"a.example", "missing.example"}))
+	assert.True(t, r.Allowed("a.example"))
+
+	// 任一规则不合法时不做修改
+	err = r.Add(ctx, []string{"c.example", "bad domain"})
+	assert.True(t, errorx.Is(err, errorx.CodeParamError))
+	assert.True(t, r.Allowed("c.example"))
+}
+
+func newTestRedisRules(t *testing.T, mr *miniredis.Miniredis, seedPath string) Rules {
+	conf := config.DomainRuleConf{Mode: config.DomainRuleModeBlock, Path: seedPath, Key: "domain-rules"}
+	r, err := NewRedisRules(conf, redis.New(mr.Addr()))
+	require.NoError(t, err)
+	return r
+}
+
+func TestRedisRules(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+
+	seed := filepath.Join(t.TempDir(), "domainRules.txt")
+	require.NoError(t, os.WriteFile(seed, []byte("seed.example\n"), 0644))
+
+	// 第一次使用时导入规则文件
+	r1 := newTestRedisRules(t, mr, seed)
+	assert.Equal(t, []string{"seed.example"}, r1.List())
+
+	require.NoError(t, r1.Add(ctx, []string{"Evil.example"}))
+	require.NoError(t, r1.Remove(ctx, []string{"seed.example"}))
+	assert.Equal(t, []string{"evil.example"}, r1.List())
+
+	// 重新部署的实例从Redis加载，不会回退到规则文件
+	r2 := newTestRedisRules(t, mr, seed)
+	assert.Equal(t, []string{"evil.example"}, r2.List())
+
+	// 删除全部规则后不会再次导入
+	require.NoError(t, r2.Remove(ctx, []string{"evil.example"}))
+	r3 := newTestRedisRules(t, mr, seed)
+	assert.Empty(t, r3.List())
+
+	// 其他实例的修改在重新加载后生效
+	require.NoError(t, r3.Add(ctx, []string{"other.example"}))
+	assert.True(t, r1.Allowed("other.example"))
+	require.NoError(t, r1.Reload(ctx))
+	assert.False(t, r1.Allowed("other.example"))
+
+	// 读取失败时保留当前规则
+	mr.Close()
+	assert.Error(t, r1.Reload(ctx))
+	assert.Equal(t, []string{"other.example"}, r1.List())
+}
+
+func TestSyncedRules(t *testing.T) {
+	ctx := context.Background()
+	ps := pubsub.NewMemoryPubSub()
+	mr := miniredis.RunT(t)
+
+	s1 := NewSyncedRules(newTestRedisRules(t, mr, ""), ps, "domain-rules")
+	s2 := NewSyncedRules(newTestRedisRules(t, mr, ""), ps, "domain-rules")
+
+	require.NoError(t, s1.Add(ctx, []string{"Evil.example"}))
+	assert.False(t, s1.Allowed("evil.example"))
+	assert.False(t, s2.Allowed("evil.example"))
+
+	require.NoError(t, s2.Remove(ctx, []string{"evil.example"}))
+	assert.True(t, s1.Allowed("evil.example"))
+}
+
+func TestRedisRules_Seed(t *testing.T) {
+	mr := miniredis.RunT(t)
+	dir := t.TempDir()
+
+	// 规则文件读取失败时不写入导入标记
+	_, err := newRedisStore(redis.New(mr.Addr()), "domain-rules", dir)
+	assert.Error(t, err)
+	assert.False(t, mr.Exists("domain-rules"+seededSuffix))
+
+	// 并发启动的实例看到导入标记时规则已经写入
+	seed := filepath.Join(dir, "domainRules.txt")
+	require.NoError(t, os.WriteFile(seed, []byte("a.example\nb.example\n"), 0644))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := newTestRedisRules(t, mr, seed)
+			assert.Equal(t, []string{"a.example", "b.example"}, r.List())
+		}()
+	}
+	wg.Wait()
+	assert.True(t, mr.Exists("domain-rules"+seededSuffix))
+}
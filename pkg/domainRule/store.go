@@ -0,0 +1,214 @@
+package domainRule
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"os"
+	"path/filepath"
+	"shortener/internal/types/errorx"
+	"strings"
+)
+
+const (
+	// seededSuffix Redis中记录规则文件已导入的键名后缀，删除全部规则后不会再次导入
+	seededSuffix = ":seeded"
+
+	// seedScript 规则和导入标记在同一个脚本中写入，其他实例看到标记时规则已经写入
+	seedScript = `if redis.call('EXISTS', KEYS[2]) == 1 then
+	return 0
+end
+if #ARGV > 0 then
+	redis.call('SADD', KEYS[1], unpack(ARGV))
+end
+redis.call('SET', KEYS[2], '1')
+return 1`
+)
+
+// store 规则的持久化存储，规则在写入前已转换为规范形式
+type store interface {
+	// load 读取全部规则，不合法的规则被跳过并返回最后一个错误，读取失败时返回nil
+	load(ctx context.Context) (map[string]struct{}, error)
+	// add 持久化新增的规则，set为修改后的全部规则
+	add(ctx context.Context, rules []string, set map[string]struct{}) error
+	// remove 持久化删除的规则，set为修改后的全部规则
+	remove(ctx context.Context, rules []string, set map[string]struct{}) error
+}
+
+// fileStore 规则文件，每行一条规则，#开头的行为注释，修改时整体写回
+type fileStore struct {
+	path string
+}
+
+func (s fileStore) load(context.Context) (map[string]struct{}, error) {
+	return load(s.path)
+}
+
+func (s fileStore) add(_ context.Context, _ []string, set map[string]struct{}) error {
+	return save(s.path, set)
+}
+
+func (s fileStore) remove(_ context.Context, _ []string, set map[string]struct{}) error {
+	return save(s.path, set)
+}
+
+// redisStore Redis集合，多实例共享同一份规则
+type redisStore struct {
+	rdb *redis.Redis
+	key string
+}
+
+// newRedisStore 集合第一次使用时导入规则文件中的规则，多个实例同时启动时只有一个实例导入。
+// 规则文件读取失败时不写入标记，下次启动重新导入
+func newRedisStore(rdb *redis.Redis, key, seedPath string) (redisStore, error) {
+	s := redisStore{rdb: rdb, key: key}
+
+	set, err := load(seedPath)
+	if set == nil {
+		return s, err
+	}
+
+	rules := sortedRules(set)
+	seeded, evalErr := rdb.EvalCtx(context.Background(), seedScript, []string{key, key + seededSuffix}, toValues(rules)...)
+	if evalErr != nil {
+		return s, errorx.NewWithCause(errorx.CodeCacheError, "seed domain rules failed", evalErr).
+			WithMeta("key", key)
+	}
+	if n, ok := seeded.(int64); ok && n == 1 {
+		logx.Infof("domain rules seeded from file,path:%v,count:%v", seedPath, len(rules))
+	}
+	return s, err
+}
+
+func (s redisStore) load(ctx context.Context) (map[string]struct{}, error) {
+	members, err := s.rdb.SmembersCtx(ctx, s.key)
+	if err != nil {
+		return nil, errorx.NewWithCause(errorx.CodeCacheError, "load domain rules failed", err).
+			WithMeta("key", s.key)
+	}
+
+	var lastErr error
+	set := make(map[string]struct{}, len(members))
+	for _, member := range members {
+		rule, err := ParseRule(member)
+		if err != nil {
+			lastErr = errorx.Wrap(err, errorx.CodeSystemError, "skip invalid domain rule")
+			continue
+		}
+		set[rule] = struct{}{}
+	}
+	return set, lastErr
+}
+
+func (s redisStore) add(ctx context.Context, rules []string, _ map[string]struct{}) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	if _, err := s.rdb.SaddCtx(ctx, s.key, toValues(rules)...); err != nil {
+		return errorx.NewWithCause(errorx.CodeCacheError, "add domain rules failed", err).
+			WithMeta("key", s.key)
+	}
+	return nil
+}
+
+func (s redisStore) remove(ctx context.Context, rules []string, _ map[string]struct{}) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	if _, err := s.rdb.SremCtx(ctx, s.key, toValues(rules)...); err != nil {
+		return errorx.NewWithCause(errorx.CodeCacheError, "remove domain rules failed", err).
+			WithMeta("key", s.key)
+	}
+	return nil
+}
+
+func toValues(rules []string) []any {
+	values := make([]any, len(rules))
+	for i, rule := range rules {
+		values[i] = rule
+	}
+	return values
+}
+
+func load(path string) (map[string]struct{}, error) {
+	set := make(map[string]struct{})
+	if len(path) == 0 {
+		return set, nil
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		logx.Infof("domain rule file does not exist,path:%v", path)
+		return set, nil
+	}
+	if err != nil {
+		return nil, errorx.NewWithCause(errorx.CodeSystemError, "open the domain rule file failed", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			logx.Errorf("failed to close domain rule file %s: %v", path, err)
+		}
+	}()
+
+	var lastErr error
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		rule, err := ParseRule(text)
+		if err != nil {
+			lastErr = errorx.Wrap(err, errorx.CodeSystemError, "skip invalid domain rule").
+				WithMeta("line", line)
+			continue
+		}
+		set[rule] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errorx.NewWithCause(errorx.CodeSystemError, "read the domain rule file failed", err)
+	}
+	return set, lastErr
+}
+
+// save 先写临时文件再重命名，避免进程中断时留下不完整的规则文件。写回后文件中的注释会丢失
+func save(path string, set map[string]struct{}) error {
+	if len(path) == 0 {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return errorx.NewWithCause(errorx.CodeSystemError, "create the domain rule file failed", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	// 临时文件默认只有所有者可读，与普通文件保持一致
+	if err = tmp.Chmod(0644); err != nil {
+		_ = tmp.Close()
+		return errorx.NewWithCause(errorx.CodeSystemError, "create the domain rule file failed", err)
+	}
+
+	w := bufio.NewWriter(tmp)
+	_, _ = fmt.Fprintln(w, "# 目标域名规则，通过管理接口修改后自动生成")
+	for _, rule := range sortedRules(set) {
+		_, _ = fmt.Fprintln(w, rule)
+	}
+	if err = w.Flush(); err == nil {
+		err = tmp.Close()
+	} else {
+		_ = tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return errorx.NewWithCause(errorx.CodeSystemError, "write the domain rule file failed", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package domainRule
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"shortener/pkg/pubsub"
+	"strings"
+)
+
+const (
+	eventReload = "reload"
+
+	// eventSeparator 事件格式：实例ID|类型
+	eventSeparator = "|"
+)
+
+// NewSyncedRules 修改规则后通过发布订阅通知其他实例从存储重新加载。
+// 通知最多送达一次，错过的通知由各实例定期重新加载兜底
+func NewSyncedRules(r Rules, ps pubsub.PubSub, channel string) Rules {
+	s := &syncedRules{Rules: r, ps: ps, channel: channel, id: pubsub.NewInstanceID()}
+	ps.Subscribe(context.Background(), channel, s.handle)
+	return s
+}
+
+type syncedRules struct {
+	Rules
+	ps      pubsub.PubSub
+	channel string
+	// id 当前实例的标识，用于忽略自己发布的事件
+	id string
+}
+
+func (s *syncedRules) Add(ctx context.Context, rules []string) error {
+	if err := s.Rules.Add(ctx, rules); err != nil {
+		return err
+	}
+	s.publish(ctx)
+	return nil
+}
+
+func (s *syncedRules) Remove(ctx context.Context, rules []string) error {
+	if err := s.Rules.Remove(ctx, rules); err != nil {
+		return err
+	}
+	s.publish(ctx)
+	return nil
+}
+
+// publish 通知失败只记录日志，本实例的修改已经生效
+func (s *syncedRules) publish(ctx context.Context) {
+	message := s.id + eventSeparator + eventReload
+	if err := s.ps.Publish(ctx, s.channel, message); err != nil {
+		logx.Errorf("publish domain rule event failed,err:%v", err)
+	}
+}
+
+func (s *syncedRules) handle(message string) {
+	id, event, ok := strings.Cut(message, eventSeparator)
+	if !ok || id == s.id || event != eventReload {
+		return
+	}
+
+	if err := s.Rules.Reload(context.Background()); err != nil {
+		logx.Errorf("reload domain rules failed,err:%v", err)
+	}
+}
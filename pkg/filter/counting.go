@@ -0,0 +1,108 @@
+package filter
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"strconv"
+)
+
+// 计数器以4位为单位打包存储在Redis字符串中，每个字节保存两个计数器，
+// 计数达到15后不再增减，避免溢出导致误删。
+const countingLuaHelpers = `local function get(o)
+	local pos = math.floor(o / 2)
+	local b = string.byte(redis.call('GETRANGE', KEYS[1], pos, pos)) or 0
+	if o % 2 == 0 then
+		return math.floor(b / 16), b, pos
+	end
+	return b % 16, b, pos
+end
+
+local function set(o, v, b, pos)
+	if o % 2 == 0 then
+		b = v * 16 + b % 16
+	else
+		b = math.floor(b / 16) * 16 + v
+	end
+	redis.call('SETRANGE', KEYS[1], pos, string.char(b))
+end
+`
+
+const (
+	countingAddScript = countingLuaHelpers + `for _, off in ipairs(ARGV) do
+	local o = tonumber(off)
+	local v, b, pos = get(o)
+	if v < 15 then
+		set(o, v + 1, b, pos)
+	end
+end
+return 1`
+
+	countingExistsScript = countingLuaHelpers + `for _, off in ipairs(ARGV) do
+	if get(tonumber(off)) == 0 then
+		return 0
+	end
+end
+return 1`
+
+	// countingRemoveScript 只有全部计数器都不为0时才递减，避免删除不存在的数据破坏其他数据的计数
+	countingRemoveScript = countingLuaHelpers + `for _, off in ipairs(ARGV) do
+	if get(tonumber(off)) == 0 then
+		return 0
+	end
+end
+for _, off in ipairs(ARGV) do
+	local o = tonumber(off)
+	local v, b, pos = get(o)
+	if v < 15 then
+		set(o, v - 1, b, pos)
+	end
+end
+return 1`
+)
+
+// newCountingBitmap 创建支持删除的计数布隆过滤器，bits为计数器个数
+func newCountingBitmap(rdb *redis.Redis, key string, bits, hashes uint) *countingBitmap {
+	return &countingBitmap{
+		rdb:    rdb,
+		key:    key,
+		bits:   bits,
+		hashes: hashes,
+	}
+}
+
+type countingBitmap struct {
+	rdb    *redis.Redis
+	key    string
+	bits   uint
+	hashes uint
+}
+
+func (c *countingBitmap) AddCtx(ctx context.Context, data []byte) error {
+	_, err := c.rdb.EvalCtx(ctx, countingAddScript, []string{c.key}, c.locations(data))
+	return err
+}
+
+func (c *countingBitmap) ExistsCtx(ctx context.Context, data []byte) (bool, error) {
+	resp, err := c.rdb.EvalCtx(ctx, countingExistsScript, []string{c.key}, c.locations(data))
+	if err != nil {
+		return false, err
+	}
+
+	exists, ok := resp.(int64)
+	return ok && exists == 1, nil
+}
+
+func (c *countingBitmap) RemoveCtx(ctx context.Context, data []byte) error {
+	_, err := c.rdb.EvalCtx(ctx, countingRemoveScript, []string{c.key}, c.locations(data))
+	return err
+}
+
+// locations 计算数据对应的计数器位置
+func (c *countingBitmap) locations(data []byte) []string {
+	offsets := locations(data, c.bits, c.hashes)
+	args := make([]string, len(offsets))
+	for i, offset := range offsets {
+		args[i] = strconv.FormatUint(uint64(offset), 10)
+	}
+	return args
+}
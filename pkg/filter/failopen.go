@@ -0,0 +1,165 @@
+package filter
+
+import (
+	"context"
+	"errors"
+	"github.com/zeromicro/go-zero/core/breaker"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	opExists = "exists"
+	opAdd    = "add"
+	opRemove = "remove"
+
+	// maxPendingAdds 等待重放的写入失败数据的上限，超过后丢弃并提示重建过滤器
+	maxPendingAdds = 100000
+	// replayTimeout 重放一条数据的超时时间
+	replayTimeout = time.Second
+)
+
+// NewFailOpenFilter 包装过滤器，Redis异常时放行而不是返回错误：
+// ExistsCtx 视为存在并交由数据库判断，AddCtx 记录写入失败的数据，Redis恢复后在后台重放，
+// 避免其他实例把已生成的短码误判为不存在。连续失败时熔断器打开，请求不再等待Redis超时。
+func NewFailOpenFilter(f Rebuilder, name string) Rebuilder {
+	return &failOpenFilter{
+		Rebuilder: f,
+		brk:       breaker.NewBreaker(breaker.WithName(name)),
+	}
+}
+
+type failOpenFilter struct {
+	Rebuilder
+	brk breaker.Breaker
+
+	// 写入失败等待重放的数据，超过上限后丢弃的个数记录在dropped中
+	mu        sync.Mutex
+	pending   [][]byte
+	dropped   int
+	replaying atomic.Bool
+}
+
+func (f *failOpenFilter) ExistsCtx(ctx context.Context, data []byte) (bool, error) {
+	var exist bool
+	err := f.brk.DoCtx(ctx, func() error {
+		var err error
+		exist, err = f.Rebuilder.ExistsCtx(ctx, data)
+		return err
+	})
+	if err != nil {
+		f.bypass(ctx, opExists, data, err)
+		return true, nil
+	}
+
+	f.recovered()
+	return exist, nil
+}
+
+func (f *failOpenFilter) AddCtx(ctx context.Context, data []byte) error {
+	err := f.brk.DoCtx(ctx, func() error {
+		return f.Rebuilder.AddCtx(ctx, data)
+	})
+	if err != nil {
+		// 数据已落库，写入过滤器失败不影响本次请求，恢复后重放
+		f.bypass(ctx, opAdd, data, err)
+		f.record(data)
+		return nil
+	}
+
+	f.recovered()
+	return nil
+}
+
+func (f *failOpenFilter) RemoveCtx(ctx context.Context, data []byte) error {
+	err := f.brk.DoCtx(ctx, func() error {
+		return f.Rebuilder.RemoveCtx(ctx, data)
+	})
+	if err != nil {
+		// 未能删除的数据只会让查询多走一次数据库
+		f.bypass(ctx, opRemove, data, err)
+		return nil
+	}
+
+	f.recovered()
+	return nil
+}
+
+// record 记录写入失败的数据，队列已满时丢弃
+func (f *failOpenFilter) record(data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.pending) >= maxPendingAdds {
+		f.dropped++
+		filterMetrics.replays.WithLabelValues("dropped").Inc()
+		return
+	}
+	f.pending = append(f.pending, append([]byte(nil), data...))
+}
+
+// recovered 过滤器调用成功后，在后台重放写入失败的数据
+func (f *failOpenFilter) recovered() {
+	f.mu.Lock()
+	empty := len(f.pending) == 0 && f.dropped == 0
+	f.mu.Unlock()
+
+	if empty || !f.replaying.CompareAndSwap(false, true) {
+		return
+	}
+	threading.GoSafe(func() {
+		defer f.replaying.Store(false)
+		f.replay()
+	})
+}
+
+// replay 按写入顺序重放，再次失败时把剩余的数据放回队列等待下一次恢复。
+// 有数据被丢弃时无法补齐，需要重建过滤器
+func (f *failOpenFilter) replay() {
+	f.mu.Lock()
+	pending, dropped := f.pending, f.dropped
+	f.pending, f.dropped = nil, 0
+	f.mu.Unlock()
+
+	for i, data := range pending {
+		ctx, cancel := context.WithTimeout(context.Background(), replayTimeout)
+		err := f.brk.DoCtx(ctx, func() error {
+			return f.Rebuilder.AddCtx(ctx, data)
+		})
+		cancel()
+
+		if err != nil {
+			f.mu.Lock()
+			f.pending = append(pending[i:], f.pending...)
+			f.dropped += dropped
+			f.mu.Unlock()
+			logx.Errorf("replay failed short code filter adds failed,remaining:%v,err:%v", len(pending)-i, err)
+			return
+		}
+		filterMetrics.replays.WithLabelValues("replayed").Inc()
+	}
+
+	if len(pending) > 0 {
+		logx.Infof("replayed failed short code filter adds,count:%v", len(pending))
+	}
+	if dropped > 0 {
+		logx.Severef("%v failed short code filter adds were dropped, run -rebuild-filter to restore them", dropped)
+	}
+}
+
+func (f *failOpenFilter) bypass(ctx context.Context, op string, data []byte, err error) {
+	reason := "error"
+	if errors.Is(err, breaker.ErrServiceUnavailable) {
+		reason = "breaker_open"
+	}
+	filterMetrics.bypasses.WithLabelValues(op, reason).Inc()
+
+	logx.WithContext(ctx).Errorw("short code filter unavailable, bypassed",
+		logx.Field("op", op),
+		logx.Field("reason", reason),
+		logx.Field("data", string(data)),
+		logx.Field("err", err.Error()))
+}
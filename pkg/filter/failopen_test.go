@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailOpenFilter(t *testing.T) {
+	ctx := context.Background()
+	bf, mr := newTestFilter(t)
+	f := NewFailOpenFilter(bf, "test-filter")
+
+	t.Run("Redis正常时透传结果", func(t *testing.T) {
+		assert.NoError(t, f.AddCtx(ctx, []byte("a")))
+
+		exist, err := f.ExistsCtx(ctx, []byte("a"))
+		assert.NoError(t, err)
+		assert.True(t, exist)
+
+		exist, err = f.ExistsCtx(ctx, []byte("b"))
+		assert.NoError(t, err)
+		assert.False(t, exist)
+	})
+
+	t.Run("Redis异常时放行并熔断", func(t *testing.T) {
+		mr.Close()
+
+		openBefore := testutil.ToFloat64(filterMetrics.bypasses.WithLabelValues(opExists, "breaker_open"))
+
+		for i := 0; i < 200; i++ {
+			exist, err := f.ExistsCtx(ctx, []byte("b"))
+			assert.NoError(t, err)
+			assert.True(t, exist)
+		}
+		assert.NoError(t, f.AddCtx(ctx, []byte("c")))
+
+		assert.Greater(t, testutil.ToFloat64(filterMetrics.bypasses.WithLabelValues(opExists, "breaker_open")), openBefore,
+			"连续失败后熔断器应打开")
+	})
+}
+
+// flakyFilter 可以模拟Redis异常的进程内过滤器
+type flakyFilter struct {
+	*memoryFilter
+	failing atomic.Bool
+}
+
+func (f *flakyFilter) AddCtx(ctx context.Context, data []byte) error {
+	if f.failing.Load() {
+		return errors.New("redis unavailable")
+	}
+	return f.memoryFilter.AddCtx(ctx, data)
+}
+
+func TestFailOpenFilter_Replay(t *testing.T) {
+	ctx := context.Background()
+	flaky := &flakyFilter{memoryFilter: newMemoryFilter(1024, 3, false)}
+	f := NewFailOpenFilter(flaky, "replay-filter")
+
+	replayed := testutil.ToFloat64(filterMetrics.replays.WithLabelValues("replayed"))
+
+	flaky.failing.Store(true)
+	assert.NoError(t, f.AddCtx(ctx, []byte("a")))
+	assert.NoError(t, f.AddCtx(ctx, []byte("b")))
+	assert.False(t, flaky.live.test([]byte("a")))
+
+	// 恢复后的第一次调用触发重放
+	flaky.failing.Store(false)
+	_, err := f.ExistsCtx(ctx, []byte("c"))
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(filterMetrics.replays.WithLabelValues("replayed")) == replayed+2
+	}, time.Second, 10*time.Millisecond)
+	for _, key := range []string{"a", "b"} {
+		exist, err := f.ExistsCtx(ctx, []byte(key))
+		assert.NoError(t, err)
+		assert.True(t, exist, key)
+	}
+}
@@ -3,18 +3,102 @@ package filter
 
 import (
 	"context"
-	"github.com/zeromicro/go-zero/core/bloom"
+	"github.com/zeromicro/go-zero/core/hash"
 	"github.com/zeromicro/go-zero/core/logx"
 	"github.com/zeromicro/go-zero/core/stores/redis"
+	"github.com/zeromicro/go-zero/core/stringx"
 	"shortener/internal/config"
+	"shortener/internal/types/errorx"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// swapScript 仍持有重建锁时原子地用新建的位图替换旧位图，临时位图不存在说明数据为空，直接删除旧位图
+	// KEYS[1]: 重建锁; KEYS[2]: 临时位图; KEYS[3]: 位图; ARGV[1]: 锁的令牌;
+	// KEYS[4]: 位图的版本号
+	swapScript = `if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if redis.call('EXISTS', KEYS[2]) == 1 then
+	redis.call('RENAME', KEYS[2], KEYS[3])
+	redis.call('PERSIST', KEYS[3])
+else
+	redis.call('DEL', KEYS[3])
+end
+redis.call('INCR', KEYS[4])
+return 1`
+
+	// renewScript 延长重建锁和临时位图的过期时间，重建进程退出后两者自动过期
+	// KEYS[1]: 重建锁; KEYS[2]: 临时位图; ARGV[1]: 锁的令牌; ARGV[2]: 过期毫秒数
+	renewScript = `if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[2])
+return 1`
+
+	// releaseScript 只释放自己持有的重建锁
+	releaseScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`
+
+	// bloomAddScript 设置数据对应的全部位，位的布局与go-zero布隆过滤器一致
+	bloomAddScript = `for _, off in ipairs(ARGV) do
+	redis.call('SETBIT', KEYS[1], off, 1)
+end
+return 1`
+
+	bloomExistsScript = `for _, off in ipairs(ARGV) do
+	if redis.call('GETBIT', KEYS[1], off) == 0 then
+		return 0
+	end
+end
+return 1`
+)
+
+const (
+	// rebuildKeySuffix 重建锁的键名后缀，锁的值为本次重建的令牌，同时作为各实例共享的重建标记。
+	// 临时位图的键名为 锁的键名:令牌，每次重建使用不同的临时位图
+	rebuildKeySuffix = ":rebuild"
+	// rebuildLockExpire 重建锁的过期时间，每写入一批数据续期一次
+	rebuildLockExpire = time.Minute
+	// markerRefresh 重新读取重建标记的间隔，重建结束后等待两个间隔再清理其他实例可能写入的临时位图
+	markerRefresh = time.Second
+	// versionKeySuffix 位图版本号的键名后缀，每次增删和重建后加一，副本比较版本号判断是否需要重新加载快照
+	versionKeySuffix = ":version"
+	// tokenLength 重建锁令牌的长度
+	tokenLength = 16
+	// defaultHashes 直接配置位数时使用的哈希函数个数，与go-zero布隆过滤器一致
+	defaultHashes = 14
 )
 
 type Filter interface {
 	AddCtx(ctx context.Context, data []byte) error
 	ExistsCtx(ctx context.Context, data []byte) (bool, error)
+	// RemoveCtx 从过滤器中移除数据，不支持删除的实现直接忽略
+	RemoveCtx(ctx context.Context, data []byte) error
+}
+
+// Source 分批读取需要写入过滤器的全部数据，每读取一批调用一次fn
+type Source func(ctx context.Context, fn func(batch [][]byte) error) error
+
+// Rebuilder 支持从数据源重建的过滤器
+type Rebuilder interface {
+	Filter
+	// Rebuild 将source中的数据写入新位图后原子替换旧位图，同一时间只允许一个实例重建，
+	// 重建期间全部实例的增删同时写入新位图，ExistsCtx直接返回true
+	Rebuild(ctx context.Context, source Source) error
+	// Rebuilding 判断是否有实例正在重建
+	Rebuilding(ctx context.Context) bool
+	// Lost 判断过滤器位图是否丢失
+	Lost(ctx context.Context) (bool, error)
 }
 
-func NewBloomFilter(conf config.BloomFilterConf) Filter {
+// NewBloomFilter 根据配置创建Redis过滤器，Type为counting时使用支持删除的计数布隆过滤器
+func NewBloomFilter(conf config.BloomFilterConf) Rebuilder {
 	// 初始化布隆过滤器Redis连接
 	redisConnection, err := redis.NewRedis(redis.RedisConf{
 		Host: conf.Redis.Addr,
@@ -25,9 +109,293 @@ func NewBloomFilter(conf config.BloomFilterConf) Filter {
 		logx.Severef("NewServiceContext redis.NewRedis failed,err:%v", err)
 	}
 
-	return bloom.New(
-		redisConnection,
-		conf.Key,
-		conf.Bits,
-	)
+	bits, hashes := sizeOf(conf)
+	if conf.Type == config.FilterTypeCounting {
+		return newRedisFilter(redisConnection, conf.Key, conf.Replica, func(key string) bitmap {
+			return newCountingBitmap(redisConnection, key, bits, hashes)
+		})
+	}
+
+	return newRedisFilter(redisConnection, conf.Key, conf.Replica, func(key string) bitmap {
+		return newBloomBitmap(redisConnection, key, bits, hashes)
+	})
+}
+
+// sizeOf 获取过滤器的位数和哈希函数个数，配置了ExpectedItems时由预期元素个数和误判率计算，
+// 否则使用配置的位数和与go-zero布隆过滤器相同的哈希函数个数
+func sizeOf(conf config.BloomFilterConf) (bits, hashes uint) {
+	if conf.ExpectedItems > 0 {
+		return Size(conf.ExpectedItems, conf.FalsePositiveRate)
+	}
+	return conf.Bits, defaultHashes
+}
+
+// locations 计算数据对应的位置，与go-zero布隆过滤器的取位方式一致
+func locations(data []byte, bits, hashes uint) []uint {
+	locations := make([]uint, hashes)
+	for i := uint(0); i < hashes; i++ {
+		hashValue := hash.Hash(append(data, byte(i)))
+		locations[i] = uint(hashValue % uint64(bits))
+	}
+	return locations
+}
+
+// bitmap 存储在单个Redis键上的过滤器
+type bitmap interface {
+	AddCtx(ctx context.Context, data []byte) error
+	ExistsCtx(ctx context.Context, data []byte) (bool, error)
+	RemoveCtx(ctx context.Context, data []byte) error
+}
+
+// newBloomBitmap 创建不支持删除的布隆过滤器，哈希函数个数为14时与go-zero布隆过滤器的位图兼容
+func newBloomBitmap(rdb *redis.Redis, key string, bits, hashes uint) *bloomBitmap {
+	return &bloomBitmap{
+		rdb:    rdb,
+		key:    key,
+		bits:   bits,
+		hashes: hashes,
+	}
+}
+
+type bloomBitmap struct {
+	rdb    *redis.Redis
+	key    string
+	bits   uint
+	hashes uint
+}
+
+func (b *bloomBitmap) AddCtx(ctx context.Context, data []byte) error {
+	_, err := b.rdb.EvalCtx(ctx, bloomAddScript, []string{b.key}, b.locations(data))
+	return err
+}
+
+func (b *bloomBitmap) ExistsCtx(ctx context.Context, data []byte) (bool, error) {
+	resp, err := b.rdb.EvalCtx(ctx, bloomExistsScript, []string{b.key}, b.locations(data))
+	if err != nil {
+		return false, err
+	}
+
+	exists, ok := resp.(int64)
+	return ok && exists == 1, nil
+}
+
+// RemoveCtx 普通布隆过滤器无法删除，残留的位只会让查询多走一次数据库
+func (b *bloomBitmap) RemoveCtx(context.Context, []byte) error {
+	return nil
+}
+
+// locations 计算数据对应的位的位置
+func (b *bloomBitmap) locations(data []byte) []string {
+	offsets := locations(data, b.bits, b.hashes)
+	args := make([]string, len(offsets))
+	for i, offset := range offsets {
+		args[i] = strconv.FormatUint(uint64(offset), 10)
+	}
+	return args
+}
+
+// newRedisFilter 创建Redis过滤器，versioned为true时每次增删都增加位图版本号，供副本判断是否需要重新加载快照
+func newRedisFilter(rdb *redis.Redis, key string, versioned bool, newBitmap func(key string) bitmap) *redisFilter {
+	return &redisFilter{
+		rdb:        rdb,
+		key:        key,
+		lockKey:    key + rebuildKeySuffix,
+		versionKey: key + versionKeySuffix,
+		versioned:  versioned,
+		newBitmap:  newBitmap,
+		live:       newBitmap(key),
+	}
+}
+
+type redisFilter struct {
+	rdb        *redis.Redis
+	key        string
+	lockKey    string
+	versionKey string
+	versioned  bool
+	newBitmap  func(key string) bitmap
+	live       bitmap
+
+	// 最近一次读取的重建标记，每隔markerRefresh重新读取
+	marker atomic.Pointer[rebuildMarker]
+}
+
+// rebuildMarker 正在进行的重建，building为nil时没有重建
+type rebuildMarker struct {
+	token     string
+	building  bitmap
+	checkedAt time.Time
+}
+
+func (f *redisFilter) AddCtx(ctx context.Context, data []byte) error {
+	if err := f.live.AddCtx(ctx, data); err != nil {
+		return err
+	}
+	f.bump(ctx)
+
+	if building := f.building(ctx); building != nil {
+		return building.AddCtx(ctx, data)
+	}
+	return nil
+}
+
+func (f *redisFilter) ExistsCtx(ctx context.Context, data []byte) (bool, error) {
+	// 重建期间位图可能不完整，跳过过滤直接查询数据库
+	if f.building(ctx) != nil {
+		return true, nil
+	}
+	return f.live.ExistsCtx(ctx, data)
+}
+
+func (f *redisFilter) RemoveCtx(ctx context.Context, data []byte) error {
+	if err := f.live.RemoveCtx(ctx, data); err != nil {
+		return err
+	}
+	f.bump(ctx)
+
+	if building := f.building(ctx); building != nil {
+		return building.RemoveCtx(ctx, data)
+	}
+	return nil
+}
+
+// bump 位图修改后增加版本号，失败只记录日志，副本版本号不一致时会重新加载快照。
+// 未启用副本时没有读取版本号的一方，不额外访问Redis
+func (f *redisFilter) bump(ctx context.Context) {
+	if !f.versioned {
+		return
+	}
+
+	if _, err := f.rdb.IncrCtx(ctx, f.versionKey); err != nil {
+		logx.WithContext(ctx).Errorf("bump bloom filter version failed,key:%v,err:%v", f.versionKey, err)
+	}
+}
+
+func (f *redisFilter) Rebuilding(ctx context.Context) bool {
+	return f.building(ctx) != nil
+}
+
+// building 获取正在重建的临时位图，没有重建时返回nil。
+// 重建标记在本地缓存markerRefresh，读取失败时沿用上一次的结果
+func (f *redisFilter) building(ctx context.Context) bitmap {
+	current := f.marker.Load()
+	if current != nil && time.Since(current.checkedAt) < markerRefresh {
+		return current.building
+	}
+
+	next := &rebuildMarker{checkedAt: time.Now()}
+	token, err := f.rdb.GetCtx(ctx, f.lockKey)
+	switch {
+	case err != nil:
+		logx.WithContext(ctx).Errorf("get bloom filter rebuild marker failed,key:%v,err:%v", f.lockKey, err)
+		if current != nil {
+			next.token, next.building = current.token, current.building
+		}
+	case len(token) == 0:
+	case current != nil && current.token == token:
+		next.token, next.building = token, current.building
+	default:
+		next.token, next.building = token, f.newBitmap(f.buildingKey(token))
+	}
+
+	f.marker.Store(next)
+	return next.building
+}
+
+func (f *redisFilter) buildingKey(token string) string {
+	return f.lockKey + ":" + token
+}
+
+func (f *redisFilter) Lost(ctx context.Context) (bool, error) {
+	exists, err := f.rdb.ExistsCtx(ctx, f.key)
+	if err != nil {
+		return false, errorx.NewWithCause(errorx.CodeCacheError, "check bloom filter key failed", err).
+			WithMeta("key", f.key)
+	}
+	return !exists, nil
+}
+
+func (f *redisFilter) Rebuild(ctx context.Context, source Source) error {
+	token := stringx.Randn(tokenLength)
+	locked, err := f.rdb.SetnxExCtx(ctx, f.lockKey, token, int(rebuildLockExpire/time.Second))
+	if err != nil {
+		return errorx.NewWithCause(errorx.CodeCacheError, "acquire bloom filter rebuild lock failed", err).
+			WithMeta("key", f.lockKey)
+	}
+	if !locked {
+		return errorx.New(errorx.CodeTooFrequent, "bloom filter rebuild is already running").
+			WithMeta("key", f.key)
+	}
+
+	tmpKey := f.buildingKey(token)
+	building := f.newBitmap(tmpKey)
+	f.marker.Store(&rebuildMarker{token: token, building: building, checkedAt: time.Now()})
+	defer f.release(token, tmpKey)
+
+	var total int
+	err = source(ctx, func(batch [][]byte) error {
+		for _, data := range batch {
+			if err := building.AddCtx(ctx, data); err != nil {
+				return errorx.NewWithCause(errorx.CodeCacheError, "add data to rebuilding bloom filter failed", err).
+					WithMeta("key", tmpKey)
+			}
+		}
+		if err := f.renew(ctx, token, tmpKey); err != nil {
+			return err
+		}
+
+		total += len(batch)
+		logx.WithContext(ctx).Infof("bloom filter rebuilding,key:%v,added:%v", f.key, total)
+		return nil
+	})
+	if err != nil {
+		// 保留旧位图，未完成的临时位图在release中清理
+		return errorx.Wrap(err, errorx.CodeSystemError, "rebuild bloom filter failed").WithMeta("key", f.key)
+	}
+
+	swapped, err := f.rdb.EvalCtx(ctx, swapScript, []string{f.lockKey, tmpKey, f.key, f.versionKey}, token)
+	if err != nil {
+		return errorx.NewWithCause(errorx.CodeCacheError, "swap bloom filter key failed", err).
+			WithMeta("key", f.key)
+	}
+	if swapped != int64(1) {
+		return errorx.New(errorx.CodeTimeout, "bloom filter rebuild lock expired before swap").
+			WithMeta("key", f.key)
+	}
+
+	logx.WithContext(ctx).Infof("bloom filter rebuilt,key:%v,total:%v", f.key, total)
+	return nil
+}
+
+// renew 续期重建锁，锁已过期或被其他实例持有时终止重建，避免替换不完整的位图
+func (f *redisFilter) renew(ctx context.Context, token, tmpKey string) error {
+	expire := strconv.FormatInt(rebuildLockExpire.Milliseconds(), 10)
+	renewed, err := f.rdb.EvalCtx(ctx, renewScript, []string{f.lockKey, tmpKey}, token, expire)
+	if err != nil {
+		return errorx.NewWithCause(errorx.CodeCacheError, "renew bloom filter rebuild lock failed", err).
+			WithMeta("key", f.lockKey)
+	}
+	if renewed != int64(1) {
+		return errorx.New(errorx.CodeTimeout, "bloom filter rebuild lock expired").WithMeta("key", f.lockKey)
+	}
+	return nil
+}
+
+// release 释放重建锁并删除临时位图。其他实例在重建标记的缓存过期前仍可能写入临时位图，
+// 等待两个markerRefresh后再删除一次
+func (f *redisFilter) release(token, tmpKey string) {
+	f.marker.Store(&rebuildMarker{checkedAt: time.Now()})
+
+	ctx := context.Background()
+	if _, err := f.rdb.EvalCtx(ctx, releaseScript, []string{f.lockKey}, token); err != nil {
+		logx.Errorf("release bloom filter rebuild lock failed,key:%v,err:%v", f.lockKey, err)
+	}
+
+	del := func() {
+		if _, err := f.rdb.DelCtx(ctx, tmpKey); err != nil {
+			logx.Errorf("clear bloom filter rebuild key failed,key:%v,err:%v", tmpKey, err)
+		}
+	}
+	del()
+	time.AfterFunc(2*markerRefresh, del)
 }
@@ -0,0 +1,267 @@
+package filter
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/bloom"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"shortener/internal/config"
+	"shortener/internal/types/errorx"
+)
+
+func newTestFilter(t *testing.T) (*redisFilter, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	rdb := redis.New(mr.Addr())
+	return newRedisFilter(rdb, "filter", false, func(key string) bitmap {
+		return newBloomBitmap(rdb, key, 1024, defaultHashes)
+	}), mr
+}
+
+func TestBloomFilter_Rebuild(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("丢失后重建", func(t *testing.T) {
+		f, mr := newTestFilter(t)
+		assert.NoError(t, f.AddCtx(ctx, []byte("a")))
+
+		lost, err := f.Lost(ctx)
+		assert.NoError(t, err)
+		assert.False(t, lost)
+
+		mr.Del("filter")
+		lost, err = f.Lost(ctx)
+		assert.NoError(t, err)
+		assert.True(t, lost)
+
+		err = f.Rebuild(ctx, func(ctx context.Context, fn func(batch [][]byte) error) error {
+			if err := fn([][]byte{[]byte("a"), []byte("b")}); err != nil {
+				return err
+			}
+			return fn([][]byte{[]byte("c")})
+		})
+		assert.NoError(t, err)
+
+		for _, key := range []string{"a", "b", "c"} {
+			exist, err := f.ExistsCtx(ctx, []byte(key))
+			assert.NoError(t, err)
+			assert.True(t, exist, key)
+		}
+		assert.False(t, mr.Exists("filter"+rebuildKeySuffix))
+	})
+
+	t.Run("重建期间跳过过滤并同时写入新位图", func(t *testing.T) {
+		f, _ := newTestFilter(t)
+
+		err := f.Rebuild(ctx, func(ctx context.Context, fn func(batch [][]byte) error) error {
+			exist, err := f.ExistsCtx(ctx, []byte("unknown"))
+			assert.NoError(t, err)
+			assert.True(t, exist, "重建期间应跳过过滤")
+
+			assert.NoError(t, f.AddCtx(ctx, []byte("new")))
+			return fn([][]byte{[]byte("old")})
+		})
+		assert.NoError(t, err)
+
+		exist, err := f.ExistsCtx(ctx, []byte("new"))
+		assert.NoError(t, err)
+		assert.True(t, exist, "重建期间新增的数据不应丢失")
+
+		exist, err = f.ExistsCtx(ctx, []byte("unknown"))
+		assert.NoError(t, err)
+		assert.False(t, exist)
+	})
+
+	t.Run("数据源出错时保留旧位图", func(t *testing.T) {
+		f, mr := newTestFilter(t)
+		assert.NoError(t, f.AddCtx(ctx, []byte("a")))
+
+		err := f.Rebuild(ctx, func(ctx context.Context, fn func(batch [][]byte) error) error {
+			if err := fn([][]byte{[]byte("b")}); err != nil {
+				return err
+			}
+			return errors.New("database error")
+		})
+		assert.Error(t, err)
+
+		exist, err := f.ExistsCtx(ctx, []byte("a"))
+		assert.NoError(t, err)
+		assert.True(t, exist)
+		assert.False(t, mr.Exists("filter"+rebuildKeySuffix))
+	})
+
+	t.Run("其他实例重建期间同时写入新位图", func(t *testing.T) {
+		f, mr := newTestFilter(t)
+		assert.NoError(t, mr.Set("filter"+rebuildKeySuffix, "token"))
+
+		err := f.Rebuild(ctx, func(ctx context.Context, fn func(batch [][]byte) error) error { return nil })
+		assert.True(t, errorx.Is(err, errorx.CodeTooFrequent))
+		assert.True(t, f.Rebuilding(ctx))
+
+		exist, err := f.ExistsCtx(ctx, []byte("unknown"))
+		assert.NoError(t, err)
+		assert.True(t, exist, "重建期间应跳过过滤")
+
+		assert.NoError(t, f.AddCtx(ctx, []byte("new")))
+		assert.True(t, mr.Exists("filter"+rebuildKeySuffix+":token"))
+	})
+
+	t.Run("锁过期时不替换位图", func(t *testing.T) {
+		f, mr := newTestFilter(t)
+		assert.NoError(t, f.AddCtx(ctx, []byte("a")))
+
+		err := f.Rebuild(ctx, func(ctx context.Context, fn func(batch [][]byte) error) error {
+			mr.Del("filter" + rebuildKeySuffix)
+			return fn([][]byte{[]byte("b")})
+		})
+		assert.Error(t, err)
+
+		exist, err := f.ExistsCtx(ctx, []byte("a"))
+		assert.NoError(t, err)
+		assert.True(t, exist)
+		assert.ElementsMatch(t, []string{"filter"}, mr.Keys())
+	})
+
+	t.Run("不允许并发重建", func(t *testing.T) {
+		f, _ := newTestFilter(t)
+
+		err := f.Rebuild(ctx, func(ctx context.Context, fn func(batch [][]byte) error) error {
+			return f.Rebuild(ctx, func(ctx context.Context, fn func(batch [][]byte) error) error { return nil })
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestCountingBitmap(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	c := newCountingBitmap(redis.New(mr.Addr()), "counting", 1024, 7)
+
+	assert.NoError(t, c.AddCtx(ctx, []byte("a")))
+	assert.NoError(t, c.AddCtx(ctx, []byte("b")))
+	assert.NoError(t, c.AddCtx(ctx, []byte("b")))
+
+	exist, err := c.ExistsCtx(ctx, []byte("a"))
+	assert.NoError(t, err)
+	assert.True(t, exist)
+
+	t.Run("删除后不再存在且不影响其他数据", func(t *testing.T) {
+		assert.NoError(t, c.RemoveCtx(ctx, []byte("a")))
+
+		exist, err := c.ExistsCtx(ctx, []byte("a"))
+		assert.NoError(t, err)
+		assert.False(t, exist)
+
+		exist, err = c.ExistsCtx(ctx, []byte("b"))
+		assert.NoError(t, err)
+		assert.True(t, exist)
+	})
+
+	t.Run("重复添加需要删除相同次数", func(t *testing.T) {
+		assert.NoError(t, c.RemoveCtx(ctx, []byte("b")))
+		exist, err := c.ExistsCtx(ctx, []byte("b"))
+		assert.NoError(t, err)
+		assert.True(t, exist)
+
+		assert.NoError(t, c.RemoveCtx(ctx, []byte("b")))
+		exist, err = c.ExistsCtx(ctx, []byte("b"))
+		assert.NoError(t, err)
+		assert.False(t, exist)
+	})
+
+	t.Run("删除不存在的数据不影响计数", func(t *testing.T) {
+		assert.NoError(t, c.AddCtx(ctx, []byte("c")))
+		assert.NoError(t, c.RemoveCtx(ctx, []byte("missing")))
+
+		exist, err := c.ExistsCtx(ctx, []byte("c"))
+		assert.NoError(t, err)
+		assert.True(t, exist)
+	})
+
+	t.Run("计数饱和后不再递减", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			assert.NoError(t, c.AddCtx(ctx, []byte("hot")))
+		}
+		for i := 0; i < 20; i++ {
+			assert.NoError(t, c.RemoveCtx(ctx, []byte("hot")))
+		}
+
+		exist, err := c.ExistsCtx(ctx, []byte("hot"))
+		assert.NoError(t, err)
+		assert.True(t, exist)
+	})
+}
+
+func TestSize(t *testing.T) {
+	bits, hashes := Size(1000000, 0.01)
+	assert.Equal(t, uint(9585059), bits)
+	assert.Equal(t, uint(7), hashes)
+
+	bits, hashes = Size(1000000, 0.001)
+	assert.Equal(t, uint(14377588), bits)
+	assert.Equal(t, uint(10), hashes)
+
+	// 非法误判率使用默认值
+	defaultBits, _ := Size(1000000, 0)
+	assert.Equal(t, bits, defaultBits)
+}
+
+func TestSizeOf(t *testing.T) {
+	// 配置了ExpectedItems时，两种过滤器都由位数推导哈希函数个数 k = m/n·ln2
+	for _, typ := range []string{config.FilterTypeBloom, config.FilterTypeCounting} {
+		bits, hashes := sizeOf(config.BloomFilterConf{Type: typ, ExpectedItems: 1000000, FalsePositiveRate: 0.01})
+		assert.Equal(t, uint(9585059), bits, typ)
+		assert.Equal(t, uint(math.Round(float64(bits)/1000000*math.Ln2)), hashes, typ)
+		assert.Equal(t, uint(7), hashes, typ)
+	}
+
+	// 直接配置位数时沿用go-zero布隆过滤器的哈希函数个数，保持已有位图兼容
+	bits, hashes := sizeOf(config.BloomFilterConf{Type: config.FilterTypeBloom, Bits: 1 << 20})
+	assert.Equal(t, uint(1<<20), bits)
+	assert.Equal(t, uint(defaultHashes), hashes)
+}
+
+func TestBloomBitmap(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.New(mr.Addr())
+
+	// 哈希函数个数为14时与go-zero布隆过滤器的位图兼容
+	legacy := bloom.New(rdb, "legacy", 1024)
+	assert.NoError(t, legacy.AddCtx(ctx, []byte("a")))
+	exist, err := newBloomBitmap(rdb, "legacy", 1024, defaultHashes).ExistsCtx(ctx, []byte("a"))
+	assert.NoError(t, err)
+	assert.True(t, exist)
+
+	b := newBloomBitmap(rdb, "derived", 1024, 7)
+	assert.NoError(t, b.AddCtx(ctx, []byte("a")))
+	exist, err = b.ExistsCtx(ctx, []byte("a"))
+	assert.NoError(t, err)
+	assert.True(t, exist)
+	exist, err = b.ExistsCtx(ctx, []byte("b"))
+	assert.NoError(t, err)
+	assert.False(t, exist)
+}
+
+func TestBloomFilter_Version(t *testing.T) {
+	ctx := context.Background()
+
+	// 未启用副本时增删不修改版本号
+	f, mr := newTestFilter(t)
+	assert.NoError(t, f.AddCtx(ctx, []byte("a")))
+	assert.False(t, mr.Exists("filter"+versionKeySuffix))
+
+	rdb := redis.New(mr.Addr())
+	versioned := newRedisFilter(rdb, "filter", true, func(key string) bitmap {
+		return newBloomBitmap(rdb, key, 1024, defaultHashes)
+	})
+	assert.NoError(t, versioned.AddCtx(ctx, []byte("b")))
+	assert.NoError(t, versioned.RemoveCtx(ctx, []byte("b")))
+	version, err := mr.Get("filter" + versionKeySuffix)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", version)
+}
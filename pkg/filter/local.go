@@ -0,0 +1,93 @@
+package filter
+
+// localBitmap 进程内的位图，存储格式与Redis中的位图一致，可以直接加载Redis的快照。
+// 普通布隆过滤器每个位置占1位，计数布隆过滤器每个位置占4位。
+type localBitmap struct {
+	data     []byte
+	bits     uint
+	hashes   uint
+	counting bool
+}
+
+// add 按与Redis脚本相同的规则写入数据
+func (b *localBitmap) add(data []byte) {
+	for _, o := range locations(data, b.bits, b.hashes) {
+		if !b.counting {
+			b.grow(o / 8)
+			b.data[o/8] |= 0x80 >> (o % 8)
+			continue
+		}
+
+		b.grow(o / 2)
+		if v := b.counter(o); v < 15 {
+			b.setCounter(o, v+1)
+		}
+	}
+}
+
+// remove 只有计数布隆过滤器支持删除，任一计数器为0时说明数据不存在，不做修改
+func (b *localBitmap) remove(data []byte) {
+	if !b.counting {
+		return
+	}
+
+	offsets := locations(data, b.bits, b.hashes)
+	for _, o := range offsets {
+		if b.counter(o) == 0 {
+			return
+		}
+	}
+	for _, o := range offsets {
+		// 计数器饱和后无法得知真实次数，不再递减
+		if v := b.counter(o); v < 15 {
+			b.setCounter(o, v-1)
+		}
+	}
+}
+
+// test 判断数据是否可能存在
+func (b *localBitmap) test(data []byte) bool {
+	for _, o := range locations(data, b.bits, b.hashes) {
+		if b.counting {
+			if b.counter(o) == 0 {
+				return false
+			}
+			continue
+		}
+
+		if o/8 >= uint(len(b.data)) || b.data[o/8]&(0x80>>(o%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// counter 读取4位计数器，偶数位置在高4位
+func (b *localBitmap) counter(o uint) byte {
+	if o/2 >= uint(len(b.data)) {
+		return 0
+	}
+
+	v := b.data[o/2]
+	if o%2 == 0 {
+		return v >> 4
+	}
+	return v & 0x0f
+}
+
+func (b *localBitmap) setCounter(o uint, v byte) {
+	old := b.data[o/2]
+	if o%2 == 0 {
+		b.data[o/2] = v<<4 | old&0x0f
+	} else {
+		b.data[o/2] = old&0xf0 | v
+	}
+}
+
+// grow 与Redis的SETBIT/SETRANGE一致，写入超出长度的位置时扩展位图
+func (b *localBitmap) grow(pos uint) {
+	if pos < uint(len(b.data)) {
+		return
+	}
+	b.data = append(b.data, make([]byte, int(pos)+1-len(b.data))...)
+}
@@ -0,0 +1,125 @@
+package filter
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"shortener/internal/config"
+	"shortener/internal/types/errorx"
+	"sync"
+)
+
+// NewMemoryFilter 创建进程内的过滤器，不依赖Redis，用于单机模式和测试。
+// 位图不会持久化，启动时需要从数据库重建，重建前Lost返回true。
+func NewMemoryFilter(conf config.BloomFilterConf) Rebuilder {
+	bits, hashes := sizeOf(conf)
+	return newMemoryFilter(bits, hashes, conf.Type == config.FilterTypeCounting)
+}
+
+func newMemoryFilter(bits, hashes uint, counting bool) *memoryFilter {
+	return &memoryFilter{
+		bits:     bits,
+		hashes:   hashes,
+		counting: counting,
+		live:     &localBitmap{bits: bits, hashes: hashes, counting: counting},
+	}
+}
+
+type memoryFilter struct {
+	bits     uint
+	hashes   uint
+	counting bool
+
+	mu   sync.RWMutex
+	live *localBitmap
+	// 重建中的临时位图，重建期间新增和删除的数据同时写入
+	building *localBitmap
+
+	// 同一时间只允许一个重建任务
+	rebuildLock sync.Mutex
+}
+
+func (f *memoryFilter) AddCtx(_ context.Context, data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.live.add(data)
+	if f.building != nil {
+		f.building.add(data)
+	}
+	return nil
+}
+
+func (f *memoryFilter) ExistsCtx(_ context.Context, data []byte) (bool, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	// 重建期间位图不完整，跳过过滤直接查询数据库
+	if f.building != nil {
+		return true, nil
+	}
+	return f.live.test(data), nil
+}
+
+func (f *memoryFilter) RemoveCtx(_ context.Context, data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.live.remove(data)
+	if f.building != nil {
+		f.building.remove(data)
+	}
+	return nil
+}
+
+// Lost 与Redis过滤器一致，位图为空时视为丢失
+func (f *memoryFilter) Lost(context.Context) (bool, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return len(f.live.data) == 0, nil
+}
+
+func (f *memoryFilter) Rebuilding(context.Context) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.building != nil
+}
+
+func (f *memoryFilter) Rebuild(ctx context.Context, source Source) error {
+	if !f.rebuildLock.TryLock() {
+		return errorx.New(errorx.CodeTooFrequent, "bloom filter rebuild is already running")
+	}
+	defer f.rebuildLock.Unlock()
+
+	building := &localBitmap{bits: f.bits, hashes: f.hashes, counting: f.counting}
+	f.mu.Lock()
+	f.building = building
+	f.mu.Unlock()
+
+	var total int
+	err := source(ctx, func(batch [][]byte) error {
+		f.mu.Lock()
+		for _, data := range batch {
+			building.add(data)
+		}
+		f.mu.Unlock()
+
+		total += len(batch)
+		logx.WithContext(ctx).Infof("memory bloom filter rebuilding,added:%v", total)
+		return nil
+	})
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.building = nil
+	if err != nil {
+		// 保留旧位图
+		return errorx.Wrap(err, errorx.CodeSystemError, "rebuild bloom filter failed")
+	}
+
+	f.live = building
+	logx.WithContext(ctx).Infof("memory bloom filter rebuilt,total:%v", total)
+	return nil
+}
@@ -0,0 +1,80 @@
+package filter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryFilter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("位图为空时视为丢失", func(t *testing.T) {
+		f := newMemoryFilter(1024, 7, false)
+
+		lost, err := f.Lost(ctx)
+		require.NoError(t, err)
+		assert.True(t, lost)
+
+		require.NoError(t, f.AddCtx(ctx, []byte("a")))
+		lost, err = f.Lost(ctx)
+		require.NoError(t, err)
+		assert.False(t, lost)
+
+		exist, err := f.ExistsCtx(ctx, []byte("a"))
+		require.NoError(t, err)
+		assert.True(t, exist)
+	})
+
+	t.Run("计数过滤器支持删除", func(t *testing.T) {
+		f := newMemoryFilter(1024, 7, true)
+		require.NoError(t, f.AddCtx(ctx, []byte("a")))
+		require.NoError(t, f.AddCtx(ctx, []byte("a")))
+
+		require.NoError(t, f.RemoveCtx(ctx, []byte("a")))
+		exist, _ := f.ExistsCtx(ctx, []byte("a"))
+		assert.True(t, exist)
+
+		require.NoError(t, f.RemoveCtx(ctx, []byte("a")))
+		exist, _ = f.ExistsCtx(ctx, []byte("a"))
+		assert.False(t, exist)
+	})
+
+	t.Run("重建替换位图，重建期间跳过过滤", func(t *testing.T) {
+		f := newMemoryFilter(1024, 7, false)
+		require.NoError(t, f.AddCtx(ctx, []byte("stale")))
+
+		err := f.Rebuild(ctx, func(ctx context.Context, fn func(batch [][]byte) error) error {
+			exist, _ := f.ExistsCtx(ctx, []byte("missing"))
+			assert.True(t, exist)
+
+			// 重建期间新增的数据同时写入新位图
+			require.NoError(t, f.AddCtx(ctx, []byte("during")))
+			return fn([][]byte{[]byte("a"), []byte("b")})
+		})
+		require.NoError(t, err)
+
+		for _, data := range []string{"a", "b", "during"} {
+			exist, _ := f.ExistsCtx(ctx, []byte(data))
+			assert.True(t, exist, data)
+		}
+		exist, _ := f.ExistsCtx(ctx, []byte("stale"))
+		assert.False(t, exist)
+	})
+
+	t.Run("重建失败时保留旧位图", func(t *testing.T) {
+		f := newMemoryFilter(1024, 7, false)
+		require.NoError(t, f.AddCtx(ctx, []byte("a")))
+
+		err := f.Rebuild(ctx, func(context.Context, func(batch [][]byte) error) error {
+			return errors.New("source failed")
+		})
+		assert.Error(t, err)
+
+		exist, _ := f.ExistsCtx(ctx, []byte("a"))
+		assert.True(t, exist)
+	})
+}
@@ -0,0 +1,41 @@
+package filter
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// filterMetrics contains all prometheus metrics of the short code filter.
+var filterMetrics = struct {
+	// bypasses tracks the operations that skipped the filter because it was unavailable,
+	// labeled by operation ("exists", "add" or "remove") and reason ("error" or "breaker_open").
+	bypasses *prometheus.CounterVec
+	// replays tracks the failed adds recorded in fail-open mode, labeled by result
+	// ("replayed" once written after recovery or "dropped" when the pending queue is full).
+	replays *prometheus.CounterVec
+}{
+	bypasses: prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "shortener",
+			Subsystem: "filter",
+			Name:      "bypass_total",
+			Help:      "Number of filter operations bypassed in fail-open mode by operation and reason",
+		},
+		[]string{"op", "reason"},
+	),
+	replays: prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "shortener",
+			Subsystem: "filter",
+			Name:      "replay_total",
+			Help:      "Number of failed filter adds replayed after recovery or dropped by result",
+		},
+		[]string{"result"},
+	),
+}
+
+// RegisterMetrics registers the filter metrics with the provided prometheus registerer.
+//
+// Example:
+//
+//	filter.RegisterMetrics(prometheus.DefaultRegisterer)
+func RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(filterMetrics.bypasses, filterMetrics.replays)
+}
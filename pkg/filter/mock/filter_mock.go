@@ -12,6 +12,7 @@ package filter
 import (
 	context "context"
 	reflect "reflect"
+	filter "shortener/pkg/filter"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -68,3 +69,194 @@ func (mr *MockFilterMockRecorder) ExistsCtx(ctx, data any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExistsCtx", reflect.TypeOf((*MockFilter)(nil).ExistsCtx), ctx, data)
 }
+
+// RemoveCtx mocks base method.
+func (m *MockFilter) RemoveCtx(ctx context.Context, data []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveCtx", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveCtx indicates an expected call of RemoveCtx.
+func (mr *MockFilterMockRecorder) RemoveCtx(ctx, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveCtx", reflect.TypeOf((*MockFilter)(nil).RemoveCtx), ctx, data)
+}
+
+// MockRebuilder is a mock of Rebuilder interface.
+type MockRebuilder struct {
+	ctrl     *gomock.Controller
+	recorder *MockRebuilderMockRecorder
+	isgomock struct{}
+}
+
+// MockRebuilderMockRecorder is the mock recorder for MockRebuilder.
+type MockRebuilderMockRecorder struct {
+	mock *MockRebuilder
+}
+
+// NewMockRebuilder creates a new mock instance.
+func NewMockRebuilder(ctrl *gomock.Controller) *MockRebuilder {
+	mock := &MockRebuilder{ctrl: ctrl}
+	mock.recorder = &MockRebuilderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRebuilder) EXPECT() *MockRebuilderMockRecorder {
+	return m.recorder
+}
+
+// AddCtx mocks base method.
+func (m *MockRebuilder) AddCtx(ctx context.Context, data []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddCtx", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddCtx indicates an expected call of AddCtx.
+func (mr *MockRebuilderMockRecorder) AddCtx(ctx, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddCtx", reflect.TypeOf((*MockRebuilder)(nil).AddCtx), ctx, data)
+}
+
+// ExistsCtx mocks base method.
+func (m *MockRebuilder) ExistsCtx(ctx context.Context, data []byte) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExistsCtx", ctx, data)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExistsCtx indicates an expected call of ExistsCtx.
+func (mr *MockRebuilderMockRecorder) ExistsCtx(ctx, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExistsCtx", reflect.TypeOf((*MockRebuilder)(nil).ExistsCtx), ctx, data)
+}
+
+// Lost mocks base method.
+func (m *MockRebuilder) Lost(ctx context.Context) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lost", ctx)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Lost indicates an expected call of Lost.
+func (mr *MockRebuilderMockRecorder) Lost(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lost", reflect.TypeOf((*MockRebuilder)(nil).Lost), ctx)
+}
+
+// Rebuild mocks base method.
+func (m *MockRebuilder) Rebuild(ctx context.Context, source filter.Source) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rebuild", ctx, source)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rebuild indicates an expected call of Rebuild.
+func (mr *MockRebuilderMockRecorder) Rebuild(ctx, source any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rebuild", reflect.TypeOf((*MockRebuilder)(nil).Rebuild), ctx, source)
+}
+
+// Rebuilding mocks base method.
+func (m *MockRebuilder) Rebuilding(ctx context.Context) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rebuilding", ctx)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Rebuilding indicates an expected call of Rebuilding.
+func (mr *MockRebuilderMockRecorder) Rebuilding(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rebuilding", reflect.TypeOf((*MockRebuilder)(nil).Rebuilding), ctx)
+}
+
+// RemoveCtx mocks base method.
+func (m *MockRebuilder) RemoveCtx(ctx context.Context, data []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveCtx", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveCtx indicates an expected call of RemoveCtx.
+func (mr *MockRebuilderMockRecorder) RemoveCtx(ctx, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveCtx", reflect.TypeOf((*MockRebuilder)(nil).RemoveCtx), ctx, data)
+}
+
+// Mockbitmap is a mock of bitmap interface.
+type Mockbitmap struct {
+	ctrl     *gomock.Controller
+	recorder *MockbitmapMockRecorder
+	isgomock struct{}
+}
+
+// MockbitmapMockRecorder is the mock recorder for Mockbitmap.
+type MockbitmapMockRecorder struct {
+	mock *Mockbitmap
+}
+
+// NewMockbitmap creates a new mock instance.
+func NewMockbitmap(ctrl *gomock.Controller) *Mockbitmap {
+	mock := &Mockbitmap{ctrl: ctrl}
+	mock.recorder = &MockbitmapMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *Mockbitmap) EXPECT() *MockbitmapMockRecorder {
+	return m.recorder
+}
+
+// AddCtx mocks base method.
+func (m *Mockbitmap) AddCtx(ctx context.Context, data []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddCtx", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddCtx indicates an expected call of AddCtx.
+func (mr *MockbitmapMockRecorder) AddCtx(ctx, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddCtx", reflect.TypeOf((*Mockbitmap)(nil).AddCtx), ctx, data)
+}
+
+// ExistsCtx mocks base method.
+func (m *Mockbitmap) ExistsCtx(ctx context.Context, data []byte) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExistsCtx", ctx, data)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExistsCtx indicates an expected call of ExistsCtx.
+func (mr *MockbitmapMockRecorder) ExistsCtx(ctx, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExistsCtx", reflect.TypeOf((*Mockbitmap)(nil).ExistsCtx), ctx, data)
+}
+
+// RemoveCtx mocks base method.
+func (m *Mockbitmap) RemoveCtx(ctx context.Context, data []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveCtx", ctx, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveCtx indicates an expected call of RemoveCtx.
+func (mr *MockbitmapMockRecorder) RemoveCtx(ctx, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveCtx", reflect.TypeOf((*Mockbitmap)(nil).RemoveCtx), ctx, data)
+}
@@ -0,0 +1,324 @@
+package filter
+
+import (
+	"context"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"github.com/zeromicro/go-zero/core/threading"
+	"shortener/internal/config"
+	"shortener/pkg/pubsub"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	eventAdd    = "a"
+	eventRemove = "r"
+	eventReload = "reload"
+
+	// eventSeparator 事件格式：实例ID|类型|数据
+	eventSeparator = "|"
+
+	// replicaLoadTimeout 加载位图快照的超时时间
+	replicaLoadTimeout = 10 * time.Second
+)
+
+// NewReplicaFilter 在进程内维护Redis位图的副本，ExistsCtx直接查询内存，不再访问Redis。
+// 副本启动时加载快照，之后通过发布订阅接收其他实例的增删，并定期比较位图的版本号，
+// 有事件丢失时才重新加载快照。快照未加载成功时回退到Redis过滤器。
+func NewReplicaFilter(f Rebuilder, conf config.BloomFilterConf, ps pubsub.PubSub) Rebuilder {
+	rdb, err := redis.NewRedis(redis.RedisConf{
+		Host: conf.Redis.Addr,
+		Type: conf.Redis.Type,
+		Pass: conf.Redis.Password,
+	})
+	if err != nil {
+		logx.Severef("NewReplicaFilter redis.NewRedis failed,err:%v", err)
+	}
+
+	bits, hashes := sizeOf(conf)
+	r := newReplicaFilter(f, rdb, conf.Key, bits, hashes, conf.Type == config.FilterTypeCounting, ps, conf.ReplicaChannel)
+	r.start(context.Background(), conf.ReplicaRefresh)
+	return r
+}
+
+func newReplicaFilter(
+	f Rebuilder,
+	rdb *redis.Redis,
+	key string,
+	bits, hashes uint,
+	counting bool,
+	ps pubsub.PubSub,
+	channel string,
+) *replicaFilter {
+	return &replicaFilter{
+		Rebuilder:  f,
+		rdb:        rdb,
+		key:        key,
+		versionKey: key + versionKeySuffix,
+		snapshot:   localBitmap{bits: bits, hashes: hashes, counting: counting},
+		ps:         ps,
+		channel:    channel,
+		id:         pubsub.NewInstanceID(),
+	}
+}
+
+type replicaFilter struct {
+	Rebuilder
+	rdb        *redis.Redis
+	key        string
+	versionKey string
+	ps         pubsub.PubSub
+	channel    string
+	// id 当前实例的标识，用于忽略自己发布的事件
+	id string
+
+	mu       sync.RWMutex
+	snapshot localBitmap
+	ready    bool
+	// version 副本对应的位图版本号，快照的版本号加上之后应用的事件数
+	version int64
+	// observed 最近一次读取的Redis版本号，副本的版本号落后时可能还有未收到的新增
+	observed int64
+	// loading 为true时记录收到的事件，快照加载完成后重放，避免丢失加载期间的增删
+	loading bool
+	pending []replicaEvent
+
+	// 本实例重建期间跳过过滤，直到重建后重新加载快照
+	rebuilding atomic.Bool
+}
+
+type replicaEvent struct {
+	op   string
+	data []byte
+}
+
+// start 订阅事件、加载快照并定期检查版本号
+func (r *replicaFilter) start(ctx context.Context, refresh time.Duration) {
+	r.ps.Subscribe(ctx, r.channel, r.handle)
+
+	if err := r.reload(ctx); err != nil {
+		logx.Errorf("load bloom filter replica failed,key:%v,err:%v", r.key, err)
+	}
+
+	if refresh <= 0 {
+		return
+	}
+
+	threading.GoSafe(func() {
+		ticker := time.NewTicker(refresh)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.refresh(ctx); err != nil {
+					logx.Errorf("refresh bloom filter replica failed,key:%v,err:%v", r.key, err)
+				}
+			}
+		}
+	})
+}
+
+func (r *replicaFilter) ExistsCtx(ctx context.Context, data []byte) (bool, error) {
+	// 任一实例重建期间副本可能不完整，跳过过滤
+	if r.rebuilding.Load() || r.Rebuilder.Rebuilding(ctx) {
+		return true, nil
+	}
+
+	r.mu.RLock()
+	if r.ready {
+		exist := r.snapshot.test(data)
+		behind := r.version < r.observed
+		r.mu.RUnlock()
+
+		// 副本落后时新建的短码可能还未同步，不存在的结果以Redis为准
+		if exist || !behind {
+			return exist, nil
+		}
+		return r.Rebuilder.ExistsCtx(ctx, data)
+	}
+	r.mu.RUnlock()
+
+	return r.Rebuilder.ExistsCtx(ctx, data)
+}
+
+func (r *replicaFilter) AddCtx(ctx context.Context, data []byte) error {
+	if err := r.Rebuilder.AddCtx(ctx, data); err != nil {
+		return err
+	}
+
+	r.apply(replicaEvent{op: eventAdd, data: data})
+	r.publish(ctx, eventAdd, data)
+	return nil
+}
+
+func (r *replicaFilter) RemoveCtx(ctx context.Context, data []byte) error {
+	if err := r.Rebuilder.RemoveCtx(ctx, data); err != nil {
+		return err
+	}
+
+	r.apply(replicaEvent{op: eventRemove, data: data})
+	r.publish(ctx, eventRemove, data)
+	return nil
+}
+
+func (r *replicaFilter) Rebuild(ctx context.Context, source Source) error {
+	r.rebuilding.Store(true)
+	defer r.rebuilding.Store(false)
+
+	if err := r.Rebuilder.Rebuild(ctx, source); err != nil {
+		return err
+	}
+
+	if err := r.reload(ctx); err != nil {
+		logx.Errorf("reload bloom filter replica after rebuild failed,key:%v,err:%v", r.key, err)
+	}
+	r.publish(ctx, eventReload, nil)
+	return nil
+}
+
+// refresh 比较Redis与副本的版本号，只在副本丢失了事件时重新加载快照。
+// 事件在Redis写入后发布，副本短暂落后是正常的；上一次检查时的版本号仍未追上，或副本超前（版本号被重置），
+// 说明有事件丢失
+func (r *replicaFilter) refresh(ctx context.Context) error {
+	val, err := r.rdb.GetCtx(ctx, r.versionKey)
+	if err != nil {
+		return err
+	}
+	remote, err := parseVersion(val)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	ready, version, previous := r.ready, r.version, r.observed
+	r.observed = remote
+	r.mu.Unlock()
+
+	if ready && (version == remote || version >= previous && version < remote) {
+		return nil
+	}
+	return r.reload(ctx)
+}
+
+// reload 从Redis加载位图快照并替换当前副本
+func (r *replicaFilter) reload(ctx context.Context) error {
+	r.mu.Lock()
+	r.loading = true
+	r.pending = nil
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, replicaLoadTimeout)
+	defer cancel()
+
+	// 位图和版本号一起读取，保证快照与版本号对应
+	vals, err := r.rdb.MgetCtx(ctx, r.key, r.versionKey)
+	var val string
+	var version int64
+	if err == nil {
+		val = vals[0]
+		version, err = parseVersion(vals[1])
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending := r.pending
+	r.loading = false
+	r.pending = nil
+
+	if err != nil {
+		return err
+	}
+	r.version, r.observed = version, version
+
+	// 位图丢失时回退到Redis过滤器，等待重建
+	if len(val) == 0 {
+		r.snapshot.data = nil
+		r.ready = false
+		return nil
+	}
+
+	// 快照可能已包含加载期间的事件，只重放幂等方向的新增：
+	// 重复计数只会多一次误判，重复删除却可能让存在的数据被拒绝。
+	// 重放的事件不计入版本号，未包含在快照中的事件由下一次检查发现并重新加载
+	r.snapshot.data = []byte(val)
+	for _, event := range pending {
+		if event.op == eventAdd {
+			r.applyLocked(event)
+		}
+	}
+	r.version = version
+	r.ready = true
+
+	logx.WithContext(ctx).Debugw("bloom filter replica loaded",
+		logx.Field("key", r.key),
+		logx.Field("size", len(val)),
+		logx.Field("replayed", len(pending)))
+	return nil
+}
+
+// handle 处理其他实例发布的事件
+func (r *replicaFilter) handle(message string) {
+	parts := strings.SplitN(message, eventSeparator, 3)
+	if len(parts) != 3 || parts[0] == r.id {
+		return
+	}
+
+	switch parts[1] {
+	case eventAdd, eventRemove:
+		r.apply(replicaEvent{op: parts[1], data: []byte(parts[2])})
+	case eventReload:
+		threading.GoSafe(func() {
+			if err := r.reload(context.Background()); err != nil {
+				logx.Errorf("reload bloom filter replica failed,key:%v,err:%v", r.key, err)
+			}
+		})
+	}
+}
+
+func (r *replicaFilter) publish(ctx context.Context, op string, data []byte) {
+	message := r.id + eventSeparator + op + eventSeparator + string(data)
+	if err := r.ps.Publish(ctx, r.channel, message); err != nil {
+		logx.WithContext(ctx).Errorf("publish bloom filter event failed,op:%v,err:%v", op, err)
+	}
+}
+
+func (r *replicaFilter) apply(event replicaEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.loading {
+		r.pending = append(r.pending, event)
+	}
+	r.applyLocked(event)
+}
+
+// applyLocked 按与Redis脚本相同的规则修改副本，调用方需持有写锁
+func (r *replicaFilter) applyLocked(event replicaEvent) {
+	if !r.ready && !r.loading {
+		return
+	}
+
+	switch event.op {
+	case eventAdd:
+		r.snapshot.add(event.data)
+	case eventRemove:
+		r.snapshot.remove(event.data)
+	}
+	r.version++
+}
+
+// parseVersion 解析位图的版本号，版本号不存在时为0
+func parseVersion(val string) (int64, error) {
+	if len(val) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
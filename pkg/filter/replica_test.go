@@ -0,0 +1,153 @@
+package filter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"shortener/pkg/pubsub"
+)
+
+func newTestReplica(rdb *redis.Redis, ps pubsub.PubSub, counting bool) *replicaFilter {
+	const bits, hashes = 1024, 7
+
+	f := newRedisFilter(rdb, "filter", true, func(key string) bitmap {
+		if counting {
+			return newCountingBitmap(rdb, key, bits, hashes)
+		}
+		return newBloomBitmap(rdb, key, bits, defaultHashes)
+	})
+
+	if !counting {
+		return newReplicaFilter(f, rdb, "filter", bits, defaultHashes, false, ps, "events")
+	}
+	return newReplicaFilter(f, rdb, "filter", bits, hashes, true, ps, "events")
+}
+
+func TestReplicaFilter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, counting := range []bool{false, true} {
+		name := "bloom"
+		if counting {
+			name = "counting"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			rdb := redis.New(mr.Addr())
+			ps := pubsub.NewMemoryPubSub()
+
+			// 副本启动前写入的数据通过快照加载
+			a := newTestReplica(rdb, ps, counting)
+			assert.NoError(t, a.Rebuilder.AddCtx(ctx, []byte("before")))
+			a.start(ctx, 0)
+
+			b := newTestReplica(rdb, ps, counting)
+			b.start(ctx, 0)
+
+			// 启动后的新增通过事件同步到其他实例
+			assert.NoError(t, a.AddCtx(ctx, []byte("after")))
+
+			// 关闭Redis后副本仍能直接判断
+			mr.Close()
+			for _, replica := range []*replicaFilter{a, b} {
+				for _, key := range []string{"before", "after"} {
+					exist, err := replica.ExistsCtx(ctx, []byte(key))
+					assert.NoError(t, err)
+					assert.True(t, exist, key)
+				}
+
+				exist, err := replica.ExistsCtx(ctx, []byte("unknown"))
+				assert.NoError(t, err)
+				assert.False(t, exist)
+			}
+		})
+	}
+
+	t.Run("计数过滤器同步删除", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		rdb := redis.New(mr.Addr())
+		ps := pubsub.NewMemoryPubSub()
+
+		a := newTestReplica(rdb, ps, true)
+		assert.NoError(t, a.Rebuilder.AddCtx(ctx, []byte("seed")))
+		a.start(ctx, 0)
+		b := newTestReplica(rdb, ps, true)
+		b.start(ctx, 0)
+
+		assert.NoError(t, a.AddCtx(ctx, []byte("gone")))
+		assert.NoError(t, a.RemoveCtx(ctx, []byte("gone")))
+
+		for _, replica := range []*replicaFilter{a, b} {
+			exist, err := replica.ExistsCtx(ctx, []byte("gone"))
+			assert.NoError(t, err)
+			assert.False(t, exist)
+		}
+	})
+
+	t.Run("位图不存在时回退到Redis过滤器", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		rdb := redis.New(mr.Addr())
+
+		r := newTestReplica(rdb, pubsub.NewMemoryPubSub(), false)
+		r.start(ctx, 0)
+		assert.False(t, r.ready)
+
+		mr.Close()
+		_, err := r.ExistsCtx(ctx, []byte("any"))
+		assert.Error(t, err)
+	})
+
+	t.Run("版本号一致时不重新加载快照", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		rdb := redis.New(mr.Addr())
+
+		r := newTestReplica(rdb, pubsub.NewMemoryPubSub(), false)
+		assert.NoError(t, r.Rebuilder.AddCtx(ctx, []byte("seed")))
+		r.start(ctx, 0)
+		assert.NoError(t, r.AddCtx(ctx, []byte("own")))
+
+		// 直接修改Redis中的位图，版本号不变时副本不会读取
+		assert.NoError(t, mr.Set("filter", ""))
+		assert.NoError(t, r.refresh(ctx))
+		assert.True(t, r.ready)
+		exist, err := r.ExistsCtx(ctx, []byte("own"))
+		assert.NoError(t, err)
+		assert.True(t, exist)
+	})
+
+	t.Run("副本落后时回退到Redis并在事件丢失后重新加载", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		rdb := redis.New(mr.Addr())
+
+		r := newTestReplica(rdb, pubsub.NewMemoryPubSub(), false)
+		assert.NoError(t, r.Rebuilder.AddCtx(ctx, []byte("seed")))
+		r.start(ctx, 0)
+
+		// 其他实例新增后事件尚未到达
+		other := newTestReplica(rdb, pubsub.NewMemoryPubSub(), false)
+		assert.NoError(t, other.AddCtx(ctx, []byte("recent")))
+
+		// 第一次检查视为事件在途，不重新加载，不存在的结果以Redis为准
+		assert.NoError(t, r.refresh(ctx))
+		assert.False(t, r.snapshot.test([]byte("recent")))
+		exist, err := r.ExistsCtx(ctx, []byte("recent"))
+		assert.NoError(t, err)
+		assert.True(t, exist)
+
+		// 下一次检查仍未追上，说明事件丢失，重新加载快照
+		assert.NoError(t, r.refresh(ctx))
+		assert.True(t, r.snapshot.test([]byte("recent")))
+		assert.Equal(t, r.observed, r.version)
+
+		// 追上后直接使用副本
+		mr.Close()
+		exist, err = r.ExistsCtx(ctx, []byte("unknown"))
+		assert.NoError(t, err)
+		assert.False(t, exist)
+	})
+}
@@ -0,0 +1,29 @@
+package filter
+
+import "math"
+
+const (
+	// defaultFalsePositiveRate 未配置误判率时使用的默认值
+	defaultFalsePositiveRate = 0.001
+	// maxHashes 哈希函数个数上限，Redis单次脚本执行的参数个数
+	maxHashes = 32
+)
+
+// Size 根据预期元素个数和目标误判率计算布隆过滤器的位数和哈希函数个数：
+// bits = -n*ln(p)/(ln2)^2，hashes = bits/n*ln2
+func Size(expectedItems uint64, falsePositiveRate float64) (bits, hashes uint) {
+	if expectedItems == 0 {
+		expectedItems = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = defaultFalsePositiveRate
+	}
+
+	n := float64(expectedItems)
+	m := math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / n * math.Ln2)
+
+	bits = uint(m)
+	hashes = uint(min(max(k, 1), maxHashes))
+	return bits, hashes
+}
@@ -0,0 +1,33 @@
+package limit
+
+import (
+	"context"
+	"golang.org/x/time/rate"
+	"time"
+)
+
+// NewLocalLimit 创建进程内的令牌桶限流器，每秒生成rate个令牌，最多积累burst个。
+// 限流只对当前实例生效，用于不依赖Redis的单机模式。
+func NewLocalLimit(r, burst int) Limit {
+	return &localLimit{limiter: rate.NewLimiter(rate.Limit(r), burst)}
+}
+
+type localLimit struct {
+	limiter *rate.Limiter
+}
+
+func (l *localLimit) Allow() bool {
+	return l.limiter.Allow()
+}
+
+func (l *localLimit) AllowCtx(_ context.Context) bool {
+	return l.limiter.Allow()
+}
+
+func (l *localLimit) AllowN(now time.Time, n int) bool {
+	return l.limiter.AllowN(now, n)
+}
+
+func (l *localLimit) AllowNCtx(_ context.Context, now time.Time, n int) bool {
+	return l.limiter.AllowN(now, n)
+}
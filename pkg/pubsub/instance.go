@@ -0,0 +1,16 @@
+package pubsub
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// NewInstanceID 生成当前实例的随机标识，发布的消息带上该标识，订阅者据此忽略自己发布的事件
+func NewInstanceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		logx.Errorf("generate instance id failed,err:%v", err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,35 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// NewMemoryPubSub 创建进程内的发布订阅，消息同步投递给当前进程的订阅者，用于单机模式和测试
+func NewMemoryPubSub() PubSub {
+	return &memoryPubSub{handlers: make(map[string][]func(message string))}
+}
+
+type memoryPubSub struct {
+	mu       sync.Mutex
+	handlers map[string][]func(message string)
+}
+
+func (p *memoryPubSub) Publish(_ context.Context, channel, message string) error {
+	p.mu.Lock()
+	handlers := p.handlers[channel]
+	p.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+	return nil
+}
+
+// Subscribe 进程内的订阅随进程结束，忽略ctx
+func (p *memoryPubSub) Subscribe(_ context.Context, channel string, handler func(message string)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.handlers[channel] = append(p.handlers[channel], handler)
+}
@@ -0,0 +1,67 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: pubsub.go
+//
+// Generated by this command:
+//
+//	mockgen -source=pubsub.go -destination=./mock/pubsub_mock.go -package=pubsub
+//
+
+// Package pubsub is a generated GoMock package.
+package pubsub
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockPubSub is a mock of PubSub interface.
+type MockPubSub struct {
+	ctrl     *gomock.Controller
+	recorder *MockPubSubMockRecorder
+	isgomock struct{}
+}
+
+// MockPubSubMockRecorder is the mock recorder for MockPubSub.
+type MockPubSubMockRecorder struct {
+	mock *MockPubSub
+}
+
+// NewMockPubSub creates a new mock instance.
+func NewMockPubSub(ctrl *gomock.Controller) *MockPubSub {
+	mock := &MockPubSub{ctrl: ctrl}
+	mock.recorder = &MockPubSubMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPubSub) EXPECT() *MockPubSubMockRecorder {
+	return m.recorder
+}
+
+// Publish mocks base method.
+func (m *MockPubSub) Publish(ctx context.Context, channel, message string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Publish", ctx, channel, message)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockPubSubMockRecorder) Publish(ctx, channel, message any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockPubSub)(nil).Publish), ctx, channel, message)
+}
+
+// Subscribe mocks base method.
+func (m *MockPubSub) Subscribe(ctx context.Context, channel string, handler func(string)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Subscribe", ctx, channel, handler)
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockPubSubMockRecorder) Subscribe(ctx, channel, handler any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockPubSub)(nil).Subscribe), ctx, channel, handler)
+}
@@ -0,0 +1,76 @@
+//go:generate mockgen -source=$GOFILE -destination=./mock/pubsub_mock.go -package=pubsub
+package pubsub
+
+import (
+	"context"
+	red "github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+	"shortener/internal/config"
+	"shortener/internal/types/errorx"
+)
+
+const redisTypeCluster = "cluster"
+
+// PubSub 用于在多个实例之间广播事件
+type PubSub interface {
+	// Publish 向频道发布消息
+	Publish(ctx context.Context, channel, message string) error
+	// Subscribe 在后台订阅频道，ctx结束时停止订阅，断线后自动重连
+	Subscribe(ctx context.Context, channel string, handler func(message string))
+}
+
+// NewRedisPubSub 创建基于Redis发布订阅的实现
+func NewRedisPubSub(conf config.RedisConf) PubSub {
+	var client red.UniversalClient
+	if conf.Type == redisTypeCluster {
+		client = red.NewClusterClient(&red.ClusterOptions{
+			Addrs:    []string{conf.Addr},
+			Password: conf.Password,
+		})
+	} else {
+		client = red.NewClient(&red.Options{
+			Addr:     conf.Addr,
+			Password: conf.Password,
+		})
+	}
+
+	return &redisPubSub{client: client}
+}
+
+type redisPubSub struct {
+	client red.UniversalClient
+}
+
+func (p *redisPubSub) Publish(ctx context.Context, channel, message string) error {
+	if err := p.client.Publish(ctx, channel, message).Err(); err != nil {
+		return errorx.NewWithCause(errorx.CodeCacheError, "publish message failed", err).
+			WithMeta("channel", channel)
+	}
+	return nil
+}
+
+func (p *redisPubSub) Subscribe(ctx context.Context, channel string, handler func(message string)) {
+	sub := p.client.Subscribe(ctx, channel)
+
+	threading.GoSafe(func() {
+		defer func() {
+			if err := sub.Close(); err != nil {
+				logx.Errorf("close subscription failed,channel:%v,err:%v", channel, err)
+			}
+		}()
+
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				handler(msg.Payload)
+			}
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package threat
+
+import (
+	"errors"
+	"golang.org/x/net/idna"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const (
+	// maxHostSuffixes 除完整主机外最多检查的上级域名个数，取自最后5级域名
+	maxHostSuffixes = 4
+	// maxPathPrefixes 除完整路径外最多检查的路径前缀个数
+	maxPathPrefixes = 4
+)
+
+var errEmptyHost = errors.New("the url has no host")
+
+// Expressions 按Safe Browsing的规则生成URL的全部匹配表达式：完整主机和上级域名 × 完整路径（含查询）和路径前缀。
+// 例如 http://a.b.example/1/2.html?q=1 生成 a.b.example/1/2.html?q=1、b.example/1/ 等表达式
+func Expressions(raw string) ([]string, error) {
+	host, p, query, err := canonicalParts(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := hostSuffixes(host)
+	paths := pathPrefixes(p, query)
+	expressions := make([]string, 0, len(hosts)*len(paths))
+	for _, h := range hosts {
+		for _, p := range paths {
+			expressions = append(expressions, h+p)
+		}
+	}
+	return expressions, nil
+}
+
+// exactExpression URL自身的表达式，用于URL列表
+func exactExpression(raw string) (string, error) {
+	host, p, query, err := canonicalParts(raw)
+	if err != nil {
+		return "", err
+	}
+	if len(query) != 0 {
+		return host + p + "?" + query, nil
+	}
+	return host + p, nil
+}
+
+// canonicalParts 将URL转换为规范的主机、路径和查询，没有协议时按http处理
+func canonicalParts(raw string) (host, p, query string, err error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	host = canonicalHost(u.Hostname())
+	if len(host) == 0 {
+		return "", "", "", errEmptyHost
+	}
+	return host, canonicalPath(u.EscapedPath()), escape(unescape(u.RawQuery)), nil
+}
+
+// canonicalHost 解码后去掉首尾和连续的点，转为小写，国际化域名转换为punycode
+func canonicalHost(host string) string {
+	host = unescape(host)
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.To4() != nil {
+			return ip.String()
+		}
+		return "[" + ip.String() + "]"
+	}
+
+	labels := strings.FieldsFunc(host, func(r rune) bool { return r == '.' })
+	host = strings.ToLower(strings.Join(labels, "."))
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		return ascii
+	}
+	return host
+}
+
+// canonicalPath 反复解码后处理 /./ 和 /../，合并连续的斜杠，保留末尾的斜杠，再重新编码
+func canonicalPath(escaped string) string {
+	p := unescape(escaped)
+	trailing := strings.HasSuffix(p, "/") || strings.HasSuffix(p, "/.") || strings.HasSuffix(p, "/..")
+
+	p = path.Clean("/" + p)
+	if trailing && p != "/" {
+		p += "/"
+	}
+	return escape(p)
+}
+
+// hostSuffixes 完整主机，以及从最后5级域名开始依次去掉最左一级得到的上级域名（至少两级），IP地址只有自身
+func hostSuffixes(host string) []string {
+	hosts := []string{host}
+	if net.ParseIP(strings.Trim(host, "[]")) != nil {
+		return hosts
+	}
+
+	labels := strings.Split(host, ".")
+	start := len(labels) - maxHostSuffixes - 1
+	if start < 1 {
+		start = 1
+	}
+	for i := start; i <= len(labels)-2; i++ {
+		hosts = append(hosts, strings.Join(labels[i:], "."))
+	}
+	return hosts
+}
+
+// pathPrefixes 完整路径（含查询和不含查询），以及从根路径开始逐级增加的路径前缀
+func pathPrefixes(p, query string) []string {
+	paths := make([]string, 0, maxPathPrefixes+2)
+	seen := make(map[string]struct{}, maxPathPrefixes+2)
+	add := func(p string) {
+		if _, ok := seen[p]; !ok {
+			seen[p] = struct{}{}
+			paths = append(paths, p)
+		}
+	}
+
+	if len(query) != 0 {
+		add(p + "?" + query)
+	}
+	add(p)
+
+	prefix := "/"
+	add(prefix)
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	for i := 0; i < len(segments)-1 && i < maxPathPrefixes-1; i++ {
+		prefix += segments[i] + "/"
+		add(prefix)
+	}
+	return paths
+}
+
+// unescape 反复解码百分号编码直到不再变化，不合法的编码原样保留
+func unescape(s string) string {
+	for strings.Contains(s, "%") {
+		decoded := unescapeOnce(s)
+		if decoded == s {
+			break
+		}
+		s = decoded
+	}
+	return s
+}
+
+func unescapeOnce(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			b.WriteByte(unhex(s[i+1])<<4 | unhex(s[i+2]))
+			i += 2
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// escape 编码控制字符、空格、非ASCII字符以及 # 和 %
+func escape(s string) string {
+	const hex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || c == '#' || c == '%' {
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0xf])
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
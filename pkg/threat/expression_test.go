@@ -0,0 +1,80 @@
+package threat
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestExpressions 测试生成匹配表达式，用例取自Safe Browsing的文档
+func TestExpressions(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expect []string
+	}{
+		{
+			name:  "主机和路径组合",
+			input: "http://a.b.c/1/2.html?param=1",
+			expect: []string{
+				"a.b.c/1/2.html?param=1", "a.b.c/1/2.html", "a.b.c/", "a.b.c/1/",
+				"b.c/1/2.html?param=1", "b.c/1/2.html", "b.c/", "b.c/1/",
+			},
+		},
+		{
+			name:  "最多取最后5级域名",
+			input: "http://a.b.c.d.e.f.g/1.html",
+			expect: []string{
+				"a.b.c.d.e.f.g/1.html", "a.b.c.d.e.f.g/",
+				"c.d.e.f.g/1.html", "c.d.e.f.g/",
+				"d.e.f.g/1.html", "d.e.f.g/",
+				"e.f.g/1.html", "e.f.g/",
+				"f.g/1.html", "f.g/",
+			},
+		},
+		{
+			name:   "最多4个路径前缀",
+			input:  "http://a.com/1/2/3/4/5/6.html",
+			expect: []string{"a.com/1/2/3/4/5/6.html", "a.com/", "a.com/1/", "a.com/1/2/", "a.com/1/2/3/"},
+		},
+		{name: "IP地址只有自身", input: "http://1.2.3.4/1/", expect: []string{"1.2.3.4/1/", "1.2.3.4/"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expressions, err := Expressions(tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expect, expressions)
+		})
+	}
+}
+
+// TestExactExpression 测试URL的规范化
+func TestExactExpression(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{input: "http://www.GOOgle.com/", expect: "www.google.com/"},
+		{input: "www.google.com", expect: "www.google.com/"},
+		{input: "http://...www.google.com.../", expect: "www.google.com/"},
+		{input: "http://www.google.com/blah/..", expect: "www.google.com/"},
+		{input: "http://www.google.com/#frag", expect: "www.google.com/"},
+		{input: "http://www.google.com/q?r?s", expect: "www.google.com/q?r?s"},
+		{input: "http://host.com//twoslashes?more//slashes", expect: "host.com/twoslashes?more//slashes"},
+		{input: "http://host.com/%25%32%35", expect: "host.com/%25"},
+		{input: "http://host.com/%257Ea%2521b%2540c%2523d%2524e%25f%255E00%252611%252A22%252833%252944_55%252B", expect: "host.com/~a!b@c%23d$e%25f^00&11*22(33)44_55+"},
+		{input: "http://168.188.99.26/%2E%73%65%63%75%72%65/%77%77%77%2E%65%62%61%79%2E%63%6F%6D/", expect: "168.188.99.26/.secure/www.ebay.com/"},
+		{input: "https://例子.测试/路径", expect: "xn--fsqu00a.xn--0zwm56d/%E8%B7%AF%E5%BE%84"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			expression, err := exactExpression(tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expect, expression)
+		})
+	}
+
+	_, err := exactExpression("http:///a")
+	assert.Error(t, err)
+}
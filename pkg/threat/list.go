@@ -0,0 +1,197 @@
+package threat
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/zeromicro/go-zero/core/logx"
+	"os"
+	"shortener/internal/config"
+	"shortener/internal/types/errorx"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// minPrefixSize 哈希前缀的最小字节数，与Safe Browsing一致。
+	// 不足完整哈希长度的前缀存在碰撞，只命中前缀的结果视为未确认
+	minPrefixSize = 4
+)
+
+// list 一个列表文件，全部条目转换为SHA-256前缀，按前缀长度分表
+type list struct {
+	name   string
+	tables []prefixTable // 按前缀长度升序
+
+	// 文件的修改时间和大小，用于判断是否需要重新加载
+	modTime time.Time
+	size    int64
+}
+
+// prefixTable 同一长度的哈希前缀，排序后连续存放，按二分查找匹配，
+// 百万条4字节前缀只占用4MB内存
+type prefixTable struct {
+	size int
+	data []byte
+}
+
+func (t prefixTable) len() int {
+	return len(t.data) / t.size
+}
+
+func (t prefixTable) at(i int) []byte {
+	return t.data[i*t.size : (i+1)*t.size]
+}
+
+func (t prefixTable) contains(hash []byte) bool {
+	prefix := hash[:t.size]
+	i := sort.Search(t.len(), func(i int) bool {
+		return bytes.Compare(t.at(i), prefix) >= 0
+	})
+	return i < t.len() && bytes.Equal(t.at(i), prefix)
+}
+
+// Len、Less、Swap 用于排序
+func (t prefixTable) Len() int           { return t.len() }
+func (t prefixTable) Less(i, j int) bool { return bytes.Compare(t.at(i), t.at(j)) < 0 }
+func (t prefixTable) Swap(i, j int) {
+	a, b := t.at(i), t.at(j)
+	for k := range a {
+		a[k], b[k] = b[k], a[k]
+	}
+}
+
+// match 判断任一表达式的哈希是否命中列表，verified表示命中的是完整哈希。
+// 从最长的前缀开始查找，有完整哈希命中时优先返回
+func (l *list) match(hashes [][sha256.Size]byte) (matched, verified bool) {
+	for t := len(l.tables) - 1; t >= 0; t-- {
+		table := l.tables[t]
+		for i := range hashes {
+			if table.contains(hashes[i][:]) {
+				return true, table.size == sha256.Size
+			}
+		}
+	}
+	return false, false
+}
+
+func (l *list) entries() int {
+	n := 0
+	for _, table := range l.tables {
+		n += table.len()
+	}
+	return n
+}
+
+// loadList 读取列表文件，不合法的行被跳过并记录日志，只有读取失败时返回错误
+func loadList(conf config.ThreatListConf, info os.FileInfo) (*list, error) {
+	file, err := os.Open(conf.Path)
+	if err != nil {
+		return nil, errorx.NewWithCause(errorx.CodeSystemError, "open the threat list file failed", err).
+			WithMeta("list", conf.Name)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			logx.Errorf("failed to close threat list file %s: %v", conf.Path, err)
+		}
+	}()
+
+	parse := parserOf(conf.Format)
+	buffers := make(map[int][]byte)
+	invalid := 0
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		prefix, ok := parse(line)
+		if !ok {
+			invalid++
+			continue
+		}
+		buffers[len(prefix)] = append(buffers[len(prefix)], prefix...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errorx.NewWithCause(errorx.CodeSystemError, "read the threat list file failed", err).
+			WithMeta("list", conf.Name)
+	}
+	if invalid > 0 {
+		logx.Errorf("skip invalid threat list entries,list:%v,count:%v", conf.Name, invalid)
+	}
+
+	l := &list{name: conf.Name, modTime: info.ModTime(), size: info.Size()}
+	for size, data := range buffers {
+		l.tables = append(l.tables, newPrefixTable(size, data))
+	}
+	sort.Slice(l.tables, func(i, j int) bool {
+		return l.tables[i].size < l.tables[j].size
+	})
+	return l, nil
+}
+
+// newPrefixTable 排序并去掉重复的前缀
+func newPrefixTable(size int, data []byte) prefixTable {
+	t := prefixTable{size: size, data: data}
+	sort.Sort(t)
+
+	n := 0
+	for i := 0; i < t.len(); i++ {
+		if n > 0 && bytes.Equal(t.at(i), t.at(n-1)) {
+			continue
+		}
+		copy(t.at(n), t.at(i))
+		n++
+	}
+	t.data = t.data[:n*size]
+	return t
+}
+
+// parserOf 返回将一行转换为哈希前缀的函数
+func parserOf(format string) func(line string) ([]byte, bool) {
+	switch format {
+	case config.ThreatListDomain:
+		return parseDomain
+	case config.ThreatListUrl:
+		return parseUrl
+	default:
+		return parseHash
+	}
+}
+
+// parseHash 十六进制的哈希前缀，4-32字节，只有32字节的完整哈希命中时才会拒绝
+func parseHash(line string) ([]byte, bool) {
+	prefix, err := hex.DecodeString(line)
+	if err != nil || len(prefix) < minPrefixSize || len(prefix) > sha256.Size {
+		return nil, false
+	}
+	return prefix, true
+}
+
+// parseDomain 域名转换为 domain/ 表达式的完整哈希，匹配该域名及其子域名下的全部路径。
+// 兼容hosts文件格式（如 0.0.0.0 evil.example）和 *.evil.example 写法
+func parseDomain(line string) ([]byte, bool) {
+	fields := strings.Fields(line)
+	domain := strings.TrimPrefix(fields[len(fields)-1], "*.")
+
+	host := canonicalHost(domain)
+	if len(host) == 0 || strings.ContainsAny(host, "/?") {
+		return nil, false
+	}
+	hash := sha256.Sum256([]byte(host + "/"))
+	return hash[:], true
+}
+
+// parseUrl URL转换为自身表达式的完整哈希，以斜杠结尾时匹配其下级路径
+func parseUrl(line string) ([]byte, bool) {
+	expression, err := exactExpression(line)
+	if err != nil {
+		return nil, false
+	}
+	hash := sha256.Sum256([]byte(expression))
+	return hash[:], true
+}
@@ -0,0 +1,141 @@
+// Package threat 使用本地同步的恶意链接列表检查目标地址，匹配在进程内完成，不访问外部服务
+//
+//go:generate mockgen -source=$GOFILE -destination=./mock/matcher_mock.go -package=threat
+package threat
+
+import (
+	"context"
+	"crypto/sha256"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+	"os"
+	"shortener/internal/config"
+	"shortener/internal/types/errorx"
+	"sync/atomic"
+	"time"
+)
+
+type Matcher interface {
+	// Match 判断URL是否命中任一列表，优先返回命中完整哈希的列表
+	Match(rawUrl string) (Result, bool)
+}
+
+// Result 命中的列表
+type Result struct {
+	List string
+	// Verified 命中完整哈希时为true。只命中哈希前缀时可能是前缀碰撞，结果未经确认，不能据此拒绝
+	Verified bool
+}
+
+// NewMatcher 加载全部列表文件，之后每隔Refresh检查文件是否更新并重新加载。
+// 列表加载失败时返回最后一个错误，其余列表仍然生效
+func NewMatcher(conf config.ThreatConf) (Matcher, error) {
+	m := &matcher{conf: conf.Lists}
+	err := m.refresh()
+	m.start(context.Background(), conf.Refresh)
+	return m, err
+}
+
+type matcher struct {
+	conf  []config.ThreatListConf
+	lists atomic.Pointer[[]*list]
+}
+
+func (m *matcher) Match(rawUrl string) (Result, bool) {
+	lists := m.lists.Load()
+	if lists == nil || len(*lists) == 0 {
+		return Result{}, false
+	}
+
+	// 无法解析的URL不会通过参数校验，这里视为未命中
+	expressions, err := Expressions(rawUrl)
+	if err != nil {
+		return Result{}, false
+	}
+	hashes := make([][sha256.Size]byte, len(expressions))
+	for i, expression := range expressions {
+		hashes[i] = sha256.Sum256([]byte(expression))
+	}
+
+	var unverified *Result
+	for _, l := range *lists {
+		matched, verified := l.match(hashes)
+		if verified {
+			return Result{List: l.name, Verified: true}, true
+		}
+		if matched && unverified == nil {
+			unverified = &Result{List: l.name}
+		}
+	}
+	if unverified != nil {
+		return *unverified, true
+	}
+	return Result{}, false
+}
+
+func (m *matcher) start(ctx context.Context, refresh time.Duration) {
+	if refresh <= 0 {
+		return
+	}
+
+	threading.GoSafe(func() {
+		ticker := time.NewTicker(refresh)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.refresh(); err != nil {
+					logx.Errorf("refresh threat lists failed,err:%v", err)
+				}
+			}
+		}
+	})
+}
+
+// refresh 重新加载修改时间或大小变化的列表文件，加载失败的列表保留上一次的内容
+func (m *matcher) refresh() error {
+	previous := make(map[string]*list)
+	if lists := m.lists.Load(); lists != nil {
+		for _, l := range *lists {
+			previous[l.name] = l
+		}
+	}
+
+	var lastErr error
+	lists := make([]*list, 0, len(m.conf))
+	for _, conf := range m.conf {
+		old := previous[conf.Name]
+
+		info, err := os.Stat(conf.Path)
+		if err != nil {
+			lastErr = errorx.NewWithCause(errorx.CodeSystemError, "stat the threat list file failed", err).
+				WithMeta("list", conf.Name)
+			if old != nil {
+				lists = append(lists, old)
+			}
+			continue
+		}
+
+		if old != nil && old.modTime.Equal(info.ModTime()) && old.size == info.Size() {
+			lists = append(lists, old)
+			continue
+		}
+
+		l, err := loadList(conf, info)
+		if err != nil {
+			lastErr = err
+			if old != nil {
+				lists = append(lists, old)
+			}
+			continue
+		}
+		logx.Infof("threat list loaded,list:%v,entries:%v", l.name, l.entries())
+		lists = append(lists, l)
+	}
+
+	m.lists.Store(&lists)
+	return lastErr
+}
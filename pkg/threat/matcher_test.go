@@ -0,0 +1,134 @@
+package threat
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"shortener/internal/config"
+	"testing"
+	"time"
+)
+
+func newFixtureMatcher(t *testing.T) Matcher {
+	m, err := NewMatcher(config.ThreatConf{
+		Lists: []config.ThreatListConf{
+			{Name: "hashes", Path: "testdata/hash_prefixes.txt", Format: config.ThreatListHash},
+			{Name: "domains", Path: "testdata/domains.txt", Format: config.ThreatListDomain},
+			{Name: "urls", Path: "testdata/urls.txt", Format: config.ThreatListUrl},
+		},
+	})
+	require.NoError(t, err)
+	return m
+}
+
+func TestMatcher_Match(t *testing.T) {
+	m := newFixtureMatcher(t)
+
+	tests := []struct {
+		name       string
+		input      string
+		expect     string
+		unverified bool
+	}{
+		{name: "哈希前缀匹配域名", input: "https://malware.example/a/b?c=1", expect: "hashes", unverified: true},
+		{name: "哈希前缀匹配子域名", input: "http://www.MALWARE.example/", expect: "hashes", unverified: true},
+		{name: "完整哈希匹配路径前缀", input: "https://phish.example/login/index.html", expect: "hashes"},
+		{name: "完整哈希不匹配其他路径", input: "https://phish.example/about", expect: ""},
+		{name: "域名列表", input: "https://cdn.evil.example/x.js", expect: "domains"},
+		{name: "hosts文件格式", input: "https://tracker.example/", expect: "domains"},
+		{name: "通配写法", input: "https://a.wild.example/", expect: "domains"},
+		{name: "国际化域名", input: "https://www.例子.测试/", expect: "domains"},
+		{name: "URL列表精确匹配", input: "http://scam.example/pay.html?id=1", expect: "urls"},
+		{name: "URL列表不匹配其他查询", input: "http://scam.example/pay.html?id=2", expect: ""},
+		{name: "URL列表以斜杠结尾时匹配下级路径", input: "https://files.example/downloads/setup.exe", expect: "urls"},
+		{name: "URL列表解码后比较", input: "http://scam.example/prize", expect: "urls"},
+		{name: "未命中", input: "https://example.com/", expect: ""},
+		{name: "相似域名未命中", input: "https://notevil.example/", expect: ""},
+		{name: "无法解析的URL", input: "http://[::1", expect: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := m.Match(tt.input)
+			assert.Equal(t, tt.expect, result.List)
+			assert.Equal(t, len(tt.expect) != 0, ok)
+			assert.Equal(t, ok && !tt.unverified, result.Verified)
+		})
+	}
+}
+
+func TestMatcher_MatchVerifiedFirst(t *testing.T) {
+	dir := t.TempDir()
+	hash := sha256.Sum256([]byte("mixed.example/"))
+	prefixes := filepath.Join(dir, "prefixes.txt")
+	hashes := filepath.Join(dir, "hashes.txt")
+	require.NoError(t, os.WriteFile(prefixes, []byte(hex.EncodeToString(hash[:4])+"\n"), 0644))
+	require.NoError(t, os.WriteFile(hashes, []byte(hex.EncodeToString(hash[:])+"\n"), 0644))
+
+	// 靠前的列表只命中前缀时，返回靠后命中完整哈希的列表
+	m := &matcher{conf: []config.ThreatListConf{
+		{Name: "prefixes", Path: prefixes},
+		{Name: "hashes", Path: hashes},
+	}}
+	require.NoError(t, m.refresh())
+	result, ok := m.Match("https://mixed.example/")
+	assert.True(t, ok)
+	assert.Equal(t, Result{List: "hashes", Verified: true}, result)
+
+	// 同一列表中前缀和完整哈希都命中时视为已确认
+	require.NoError(t, os.WriteFile(prefixes, []byte(hex.EncodeToString(hash[:4])+"\n"+hex.EncodeToString(hash[:])+"\n"), 0644))
+	m = &matcher{conf: []config.ThreatListConf{{Name: "prefixes", Path: prefixes}}}
+	require.NoError(t, m.refresh())
+	result, ok = m.Match("https://mixed.example/")
+	assert.True(t, ok)
+	assert.Equal(t, Result{List: "prefixes", Verified: true}, result)
+}
+
+func TestMatcher_Refresh(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "domains.txt")
+	require.NoError(t, os.WriteFile(path, []byte("old.example\n"), 0644))
+
+	m := &matcher{conf: []config.ThreatListConf{{Name: "feed", Path: path, Format: config.ThreatListDomain}}}
+	require.NoError(t, m.refresh())
+	_, ok := m.Match("http://old.example/")
+	assert.True(t, ok)
+
+	// 文件未修改时复用已加载的列表
+	loaded := (*m.lists.Load())[0]
+	require.NoError(t, m.refresh())
+	assert.Same(t, loaded, (*m.lists.Load())[0])
+
+	// 文件修改后重新加载
+	require.NoError(t, os.WriteFile(path, []byte("new.example\n"), 0644))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	require.NoError(t, m.refresh())
+	_, ok = m.Match("http://old.example/")
+	assert.False(t, ok)
+	_, ok = m.Match("http://new.example/")
+	assert.True(t, ok)
+
+	// 文件丢失时保留上一次的内容
+	require.NoError(t, os.Remove(path))
+	assert.Error(t, m.refresh())
+	_, ok = m.Match("http://new.example/")
+	assert.True(t, ok)
+}
+
+func TestNewPrefixTable(t *testing.T) {
+	hash := sha256.Sum256([]byte("a.example/"))
+	other := sha256.Sum256([]byte("b.example/"))
+
+	data := append(append(append([]byte{}, other[:4]...), hash[:4]...), other[:4]...)
+	table := newPrefixTable(4, data)
+
+	assert.Equal(t, 2, table.len())
+	assert.True(t, table.contains(hash[:]))
+	assert.True(t, table.contains(other[:]))
+
+	missing, _ := hex.DecodeString("00000000" + hex.EncodeToString(hash[4:]))
+	assert.False(t, table.contains(missing))
+}
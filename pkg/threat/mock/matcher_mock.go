@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: matcher.go
+//
+// Generated by this command:
+//
+//	mockgen -source=matcher.go -destination=./mock/matcher_mock.go -package=threat
+//
+
+// Package threat is a generated GoMock package.
+package threat
+
+import (
+	reflect "reflect"
+	threat "shortener/pkg/threat"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockMatcher is a mock of Matcher interface.
+type MockMatcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockMatcherMockRecorder
+	isgomock struct{}
+}
+
+// MockMatcherMockRecorder is the mock recorder for MockMatcher.
+type MockMatcherMockRecorder struct {
+	mock *MockMatcher
+}
+
+// NewMockMatcher creates a new mock instance.
+func NewMockMatcher(ctrl *gomock.Controller) *MockMatcher {
+	mock := &MockMatcher{ctrl: ctrl}
+	mock.recorder = &MockMatcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMatcher) EXPECT() *MockMatcherMockRecorder {
+	return m.recorder
+}
+
+// Match mocks base method.
+func (m *MockMatcher) Match(rawUrl string) (threat.Result, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Match", rawUrl)
+	ret0, _ := ret[0].(threat.Result)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// Match indicates an expected call of Match.
+func (mr *MockMatcherMockRecorder) Match(rawUrl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Match", reflect.TypeOf((*MockMatcher)(nil).Match), rawUrl)
+}
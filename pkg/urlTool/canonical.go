@@ -0,0 +1,158 @@
+package urlTool
+
+import (
+	"golang.org/x/net/idna"
+	"net"
+	"net/url"
+	"shortener/internal/config"
+	"sort"
+	"strings"
+)
+
+// defaultPorts 协议的默认端口，规范化时去掉
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Canonicalize 将URL转换为规范形式，用于判断两个写法不同的URL是否指向同一地址：
+// 协议和主机转为小写，国际化域名转换为punycode，去掉默认端口，统一百分号编码，空路径补为"/"，去掉空的查询和片段。
+// 按conf排序查询参数、去掉路径末尾的"/"，这两项可能改变部分网站的语义，默认不开启。
+// 规范形式只用于计算MD5，存储和跳转仍使用原始URL
+func Canonicalize(raw string, conf config.CanonicalConf) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	// mailto: 等不含主机的URL原样返回
+	if len(u.Opaque) != 0 || len(u.Host) == 0 {
+		return raw, nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(raw))
+
+	scheme := strings.ToLower(u.Scheme)
+	b.WriteString(scheme)
+	b.WriteString("://")
+
+	if u.User != nil {
+		b.WriteString(u.User.String())
+		b.WriteByte('@')
+	}
+	b.WriteString(canonicalHost(scheme, u))
+
+	path := normalizeEscapes(u.EscapedPath())
+	if conf.StripTrailingSlash {
+		path = strings.TrimRight(path, "/")
+	}
+	if len(path) == 0 {
+		path = "/"
+	}
+	b.WriteString(path)
+
+	if query := canonicalQuery(u.RawQuery, conf.SortQuery); len(query) != 0 {
+		b.WriteByte('?')
+		b.WriteString(query)
+	}
+
+	if fragment := u.EscapedFragment(); len(fragment) != 0 {
+		b.WriteByte('#')
+		b.WriteString(normalizeEscapes(fragment))
+	}
+	return b.String(), nil
+}
+
+// canonicalHost 主机转为小写，国际化域名转换为punycode，去掉协议的默认端口，IPv6地址保留方括号
+func canonicalHost(scheme string, u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		host = ascii
+	}
+	port := u.Port()
+	if port == defaultPorts[scheme] {
+		port = ""
+	}
+
+	if len(port) != 0 {
+		return net.JoinHostPort(host, port)
+	}
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// canonicalQuery 统一每个参数的百分号编码，去掉空参数，按需按参数名稳定排序，同名参数保持原有顺序
+func canonicalQuery(rawQuery string, sortQuery bool) string {
+	if len(rawQuery) == 0 {
+		return ""
+	}
+
+	params := make([]string, 0, strings.Count(rawQuery, "&")+1)
+	for _, param := range strings.Split(rawQuery, "&") {
+		if len(param) != 0 {
+			params = append(params, normalizeEscapes(param))
+		}
+	}
+
+	if sortQuery {
+		sort.SliceStable(params, func(i, j int) bool {
+			return queryKey(params[i]) < queryKey(params[j])
+		})
+	}
+	return strings.Join(params, "&")
+}
+
+func queryKey(param string) string {
+	key, _, _ := strings.Cut(param, "=")
+	return key
+}
+
+// normalizeEscapes 解码非保留字符的百分号编码（如 %7E -> ~），其余编码的十六进制统一为大写（%2f -> %2F），
+// 不合法的编码原样保留
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+// isUnreserved RFC 3986 2.3 的非保留字符，编码与否含义相同
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
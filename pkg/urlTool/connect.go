@@ -4,10 +4,13 @@ package urlTool
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/zeromicro/go-zero/core/logx"
 	"github.com/zeromicro/go-zero/core/syncx"
+	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"shortener/internal/config"
 	"shortener/internal/types/errorx"
 	"strings"
@@ -17,23 +20,65 @@ import (
 var globalSF = syncx.NewSingleFlight()
 
 var (
-	defaultDNSServer = "8.8.8.8:53"
-	defaultConfig    = config.ConnectConf{
-		DNSServer:       defaultDNSServer,
-		Timeout:         800 * time.Millisecond,
-		MaxRetries:      2,
-		MaxIdleConns:    100,
-		IdleConnTimeout: 30 * time.Second,
+	defaultDNSServer        = "8.8.8.8:53"
+	defaultFallbackMaxBytes = int64(1024)
+	// 重试等待时间翻倍的最大次数，避免移位溢出
+	maxBackoffShift = 30
+	defaultConfig   = config.ConnectConf{
+		DNSServer:           defaultDNSServer,
+		Timeout:             800 * time.Millisecond,
+		MaxRetries:          2,
+		RetryBackoff:        50 * time.Millisecond,
+		RetryMaxBackoff:     time.Second,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     30 * time.Second,
+		MaxRedirects:        5,
+		FallbackStatusCodes: []int{http.StatusForbidden, http.StatusMethodNotAllowed, http.StatusNotImplemented},
+		FallbackMaxBytes:    defaultFallbackMaxBytes,
 	}
 )
 
 type Client interface {
-	Check(url string) (bool, error)
+	// Check 检查URL是否可访问，目标返回非2xx时重试，重试后仍不可访问时返回最后一次的结果
+	Check(url string) (*Result, error)
+	// Resolve 逐跳跟随重定向并返回完整的重定向链，每一跳都会检查SSRF策略
+	Resolve(url string) (*Chain, error)
+}
+
+// Result 连通性检查结果
+type Result struct {
+	Reachable bool
+	// StatusCode 最终响应的状态码
+	StatusCode int
+}
+
+// Hop 重定向链中的一跳
+type Hop struct {
+	URL        string
+	StatusCode int
+}
+
+// Chain 重定向链，第一跳为原始地址，最后一跳为最终目标
+type Chain struct {
+	// Result 最终目标的检查结果
+	Result
+	Hops []Hop
+}
+
+// FinalUrl 重定向后的最终地址
+func (c *Chain) FinalUrl() string {
+	if len(c.Hops) == 0 {
+		return ""
+	}
+	return c.Hops[len(c.Hops)-1].URL
 }
 
 type clientImpl struct {
 	config config.ConnectConf
 	client *http.Client
+	// 不自动跟随重定向，用于逐跳解析重定向链
+	noRedirect *http.Client
+	policy     ipPolicy
 }
 
 func NewClient(cfg ...config.ConnectConf) Client {
@@ -42,9 +87,11 @@ func NewClient(cfg ...config.ConnectConf) Client {
 		conf = cfg[0]
 	}
 
+	policy := newIPPolicy(conf.AllowCIDRs)
 	dialer := &net.Dialer{
 		Resolver:  newResolver(conf.DNSServer),
 		KeepAlive: 30 * time.Second,
+		Control:   policy.control, // DNS解析后检查实际连接的地址
 	}
 
 	transport := &http.Transport{
@@ -60,10 +107,25 @@ func NewClient(cfg ...config.ConnectConf) Client {
 			Transport: transport,
 			Timeout:   conf.Timeout,
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				return http.ErrUseLastResponse // 禁止自动重定向
+				if err := policy.checkURL(req.URL); err != nil {
+					return err
+				}
+				// 超过最大次数时以最后一次的3xx响应作为结果
+				if len(via) > conf.MaxRedirects {
+					return http.ErrUseLastResponse
+				}
+				return nil
+			},
+		},
+		noRedirect: &http.Client{
+			Transport: transport,
+			Timeout:   conf.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
 			},
 		},
 		config: conf,
+		policy: policy,
 	}
 
 }
@@ -78,69 +140,177 @@ func newResolver(dnsServer string) *net.Resolver {
 	}
 }
 
-func (c *clientImpl) Check(URL string) (bool, error) {
+func (c *clientImpl) Check(URL string) (*Result, error) {
 	if len(URL) == 0 {
-		return false, errorx.New(errorx.CodeParamError, "URL is null")
+		return nil, errorx.New(errorx.CodeParamError, "URL is null")
 	}
 
 	result, err := globalSF.Do(URL, func() (any, error) {
 		return c.checkWithRetry(URL)
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return result.(bool), err
+	return result.(*Result), nil
 }
 
-func (c *clientImpl) checkWithRetry(url string) (success bool, err error) {
+func (c *clientImpl) checkWithRetry(url string) (result *Result, err error) {
 	for i := 0; i <= c.config.MaxRetries; i++ {
-		success, err = c.check(url)
-		if success && err == nil {
-			return true, nil
+		result, err = c.check(url)
+		if err == nil && result.Reachable {
+			return result, nil
 		}
 
 		if err != nil && !errorx.Is(err, errorx.CodeTimeout) {
-			return false, err
+			return nil, err
 		}
 
 		if i < c.config.MaxRetries {
-			time.Sleep(time.Duration(50*i) * time.Millisecond) // 退避策略
+			time.Sleep(c.backoff(i)) // 退避策略
 		}
 	}
 
 	// 所有重试都失败后
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	// 处理没有明确错误但请求未成功的情况（如状态码非2xx）
-	return false, errorx.New(errorx.CodeTimeout, "check URL failed after all retries")
+	// 可以连接但状态码不可访问（如5xx），返回最后一次的结果，由调用方决定如何处理
+	return result, nil
 }
 
-func (c *clientImpl) check(url string) (bool, error) {
+// backoff 第i次重试前的等待时间，从 RetryBackoff 开始指数增长，不超过 RetryMaxBackoff
+func (c *clientImpl) backoff(i int) time.Duration {
+	wait := c.config.RetryBackoff << min(i, maxBackoffShift)
+	if maxWait := c.config.RetryMaxBackoff; maxWait > 0 && (wait > maxWait || wait < 0) {
+		wait = maxWait
+	}
+	return wait
+}
+
+func (c *clientImpl) check(url string) (result *Result, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			logx.Errorf("panic during URL check: %v, url: %s", r, url)
+			result, err = nil, errorx.New(errorx.CodeSystemError, "panic during URL check")
 		}
 	}()
 
-	resp, err := c.client.Head(url)
+	statusCode, _, err := c.probeWithFallback(c.client, url)
 	if err != nil {
-		// 检查是否是超时错误
-		var netErr net.Error
-		if errors.As(err, &netErr) && netErr.Timeout() {
-			return false, errorx.NewWithCause(errorx.CodeTimeout, "the connection to this url timed out", err)
+		return nil, err
+	}
+	return &Result{Reachable: isReachableStatusCode(statusCode), StatusCode: statusCode}, nil
+}
+
+func (c *clientImpl) Resolve(URL string) (*Chain, error) {
+	if len(URL) == 0 {
+		return nil, errorx.New(errorx.CodeParamError, "URL is null")
+	}
+
+	result, err := globalSF.Do("resolve:"+URL, func() (any, error) {
+		return c.resolveWithRetry(URL)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*Chain), nil
+}
+
+func (c *clientImpl) resolveWithRetry(url string) (chain *Chain, err error) {
+	for i := 0; i <= c.config.MaxRetries; i++ {
+		chain, err = c.resolve(url)
+		if err == nil || !errorx.Is(err, errorx.CodeTimeout) {
+			return chain, err
 		}
 
-		// 通过错误消息检查是否为超时
-		if strings.Contains(err.Error(), "timeout") ||
-			strings.Contains(err.Error(), "deadline exceeded") {
-			return false, errorx.NewWithCause(errorx.CodeTimeout, "the connection timed out", err)
+		if i < c.config.MaxRetries {
+			time.Sleep(c.backoff(i)) // 退避策略
 		}
+	}
+	return nil, err
+}
 
-		// 其他错误情况
-		return false, errorx.NewWithCause(errorx.CodeParamError, "can't connect to this url", err)
+// resolve 逐跳请求并记录重定向链，重定向循环或超过 MaxRedirects 次时返回错误
+func (c *clientImpl) resolve(rawURL string) (*Chain, error) {
+	current, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errorx.NewWithCause(errorx.CodeParamError, "invalid url", err)
+	}
+
+	chain := &Chain{}
+	visited := make(map[string]struct{})
+	for {
+		// 字面量IP在请求前拒绝，域名在拨号时检查解析后的地址
+		if err := c.policy.checkURL(current); err != nil {
+			return nil, connectError(err)
+		}
+
+		hopURL := current.String()
+		if _, ok := visited[hopURL]; ok {
+			return nil, errorx.New(errorx.CodeParamError, "the url redirects in a loop").
+				WithMeta("url", hopURL)
+		}
+		visited[hopURL] = struct{}{}
+
+		statusCode, location, err := c.probeWithFallback(c.noRedirect, hopURL)
+		if err != nil {
+			return nil, err
+		}
+		chain.Hops = append(chain.Hops, Hop{URL: hopURL, StatusCode: statusCode})
+
+		if !isRedirectStatusCode(statusCode) || len(location) == 0 {
+			chain.Result = Result{Reachable: isReachableStatusCode(statusCode), StatusCode: statusCode}
+			return chain, nil
+		}
+
+		if len(chain.Hops) > c.config.MaxRedirects {
+			return nil, errorx.New(errorx.CodeParamError, "the url redirects too many times").
+				WithMeta("maxRedirects", c.config.MaxRedirects)
+		}
+
+		// Location可能是相对地址
+		current, err = current.Parse(location)
+		if err != nil {
+			return nil, errorx.NewWithCause(errorx.CodeParamError, "invalid redirect location", err).
+				WithMeta("location", location)
+		}
+	}
+}
+
+// probeWithFallback 发送HEAD请求，部分网站不支持HEAD或对HEAD返回不同的结果，
+// 状态码在 FallbackStatusCodes 中时使用限制长度的GET重新检查
+func (c *clientImpl) probeWithFallback(client *http.Client, url string) (int, string, error) {
+	statusCode, location, err := c.probe(client, http.MethodHead, url)
+	if err != nil || !c.shouldFallback(statusCode) {
+		return statusCode, location, err
+	}
+	return c.probe(client, http.MethodGet, url)
+}
+
+// probe 发送请求并返回最终响应的状态码和重定向地址，GET请求只读取响应体的前 FallbackMaxBytes 个字节
+func (c *clientImpl) probe(client *http.Client, method, url string) (int, string, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return 0, "", errorx.NewWithCause(errorx.CodeParamError, "invalid url", err)
+	}
+
+	maxBytes := c.config.FallbackMaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultFallbackMaxBytes
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", maxBytes-1))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", connectError(err)
 	}
 
 	if resp == nil {
-		return false, errorx.New(errorx.CodeParamError, "there is no reply")
+		return 0, "", errorx.New(errorx.CodeParamError, "there is no reply")
 	}
 
 	defer func() {
@@ -149,9 +319,58 @@ func (c *clientImpl) check(url string) (bool, error) {
 		}
 	}()
 
-	return isSuccessStatusCode(resp.StatusCode), nil
+	if method == http.MethodGet {
+		// 服务端忽略Range时返回完整内容，只读取限定长度
+		if _, err := io.CopyN(io.Discard, resp.Body, maxBytes); err != nil && err != io.EOF {
+			logx.Errorf("failed to read response body: %v, url: %s", err, url)
+		}
+	}
+
+	return resp.StatusCode, resp.Header.Get("Location"), nil
+}
+
+// shouldFallback HEAD返回的状态码是否需要使用GET重新检查
+func (c *clientImpl) shouldFallback(statusCode int) bool {
+	for _, code := range c.config.FallbackStatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// connectError 将请求错误转换为业务错误，超时错误可以重试
+func connectError(err error) error {
+	// 目标地址被SSRF策略拒绝，不需要重试
+	if errors.Is(err, ErrForbiddenAddress) {
+		return errorx.NewWithCause(errorx.CodeParamError, "the url points to a forbidden address", err)
+	}
+
+	// 检查是否是超时错误
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return errorx.NewWithCause(errorx.CodeTimeout, "the connection to this url timed out", err)
+	}
+
+	// 通过错误消息检查是否为超时
+	if strings.Contains(err.Error(), "timeout") ||
+		strings.Contains(err.Error(), "deadline exceeded") {
+		return errorx.NewWithCause(errorx.CodeTimeout, "the connection timed out", err)
+	}
+
+	// 其他错误情况
+	return errorx.NewWithCause(errorx.CodeParamError, "can't connect to this url", err)
 }
 
 func isSuccessStatusCode(statusCode int) bool {
 	return statusCode >= 200 && statusCode < 300
 }
+
+// isReachableStatusCode 2xx视为可访问，416说明服务端理解了GET回退的范围请求，资源存在但内容为空
+func isReachableStatusCode(statusCode int) bool {
+	return isSuccessStatusCode(statusCode) || statusCode == http.StatusRequestedRangeNotSatisfiable
+}
+
+func isRedirectStatusCode(statusCode int) bool {
+	return statusCode >= 300 && statusCode < 400
+}
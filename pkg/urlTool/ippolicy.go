@@ -0,0 +1,131 @@
+package urlTool
+
+import (
+	"errors"
+	"fmt"
+	"github.com/zeromicro/go-zero/core/logx"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// ErrForbiddenAddress 目标地址属于内网、环回等禁止访问的网段
+var ErrForbiddenAddress = errors.New("forbidden address")
+
+// deniedPrefixes 禁止连通性检查访问的网段：内网、环回、链路本地、组播、保留地址，
+// 以及内嵌IPv4地址的IPv6转换网段（可绕过IPv4的限制）
+var deniedPrefixes = mustParsePrefixes(
+	// IPv4
+	"0.0.0.0/8",       // 本网络
+	"10.0.0.0/8",      // 私有网络
+	"100.64.0.0/10",   // 运营商级NAT
+	"127.0.0.0/8",     // 环回
+	"169.254.0.0/16",  // 链路本地，包含云厂商元数据服务
+	"172.16.0.0/12",   // 私有网络
+	"192.0.0.0/24",    // IETF协议分配
+	"192.0.2.0/24",    // 文档示例
+	"192.88.99.0/24",  // 6to4中继
+	"192.168.0.0/16",  // 私有网络
+	"198.18.0.0/15",   // 基准测试
+	"198.51.100.0/24", // 文档示例
+	"203.0.113.0/24",  // 文档示例
+	"224.0.0.0/4",     // 组播
+	"240.0.0.0/4",     // 保留地址及广播
+	// IPv6
+	"::/128",         // 未指定地址
+	"::1/128",        // 环回
+	"64:ff9b::/96",   // NAT64
+	"64:ff9b:1::/48", // 本地NAT64
+	"100::/64",       // 丢弃前缀
+	"2001::/32",      // Teredo
+	"2001:db8::/32",  // 文档示例
+	"2002::/16",      // 6to4
+	"fc00::/7",       // 唯一本地地址
+	"fe80::/10",      // 链路本地
+	"fec0::/10",      // 站点本地（已废弃）
+	"ff00::/8",       // 组播
+)
+
+func mustParsePrefixes(cidrs ...string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefixes = append(prefixes, netip.MustParsePrefix(cidr))
+	}
+	return prefixes
+}
+
+// ipPolicy 连通性检查可以访问的地址。在拨号时对DNS解析后的地址进行检查，
+// 避免DNS重绑定以及十进制、八进制等IP写法绕过校验
+type ipPolicy struct {
+	// 内部部署时允许访问的网段，优先于禁止网段
+	allow []netip.Prefix
+}
+
+// newIPPolicy 解析允许访问的网段，无效的网段记录日志后忽略
+func newIPPolicy(allowCIDRs []string) ipPolicy {
+	var policy ipPolicy
+	for _, cidr := range allowCIDRs {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			logx.Errorf("invalid connect allow cidr %q, ignored,err:%v", cidr, err)
+			continue
+		}
+		policy.allow = append(policy.allow, prefix.Masked())
+	}
+	return policy
+}
+
+// allowed 判断地址是否允许访问
+func (p ipPolicy) allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	// 去掉IPv6的zone，fe80::1%eth0 与 fe80::1 使用相同的规则
+	addr = addr.WithZone("")
+
+	for _, prefix := range p.allow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	for _, prefix := range deniedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// control 作为 net.Dialer 的 Control 在建立连接前检查解析后的地址，
+// 每次拨号都会调用，包括重定向后的每一跳
+func (p ipPolicy) control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	if !p.allowed(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+	}
+	return nil
+}
+
+// checkURL 在发起请求前检查协议和字面量IP主机，域名在拨号时检查
+func (p ipPolicy) checkURL(u *url.URL) error {
+	if !strings.EqualFold(u.Scheme, "http") && !strings.EqualFold(u.Scheme, "https") {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrForbiddenAddress, u.Scheme)
+	}
+
+	addr, err := netip.ParseAddr(u.Hostname())
+	if err != nil {
+		return nil
+	}
+	if !p.allowed(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+	}
+	return nil
+}
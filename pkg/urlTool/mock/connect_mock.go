@@ -11,6 +11,7 @@ package urlTool
 
 import (
 	reflect "reflect"
+	urlTool "shortener/pkg/urlTool"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -40,10 +41,10 @@ func (m *MockClient) EXPECT() *MockClientMockRecorder {
 }
 
 // Check mocks base method.
-func (m *MockClient) Check(url string) (bool, error) {
+func (m *MockClient) Check(url string) (*urlTool.Result, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Check", url)
-	ret0, _ := ret[0].(bool)
+	ret0, _ := ret[0].(*urlTool.Result)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -53,3 +54,18 @@ func (mr *MockClientMockRecorder) Check(url any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockClient)(nil).Check), url)
 }
+
+// Resolve mocks base method.
+func (m *MockClient) Resolve(url string) (*urlTool.Chain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Resolve", url)
+	ret0, _ := ret[0].(*urlTool.Chain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Resolve indicates an expected call of Resolve.
+func (mr *MockClientMockRecorder) Resolve(url any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resolve", reflect.TypeOf((*MockClient)(nil).Resolve), url)
+}
@@ -0,0 +1,164 @@
+package urlTool
+
+import (
+	"net/url"
+	"strings"
+)
+
+// UTM 跳转时追加到目标地址的UTM参数，为空的参数不追加
+type UTM struct {
+	Source   string
+	Medium   string
+	Campaign string
+}
+
+// IsZero 判断是否没有任何UTM参数
+func (u UTM) IsZero() bool {
+	return len(u.Source) == 0 && len(u.Medium) == 0 && len(u.Campaign) == 0
+}
+
+// Or 为空的参数使用other中的值，用于短链自身的参数覆盖标签的参数
+func (u UTM) Or(other UTM) UTM {
+	if len(u.Source) == 0 {
+		u.Source = other.Source
+	}
+	if len(u.Medium) == 0 {
+		u.Medium = other.Medium
+	}
+	if len(u.Campaign) == 0 {
+		u.Campaign = other.Campaign
+	}
+	return u
+}
+
+// Encode 按 utm_source、utm_medium、utm_campaign 的固定顺序编码为查询字符串
+func (u UTM) Encode() string {
+	var b strings.Builder
+	for _, param := range u.params() {
+		if b.Len() > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(url.QueryEscape(param[0]))
+		b.WriteByte('=')
+		b.WriteString(url.QueryEscape(param[1]))
+	}
+	return b.String()
+}
+
+// params 非空的参数，顺序与Encode一致
+func (u UTM) params() [][2]string {
+	params := make([][2]string, 0, 3)
+	for _, param := range [][2]string{
+		{"utm_source", u.Source},
+		{"utm_medium", u.Medium},
+		{"utm_campaign", u.Campaign},
+	} {
+		if len(param[1]) != 0 {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// ParseUTM 解析Encode编码的查询字符串，忽略无法解析的内容
+func ParseUTM(query string) UTM {
+	values, _ := url.ParseQuery(query)
+	return UTM{
+		Source:   values.Get("utm_source"),
+		Medium:   values.Get("utm_medium"),
+		Campaign: values.Get("utm_campaign"),
+	}
+}
+
+// AppendUTM 将UTM参数追加到URL的查询参数末尾，URL中已有的同名参数被替换，其余参数保持原样
+func AppendUTM(raw string, u UTM) (string, error) {
+	if u.IsZero() {
+		return raw, nil
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	params := u.params()
+	query := filterQuery(parsed.RawQuery, func(key string) bool {
+		for _, param := range params {
+			if key == param[0] {
+				return true
+			}
+		}
+		return false
+	})
+
+	if len(query) != 0 {
+		query += "&"
+	}
+	parsed.RawQuery = query + u.Encode()
+	return parsed.String(), nil
+}
+
+// StripParams 去掉名称与rules匹配的查询参数，其余参数的顺序和编码保持不变。
+// 名称匹配不区分大小写，规则以*结尾时按前缀匹配，如 utm_*
+func StripParams(raw string, rules []string) (string, error) {
+	if len(rules) == 0 {
+		return raw, nil
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if len(parsed.RawQuery) == 0 {
+		return raw, nil
+	}
+
+	query := filterQuery(parsed.RawQuery, func(key string) bool {
+		return matchParam(key, rules)
+	})
+	if query == parsed.RawQuery {
+		return raw, nil
+	}
+
+	parsed.RawQuery = query
+	parsed.ForceQuery = false
+	return parsed.String(), nil
+}
+
+// matchParam 判断参数名是否与任一规则匹配
+func matchParam(key string, rules []string) bool {
+	for _, rule := range rules {
+		if prefix, ok := strings.CutSuffix(rule, "*"); ok {
+			if len(key) >= len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(key, rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterQuery 去掉drop返回true的参数，参数名按解码后的值判断
+func filterQuery(rawQuery string, drop func(key string) bool) string {
+	if len(rawQuery) == 0 {
+		return ""
+	}
+
+	kept := make([]string, 0, strings.Count(rawQuery, "&")+1)
+	for _, param := range strings.Split(rawQuery, "&") {
+		if len(param) == 0 {
+			continue
+		}
+		key, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if !drop(key) {
+			kept = append(kept, param)
+		}
+	}
+	return strings.Join(kept, "&")
+}
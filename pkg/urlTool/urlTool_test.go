@@ -1,8 +1,11 @@
 package urlTool
 
 import (
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"net/url"
 	"shortener/internal/config"
 	"shortener/internal/types/errorx"
 	"testing"
@@ -60,6 +63,127 @@ func TestGetDomainAndPath(t *testing.T) {
 	}
 }
 
+// TestCanonicalize 测试URL规范化
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		conf   config.CanonicalConf
+		expect string
+	}{
+		{name: "协议和主机转为小写", input: "HTTPS://Example.COM/Path", expect: "https://example.com/Path"},
+		{name: "去掉https默认端口", input: "https://example.com:443/a", expect: "https://example.com/a"},
+		{name: "去掉http默认端口", input: "http://example.com:80/a", expect: "http://example.com/a"},
+		{name: "保留非默认端口", input: "https://example.com:8443/a", expect: "https://example.com:8443/a"},
+		{name: "IPv6去掉默认端口后保留方括号", input: "http://[2001:DB8::1]:80/a", expect: "http://[2001:db8::1]/a"},
+		{name: "国际化域名转换为punycode", input: "https://例子.测试/路径", expect: "https://xn--fsqu00a.xn--0zwm56d/%E8%B7%AF%E5%BE%84"},
+		{name: "空路径补为斜杠", input: "https://example.com", expect: "https://example.com/"},
+		{name: "去掉空片段", input: "https://example.com/a#", expect: "https://example.com/a"},
+		{name: "去掉空查询", input: "https://example.com/a?", expect: "https://example.com/a"},
+		{name: "保留非空片段", input: "https://example.com/a#top", expect: "https://example.com/a#top"},
+		{name: "解码非保留字符", input: "https://example.com/%7Euser/%61", expect: "https://example.com/~user/a"},
+		{name: "保留字符的编码转为大写", input: "https://example.com/a%2fb?q=%e4%b8%ad", expect: "https://example.com/a%2Fb?q=%E4%B8%AD"},
+		{name: "去掉空参数", input: "https://example.com/?a=1&&b=2&", expect: "https://example.com/?a=1&b=2"},
+		{name: "默认不排序查询参数", input: "https://example.com/?b=2&a=1", expect: "https://example.com/?b=2&a=1"},
+		{
+			name:   "按参数名排序，同名参数保持顺序",
+			input:  "https://example.com/?b=2&a=3&a=1",
+			conf:   config.CanonicalConf{SortQuery: true},
+			expect: "https://example.com/?a=3&a=1&b=2",
+		},
+		{name: "默认保留末尾斜杠", input: "https://example.com/a/", expect: "https://example.com/a/"},
+		{
+			name:   "去掉末尾斜杠",
+			input:  "https://example.com/a/",
+			conf:   config.CanonicalConf{StripTrailingSlash: true},
+			expect: "https://example.com/a",
+		},
+		{
+			name:   "根路径保留斜杠",
+			input:  "https://example.com/",
+			conf:   config.CanonicalConf{StripTrailingSlash: true},
+			expect: "https://example.com/",
+		},
+		{name: "保留用户信息", input: "https://user@Example.com/a", expect: "https://user@example.com/a"},
+		{name: "不含主机的URL原样返回", input: "mailto:User@Example.com", expect: "mailto:User@Example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canonical, err := Canonicalize(tt.input, tt.conf)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expect, canonical)
+		})
+	}
+
+	_, err := Canonicalize("http://[::1", config.CanonicalConf{})
+	assert.Error(t, err)
+}
+
+// TestStripParams 测试去掉跟踪参数
+func TestStripParams(t *testing.T) {
+	rules := []string{"fbclid", "gclid", "utm_*"}
+
+	tests := []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{name: "去掉跟踪参数并保持其余参数顺序", input: "https://example.com/a?b=2&fbclid=x&a=1", expect: "https://example.com/a?b=2&a=1"},
+		{name: "名称不区分大小写", input: "https://example.com/a?GCLID=x&q=1", expect: "https://example.com/a?q=1"},
+		{name: "前缀匹配", input: "https://example.com/a?utm_source=x&utm_medium=y&id=1", expect: "https://example.com/a?id=1"},
+		{name: "全部去掉时去掉问号", input: "https://example.com/a?fbclid=x#top", expect: "https://example.com/a#top"},
+		{name: "保留其余参数的编码", input: "https://example.com/a?q=%E4%B8%AD+1&fbclid=x", expect: "https://example.com/a?q=%E4%B8%AD+1"},
+		{name: "没有匹配时原样返回", input: "HTTPS://Example.com/a?q=1", expect: "HTTPS://Example.com/a?q=1"},
+		{name: "相似的参数名不匹配", input: "https://example.com/a?fbclid2=x", expect: "https://example.com/a?fbclid2=x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stripped, err := StripParams(tt.input, rules)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expect, stripped)
+		})
+	}
+
+	_, err := StripParams("http://[::1", rules)
+	assert.Error(t, err)
+}
+
+// TestAppendUTM 测试追加UTM参数
+func TestAppendUTM(t *testing.T) {
+	utm := UTM{Source: "news letter", Campaign: "spring"}
+	assert.Equal(t, "utm_source=news+letter&utm_campaign=spring", utm.Encode())
+	assert.Equal(t, utm, ParseUTM(utm.Encode()))
+
+	// 短链自身的参数优先，为空的参数使用标签的参数
+	merged := UTM{Source: "link"}.Or(UTM{Source: "tag", Medium: "email"})
+	assert.Equal(t, UTM{Source: "link", Medium: "email"}, merged)
+
+	tests := []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{name: "没有查询参数", input: "https://example.com/a", expect: "https://example.com/a?utm_source=news+letter&utm_campaign=spring"},
+		{name: "追加到已有参数之后", input: "https://example.com/a?q=1#top", expect: "https://example.com/a?q=1&utm_source=news+letter&utm_campaign=spring#top"},
+		{name: "替换同名参数", input: "https://example.com/a?utm_source=old&utm_medium=web", expect: "https://example.com/a?utm_medium=web&utm_source=news+letter&utm_campaign=spring"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appended, err := AppendUTM(tt.input, utm)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expect, appended)
+		})
+	}
+
+	// 没有UTM参数时原样返回
+	appended, err := AppendUTM("https://example.com/a?q=1", UTM{})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/a?q=1", appended)
+}
+
 // TestClientCheck 测试URL连接检查
 func TestClientCheck(t *testing.T) {
 	// 设置一个成功的测试服务器
@@ -81,29 +205,33 @@ func TestClientCheck(t *testing.T) {
 		MaxRetries:      1,
 		MaxIdleConns:    50,
 		IdleConnTimeout: 20 * time.Second,
+		AllowCIDRs:      []string{"127.0.0.0/8"}, // 测试服务器监听在环回地址
 	}
 	client := NewClient(customConfig)
 
 	// 测试用例
 	tests := []struct {
-		name        string
-		url         string
-		expectValid bool
-		expectError bool
-		errorCode   errorx.Code
+		name         string
+		url          string
+		expectValid  bool
+		expectStatus int
+		expectError  bool
+		errorCode    errorx.Code
 	}{
 		{
-			name:        "成功URL",
-			url:         successServer.URL,
-			expectValid: true,
-			expectError: false,
+			name:         "成功URL",
+			url:          successServer.URL,
+			expectValid:  true,
+			expectStatus: http.StatusOK,
+			expectError:  false,
 		},
 		{
-			name:        "错误状态码URL",
-			url:         errorServer.URL,
-			expectValid: false,
-			expectError: true,
-			errorCode:   errorx.CodeTimeout,
+			// 重试后仍不可访问时返回最后一次的状态码
+			name:         "错误状态码URL",
+			url:          errorServer.URL,
+			expectValid:  false,
+			expectStatus: http.StatusInternalServerError,
+			expectError:  false,
 		},
 		{
 			name:        "空URL",
@@ -116,18 +244,314 @@ func TestClientCheck(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			valid, err := client.Check(tt.url)
+			result, err := client.Check(tt.url)
 
 			if tt.expectError {
 				assert.Error(t, err)
+				assert.Nil(t, result)
 				if tt.errorCode != 0 {
 					assert.True(t, errorx.Is(err, tt.errorCode), "预期错误码 %v, 实际: %v", tt.errorCode, err)
 				}
-			} else {
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectValid, result.Reachable)
+			assert.Equal(t, tt.expectStatus, result.StatusCode)
+		})
+	}
+}
+
+// TestClientCheck_ForbiddenAddress 测试SSRF策略拒绝内网地址
+func TestClientCheck_ForbiddenAddress(t *testing.T) {
+	redirectTarget := "http://169.254.169.254/latest/meta-data/"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, redirectTarget, http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	t.Run("默认禁止环回地址", func(t *testing.T) {
+		client := NewClient(config.ConnectConf{Timeout: 500 * time.Millisecond})
+		result, err := client.Check(server.URL + "/forbidden")
+		assert.Nil(t, result)
+		assert.True(t, errorx.Is(err, errorx.CodeParamError), "实际: %v", err)
+		assert.ErrorIs(t, err, ErrForbiddenAddress)
+	})
+
+	t.Run("重定向到内网地址", func(t *testing.T) {
+		client := NewClient(config.ConnectConf{
+			Timeout:    500 * time.Millisecond,
+			AllowCIDRs: []string{"127.0.0.1/32"},
+		})
+		result, err := client.Check(server.URL + "/redirect")
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, ErrForbiddenAddress)
+	})
+}
+
+// TestClientCheck_GetFallback 测试HEAD被拒绝时使用GET重新检查
+func TestClientCheck_GetFallback(t *testing.T) {
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			switch r.URL.Path {
+			case "/teapot":
+				w.WriteHeader(http.StatusTeapot)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		ranges = append(ranges, r.Header.Get("Range"))
+		switch r.URL.Path {
+		case "/empty":
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			// 忽略Range返回完整内容
+			_, _ = w.Write(make([]byte, 64*1024))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(config.ConnectConf{
+		Timeout:             500 * time.Millisecond,
+		FallbackStatusCodes: []int{http.StatusMethodNotAllowed},
+		FallbackMaxBytes:    16,
+		AllowCIDRs:          []string{"127.0.0.0/8"},
+	})
+
+	t.Run("GET成功", func(t *testing.T) {
+		result, err := client.Check(server.URL + "/ok")
+		assert.NoError(t, err)
+		assert.True(t, result.Reachable)
+		assert.Equal(t, []string{"bytes=0-15"}, ranges)
+	})
+
+	t.Run("范围无法满足视为可访问", func(t *testing.T) {
+		result, err := client.Check(server.URL + "/empty")
+		assert.NoError(t, err)
+		assert.True(t, result.Reachable)
+	})
+
+	t.Run("GET同样失败", func(t *testing.T) {
+		result, err := client.Check(server.URL + "/missing")
+		assert.NoError(t, err)
+		assert.False(t, result.Reachable)
+		assert.Equal(t, http.StatusNotFound, result.StatusCode)
+	})
+
+	t.Run("未配置的状态码不回退", func(t *testing.T) {
+		ranges = nil
+		result, err := client.Check(server.URL + "/teapot")
+		assert.NoError(t, err)
+		assert.False(t, result.Reachable)
+		assert.Equal(t, http.StatusTeapot, result.StatusCode)
+		assert.Empty(t, ranges)
+	})
+}
+
+// TestClientCheck_Redirect 测试重定向链最终返回2xx时视为可访问
+func TestClientCheck_Redirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		case "/b":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		case "/final":
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	newClient := func(maxRedirects int) Client {
+		return NewClient(config.ConnectConf{
+			Timeout:      500 * time.Millisecond,
+			MaxRedirects: maxRedirects,
+			AllowCIDRs:   []string{"127.0.0.0/8"},
+		})
+	}
+
+	result, err := newClient(2).Check(server.URL + "/a")
+	assert.NoError(t, err)
+	assert.True(t, result.Reachable)
+
+	result, err = newClient(1).Check(server.URL + "/a")
+	assert.NoError(t, err)
+	assert.False(t, result.Reachable)
+	assert.Equal(t, http.StatusFound, result.StatusCode)
+
+	result, err = newClient(0).Check(server.URL + "/b")
+	assert.NoError(t, err)
+	assert.False(t, result.Reachable)
+}
+
+// TestClientResolve 测试逐跳解析重定向链
+func TestClientResolve(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b?x=1", http.StatusMovedPermanently)
+		case "/b":
+			http.Redirect(w, r, server.URL+"/final", http.StatusFound)
+		case "/final":
+			w.WriteHeader(http.StatusOK)
+		case "/head-rejected":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			http.Redirect(w, r, "/final", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop2", http.StatusFound)
+		case "/loop2":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/internal":
+			http.Redirect(w, r, "http://10.0.0.5/admin", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(config.ConnectConf{
+		Timeout:             500 * time.Millisecond,
+		MaxRedirects:        3,
+		FallbackStatusCodes: []int{http.StatusMethodNotAllowed},
+		AllowCIDRs:          []string{"127.0.0.0/8"},
+	})
+
+	t.Run("记录完整的重定向链", func(t *testing.T) {
+		chain, err := client.Resolve(server.URL + "/a")
+		assert.NoError(t, err)
+		assert.True(t, chain.Reachable)
+		assert.Equal(t, []Hop{
+			{URL: server.URL + "/a", StatusCode: http.StatusMovedPermanently},
+			{URL: server.URL + "/b?x=1", StatusCode: http.StatusFound},
+			{URL: server.URL + "/final", StatusCode: http.StatusOK},
+		}, chain.Hops)
+		assert.Equal(t, server.URL+"/final", chain.FinalUrl())
+	})
+
+	t.Run("HEAD被拒绝时使用GET", func(t *testing.T) {
+		chain, err := client.Resolve(server.URL + "/head-rejected")
+		assert.NoError(t, err)
+		assert.True(t, chain.Reachable)
+		assert.Equal(t, server.URL+"/final", chain.FinalUrl())
+	})
+
+	t.Run("最终地址不可访问", func(t *testing.T) {
+		chain, err := client.Resolve(server.URL + "/missing")
+		assert.NoError(t, err)
+		assert.False(t, chain.Reachable)
+		assert.Len(t, chain.Hops, 1)
+	})
+
+	t.Run("重定向循环", func(t *testing.T) {
+		_, err := client.Resolve(server.URL + "/loop")
+		assert.True(t, errorx.Is(err, errorx.CodeParamError), "实际: %v", err)
+		assert.Contains(t, err.Error(), "loop")
+	})
+
+	t.Run("超过最大重定向次数", func(t *testing.T) {
+		limited := NewClient(config.ConnectConf{
+			Timeout:      500 * time.Millisecond,
+			MaxRedirects: 1,
+			AllowCIDRs:   []string{"127.0.0.0/8"},
+		})
+		_, err := limited.Resolve(server.URL + "/a")
+		assert.True(t, errorx.Is(err, errorx.CodeParamError), "实际: %v", err)
+		assert.Contains(t, err.Error(), "too many")
+	})
+
+	t.Run("重定向到内网地址", func(t *testing.T) {
+		_, err := client.Resolve(server.URL + "/internal")
+		assert.ErrorIs(t, err, ErrForbiddenAddress)
+	})
+
+	t.Run("空URL", func(t *testing.T) {
+		_, err := client.Resolve("")
+		assert.True(t, errorx.Is(err, errorx.CodeParamError))
+	})
+}
+
+// TestIPPolicy 测试地址策略
+func TestIPPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		addr    string
+		allowed bool
+	}{
+		{"公网IPv4", nil, "93.184.216.34", true},
+		{"公网IPv6", nil, "2606:2800:220:1:248:1893:25c8:1946", true},
+		{"环回", nil, "127.0.0.1", false},
+		{"私有网络10", nil, "10.0.0.5", false},
+		{"私有网络172", nil, "172.31.255.255", false},
+		{"私有网络192", nil, "192.168.1.1", false},
+		{"元数据服务", nil, "169.254.169.254", false},
+		{"运营商级NAT", nil, "100.64.0.1", false},
+		{"未指定地址", nil, "0.0.0.0", false},
+		{"广播", nil, "255.255.255.255", false},
+		{"IPv6环回", nil, "::1", false},
+		{"IPv6唯一本地地址", nil, "fd00::1", false},
+		{"IPv6链路本地", nil, "fe80::1%eth0", false},
+		{"IPv4映射的IPv6", nil, "::ffff:127.0.0.1", false},
+		{"NAT64", nil, "64:ff9b::a00:5", false},
+		{"允许列表", []string{"10.0.0.0/8"}, "10.0.0.5", true},
+		{"允许列表之外", []string{"10.0.0.0/8"}, "192.168.1.1", false},
+		{"无效的允许列表", []string{"not-a-cidr"}, "10.0.0.5", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := newIPPolicy(tt.allow)
+			assert.Equal(t, tt.allowed, policy.allowed(netip.MustParseAddr(tt.addr)))
+
+			err := policy.control("tcp", net.JoinHostPort(tt.addr, "80"), nil)
+			if tt.allowed {
 				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrForbiddenAddress)
 			}
+		})
+	}
+}
+
+// TestIPPolicy_CheckURL 测试请求前的URL检查
+func TestIPPolicy_CheckURL(t *testing.T) {
+	policy := newIPPolicy(nil)
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://example.com/path", true},
+		{"http://93.184.216.34/", true},
+		{"http://10.0.0.5/admin", false},
+		{"http://[::1]:8080/", false},
+		{"ftp://example.com/", false},
+		{"file:///etc/passwd", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			assert.NoError(t, err)
 
-			assert.Equal(t, tt.expectValid, valid)
+			err = policy.checkURL(u)
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrForbiddenAddress)
+			}
 		})
 	}
 }
@@ -157,6 +581,24 @@ func TestNewClient(t *testing.T) {
 	assert.True(t, ok2, "client2应该是clientImpl类型")
 }
 
+// TestClientBackoff 测试重试等待时间
+func TestClientBackoff(t *testing.T) {
+	c := NewClient(config.ConnectConf{
+		RetryBackoff:    50 * time.Millisecond,
+		RetryMaxBackoff: 300 * time.Millisecond,
+	}).(*clientImpl)
+
+	// 第一次重试也需要等待
+	assert.Equal(t, 50*time.Millisecond, c.backoff(0))
+	assert.Equal(t, 100*time.Millisecond, c.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, c.backoff(2))
+	assert.Equal(t, 300*time.Millisecond, c.backoff(3))
+	assert.Equal(t, 300*time.Millisecond, c.backoff(100))
+
+	// 未配置时不等待
+	assert.Zero(t, NewClient(config.ConnectConf{}).(*clientImpl).backoff(1))
+}
+
 // TestIsSuccessStatusCode 测试HTTP状态码检查
 func TestIsSuccessStatusCode(t *testing.T) {
 	tests := []struct {
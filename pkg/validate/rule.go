@@ -2,23 +2,30 @@ package validate
 
 import (
 	"github.com/go-playground/validator/v10"
+	"golang.org/x/net/idna"
+	"net"
 	"net/url"
 	"regexp"
+	"shortener/pkg/base62"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 const (
 	minShortUrlLen = 1
-	maxShortUrlLen = 11
+	maxShortUrlLen = base62.MaxCodeLen
 )
 
 var (
-	// 预编译正则表达式提高性能 - 支持更多合法字符和格式
-	urlRegex   = regexp.MustCompile(`^(http|https)://([a-zA-Z0-9]([a-zA-Z0-9\-]*[a-zA-Z0-9])?\.)+[a-zA-Z0-9\-]{2,}(:[0-9]{1,5})?(/[-a-zA-Z0-9_%.~+&=:#?]*)*$`)
 	shortRegex = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+
+	// hostProfile 按IDNA查找规则将国际化域名转换为punycode，同时校验标签字符和长度
+	hostProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.VerifyDNSLength(true))
 )
 
-// validLongUrlValidator 验证长链接
+// validLongUrlValidator 验证长链接，按RFC 3986解析，只允许http和https协议，
+// 国际化域名转换为punycode后校验，路径和查询中的非ASCII字符及 []!'()*,;@ 等字符均合法
 func validLongUrlValidator(fl validator.FieldLevel) bool {
 	urlStr := fl.Field().String()
 
@@ -27,31 +34,96 @@ func validLongUrlValidator(fl validator.FieldLevel) bool {
 		return false
 	}
 
-	// 使用标准库解析URL
+	// 空白和控制字符需要百分号编码
+	if strings.IndexFunc(urlStr, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}) >= 0 {
+		return false
+	}
+
+	// 使用标准库解析URL，路径和片段中不合法的百分号编码在这里被拒绝
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
 		return false
 	}
 
-	// 检查协议
+	// 检查协议，javascript:、data: 等协议一律拒绝
 	scheme := parsedURL.Scheme
 	if !strings.EqualFold(scheme, "http") && !strings.EqualFold(scheme, "https") {
 		return false
 	}
 
-	// 检查主机部分
-	if parsedURL.Host == "" {
+	// 必须是 scheme://host 形式
+	if len(parsedURL.Opaque) != 0 || len(parsedURL.Host) == 0 {
+		return false
+	}
+
+	// 拒绝用户信息，如 https://bank.example@evil.example 会让人误以为跳转到 bank.example
+	if parsedURL.User != nil {
+		return false
+	}
+
+	if !validPort(parsedURL.Port()) || !validEscapes(parsedURL.RawQuery) {
 		return false
 	}
 
-	// 过滤特定域名
-	host := strings.ToLower(parsedURL.Host)
-	if host == "localhost" || strings.HasPrefix(host, "127.0.0.") || host == "::1" {
+	host, ok := asciiHost(parsedURL)
+	if !ok {
 		return false
 	}
 
-	// 最后使用正则表达式进行完整验证
-	return urlRegex.MatchString(urlStr)
+	// 过滤本机地址
+	if host == "localhost" {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip == nil || !ip.IsLoopback()
+}
+
+// asciiHost 返回URL主机的ASCII形式：IP地址原样返回，域名转换为小写的punycode。
+// IPv6地址必须带方括号且不允许区域标识，域名至少两级且顶级域名至少两个字符
+func asciiHost(u *url.URL) (string, bool) {
+	host := u.Hostname()
+	if strings.HasPrefix(u.Host, "[") {
+		return host, net.ParseIP(host) != nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return host, ip.To4() != nil
+	}
+
+	ascii, err := hostProfile.ToASCII(host)
+	if err != nil {
+		return "", false
+	}
+	dot := strings.LastIndexByte(ascii, '.')
+	if dot <= 0 || len(ascii)-dot-1 < 2 {
+		return "", false
+	}
+	return ascii, true
+}
+
+// validPort 端口为空或在1-65535之间
+func validPort(port string) bool {
+	if len(port) == 0 {
+		return true
+	}
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// validEscapes 检查百分号后是否紧跟两位十六进制数，url.Parse 不检查查询部分
+func validEscapes(s string) bool {
+	for i := strings.IndexByte(s, '%'); i >= 0; i = strings.IndexByte(s, '%') {
+		if i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			return false
+		}
+		s = s[i+3:]
+	}
+	return true
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
 }
 
 // validShortUrlValidator 验证短链接
@@ -65,5 +137,22 @@ func validShortUrlValidator(fl validator.FieldLevel) bool {
 	}
 
 	// 短链接只能包含字母和数字
-	return shortRegex.MatchString(shortUrl)
+	if !shortRegex.MatchString(shortUrl) {
+		return false
+	}
+
+	return true
+}
+
+// ValidCheckCode 校验短码的校验字符，不超过legacyMaxID的短码是启用校验字符之前生成的旧短码，不带校验字符
+func ValidCheckCode(shortUrl string, legacyMaxID uint64) bool {
+	if base62.ValidCheck(shortUrl) {
+		return true
+	}
+
+	id, err := base62.Decode(shortUrl)
+	if err != nil {
+		return false
+	}
+	return id <= legacyMaxID
 }
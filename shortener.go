@@ -1,16 +1,37 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/zeromicro/go-zero/core/conf"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/proc"
 	"github.com/zeromicro/go-zero/rest"
 	"shortener/internal/config"
 	"shortener/internal/handler"
+	"shortener/internal/logic"
+	"shortener/internal/migrate"
 	"shortener/internal/svc"
+	"shortener/pkg/urlTool"
+	"strconv"
+	"time"
 )
 
-var configFile = flag.String("f", "etc/shortener-api.yaml", "the config file")
+// standaloneConfigFile 单机模式未指定配置文件时使用的配置
+const standaloneConfigFile = "etc/shortener-standalone.yaml"
+
+var (
+	configFile    = flag.String("f", "etc/shortener-api.yaml", "the config file")
+	rebuildFilter = flag.Bool("rebuild-filter", false, "rebuild the short code filter from database and exit")
+	standalone    = flag.Bool("standalone", false, "run in a single process without mysql or redis, data is stored in sqlite by default")
+	autoMigrate   = flag.Bool("auto-migrate", false, "apply pending schema migrations before the server starts")
+	rehashMd5     = flag.Bool("rehash-md5", false, "recompute the md5 of existing long urls from their canonical form and exit")
+)
 
 func main() {
 	flag.Parse()
@@ -18,16 +39,173 @@ func main() {
 	//加载环境变量
 	config.LoadEnv()
 
+	//单机模式未指定配置文件时使用单机配置
+	if *standalone && !flagPassed("f") {
+		*configFile = standaloneConfigFile
+	}
+
 	//加载配置（自动替换环境变量）
 	var c config.Config
 	conf.MustLoad(*configFile, &c, conf.UseEnv())
+	if *standalone {
+		c.Standalone = true
+	}
+	if *autoMigrate {
+		c.Storage.AutoMigrate = true
+	}
+
+	//执行结构迁移后退出：migrate up | down [steps] | status
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(c, flag.Args()[1:]); err != nil {
+			logx.Must(err)
+		}
+		return
+	}
+
+	//从数据库重建过滤器后退出
+	if *rebuildFilter {
+		ctx := svc.NewServiceContext(c)
+		if err := logic.NewRebuildFilterLogic(context.Background(), ctx).RebuildFilter(); err != nil {
+			logx.Must(err)
+		}
+		fmt.Println("Short code filter rebuilt")
+		return
+	}
+
+	//按规范形式重新计算已有长链接的MD5后退出
+	if *rehashMd5 {
+		ctx := svc.NewServiceContext(c)
+		updated, skipped, err := logic.NewRehashMd5Logic(context.Background(), ctx).RehashMd5()
+		if err != nil {
+			logx.Must(err)
+		}
+		fmt.Printf("Long url md5 rehashed, updated: %d, skipped: %d\n", updated, skipped)
+		return
+	}
 
 	server := rest.MustNewServer(c.RestConf)
 	defer server.Stop()
 
 	ctx := svc.NewServiceContext(c)
+
+	//检查过滤器位图是否丢失
+	if c.ShortUrlFilter.CheckOnStart {
+		if err := logic.NewRebuildFilterLogic(context.Background(), ctx).CheckFilter(); err != nil {
+			logx.Errorf("check short code filter failed,err:%v", err)
+		}
+	}
+
+	//预热热点短链，完成后再启动服务
+	if c.WarmUp.Enabled {
+		logic.NewWarmUpLogic(context.Background(), ctx).WarmUp()
+	}
+	//定期巡检失效链接，退出时停止
+	if c.Monitor.Enabled {
+		logic.RegisterMetrics(prometheus.DefaultRegisterer)
+		monitorCtx, cancel := context.WithCancel(context.Background())
+		proc.AddShutdownListener(cancel)
+		logic.NewMonitorLogic(monitorCtx, ctx, urlTool.NewClient(c.Connect)).Start()
+	}
+	//定期清理过期短链，退出时停止
+	if c.Expiry.Enabled {
+		expiryCtx, cancel := context.WithCancel(context.Background())
+		proc.AddShutdownListener(cancel)
+		logic.NewPurgeExpiredLogic(expiryCtx, ctx).Start()
+	}
+	//未配置管理接口密钥时关闭管理接口，空密钥签发的令牌同样能通过校验，改用不公开的随机密钥使所有令牌都被拒绝
+	if len(c.AdminAuth.AccessSecret) == 0 {
+		ctx.Config.AdminAuth.AccessSecret = disabledSecret()
+		logx.Info("AdminAuth.AccessSecret is not set, the admin api is disabled")
+	}
 	handler.RegisterHandlers(server, ctx)
 
 	fmt.Printf("Starting server at %s:%d...\n", c.Host, c.Port)
 	server.Start()
 }
+
+// runMigrate 执行migrate子命令
+func runMigrate(c config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up | down [steps] | status")
+	}
+
+	migrators, err := svc.NewMigrators(c)
+	if err != nil {
+		return err
+	}
+	if len(migrators) == 0 {
+		fmt.Printf("Storage driver %s has no schema to migrate\n", c.Storage.DriverOf())
+		return nil
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		for _, m := range migrators {
+			applied, err := m.Up(ctx)
+			printMigrations("Applied", applied)
+			if err != nil {
+				return err
+			}
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("invalid steps %q", args[1])
+			}
+		}
+		// 迁移器按依赖顺序排列，回滚时倒序执行
+		for i := len(migrators) - 1; i >= 0 && steps > 0; i-- {
+			reverted, err := migrators[i].Down(ctx, steps)
+			printMigrations("Reverted", reverted)
+			if err != nil {
+				return err
+			}
+			steps -= len(reverted)
+		}
+	case "status":
+		for _, m := range migrators {
+			status, err := m.Status(ctx)
+			if err != nil {
+				return err
+			}
+			for _, s := range status {
+				appliedAt := "pending"
+				if s.Applied {
+					appliedAt = s.AppliedAt.Format(time.DateTime)
+				}
+				fmt.Printf("%04d  %-12s %-30s %s\n", s.Version, s.Target, s.Name, appliedAt)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+	return nil
+}
+
+func printMigrations(action string, migrations []migrate.Migration) {
+	for _, m := range migrations {
+		fmt.Printf("%s %04d_%s\n", action, m.Version, m.Name)
+	}
+}
+
+// flagPassed 判断命令行是否显式指定了参数
+func flagPassed(name string) bool {
+	passed := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
+		}
+	})
+	return passed
+}
+
+// disabledSecret 生成不公开的随机密钥，用于关闭未配置密钥的JWT接口
+func disabledSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		logx.Must(err)
+	}
+	return hex.EncodeToString(b)
+}